@@ -0,0 +1,34 @@
+package controllers
+
+import (
+	"net/http"
+	"strings"
+	"transaction_system/app/lib/apperror"
+	"transaction_system/app/lib/audit"
+	"unicode/utf8"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// ActorHeader names the caller on whose behalf a request is made. It is recorded as the creator of the
+// transactions the request creates.
+const ActorHeader = "X-User-ID"
+
+// maxActorLength is the length of the created_by column.
+const maxActorLength = 255
+
+// WithActor adds the actor named by the ActorHeader of a request to its context before calling handler.
+// Requests without the header have no actor.
+func WithActor(handler httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+		actor := strings.TrimSpace(r.Header.Get(ActorHeader))
+		if utf8.RuneCountInString(actor) > maxActorLength {
+			respondWithError(w, r, apperror.InvalidRequest("Header %s must be at most %d characters", ActorHeader, maxActorLength))
+			return
+		}
+		if actor != "" {
+			r = r.WithContext(audit.WithActor(r.Context(), actor))
+		}
+		handler(w, r, params)
+	}
+}
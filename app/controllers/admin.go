@@ -0,0 +1,33 @@
+package controllers
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"transaction_system/app/lib/apperror"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+var (
+	errAdminDisabled = apperror.New("admin_disabled", http.StatusForbidden, "admin endpoints are disabled")
+	errUnauthorized  = apperror.New("unauthorized", http.StatusUnauthorized, "admin token is missing or invalid")
+)
+
+// WithAdminToken calls handler only for requests whose Authorization header carries token as a bearer
+// token. Every request is refused when token is empty, so admin endpoints stay disabled until a token is set.
+func WithAdminToken(token string, handler httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+		if token == "" {
+			respondWithError(w, r, errAdminDisabled)
+			return
+		}
+		given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			respondWithError(w, r, errUnauthorized)
+			return
+		}
+		handler(w, r, params)
+	}
+}
@@ -0,0 +1,91 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"transaction_system/app/lib/apperror"
+)
+
+// ProblemContentType is the media type of error responses, RFC 7807 problem details.
+const ProblemContentType = "application/problem+json"
+
+var (
+	errRouteNotFound    = apperror.New("route_not_found", http.StatusNotFound, "no endpoint matches the request path")
+	errMethodNotAllowed = apperror.New("method_not_allowed", http.StatusMethodNotAllowed, "the endpoint does not support the request method")
+	errBodyTooLarge     = apperror.New("body_too_large", http.StatusRequestEntityTooLarge, "request body is too large")
+)
+
+// NotFoundHandler responds to requests that match no route.
+func NotFoundHandler(w http.ResponseWriter, r *http.Request) {
+	respondWithError(w, r, errRouteNotFound)
+}
+
+// MethodNotAllowedHandler responds to requests whose path matches a route registered for other methods.
+func MethodNotAllowedHandler(w http.ResponseWriter, r *http.Request) {
+	respondWithError(w, r, errMethodNotAllowed)
+}
+
+// PanicHandler responds to requests whose handler panicked.
+func PanicHandler(w http.ResponseWriter, r *http.Request, recovered interface{}) {
+	respondWithError(w, r, fmt.Errorf("handler panicked: %v", recovered))
+}
+
+// respondWithError writes err as problem details. The status, code and message come from the
+// apperror.Error in the chain of err; any other error is logged and reported as an internal error, or as
+// a timeout or cancellation when the request context ended before it was returned. Details of the
+// error are added as extension members.
+func respondWithError(w http.ResponseWriter, r *http.Request, err error) {
+	appErr := apperror.From(err)
+	if appErr.Is(apperror.ErrInternal) {
+		switch r.Context().Err() {
+		case context.DeadlineExceeded:
+			appErr = apperror.ErrTimeout.Wrap(err)
+		case context.Canceled:
+			appErr = apperror.ErrCanceled.Wrap(err)
+		default:
+			log.Printf("Error handling %s %s: %v", r.Method, r.URL.Path, err)
+		}
+	}
+
+	problem := make(map[string]interface{}, len(appErr.Details)+6)
+	for key, value := range appErr.Details {
+		problem[key] = value
+	}
+	problem["type"] = "about:blank"
+	problem["title"] = http.StatusText(appErr.Status)
+	problem["status"] = appErr.Status
+	problem["detail"] = appErr.Message
+	problem["code"] = appErr.Code
+	problem["instance"] = r.URL.Path
+
+	jsonResponse, err := json.Marshal(problem)
+	if err != nil {
+		log.Printf("Error encoding problem details: %v", err)
+		jsonResponse = []byte(fmt.Sprintf(`{"type":"about:blank","title":%q,"status":%d,"code":%q}`,
+			http.StatusText(appErr.Status), appErr.Status, appErr.Code))
+	}
+
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.WriteHeader(appErr.Status)
+	if _, err := w.Write(jsonResponse); err != nil {
+		log.Printf("Error writing response: %v", err)
+	}
+}
+
+// respondWithJSON writes payload as a JSON response with the given status code.
+func respondWithJSON(w http.ResponseWriter, r *http.Request, payload interface{}, statusCode int) {
+	jsonResponse, err := json.Marshal(payload)
+	if err != nil {
+		respondWithError(w, r, fmt.Errorf("encoding JSON response: %w", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	if _, err := w.Write(jsonResponse); err != nil {
+		log.Printf("Error writing response: %v", err)
+	}
+}
@@ -0,0 +1,135 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"transaction_system/app/lib/apperror"
+	"transaction_system/app/lib/validation"
+	"transaction_system/app/models"
+	"transaction_system/app/services"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// maxBatchBodySize is the largest body accepted by requests carrying a batch of transactions, room for
+// services.MaxBatchSize transactions of well over a kilobyte each.
+const maxBatchBodySize = 16 << 20
+
+// batchRequest is the body of a batch creation request.
+type batchRequest struct {
+	Mode         services.BatchMode `json:"mode"`
+	Transactions []json.RawMessage  `json:"transactions" validate:"required,min=1"`
+}
+
+// batchTransactionRequest is a transaction of a batch, which carries its ID in its body.
+type batchTransactionRequest struct {
+	ID *json.Number `json:"id" validate:"required,integer,min=0,max=9223372036854775807"`
+	transactionRequest
+}
+
+// batchItemResponse reports the outcome of one transaction of a batch.
+type batchItemResponse struct {
+	Index int `json:"index"`
+	// Line is the line of an imported file the transaction was read from.
+	Line   int                      `json:"line,omitempty"`
+	ID     uint                     `json:"id"`
+	Status services.BatchItemStatus `json:"status"`
+	// Code and Error identify and describe why the transaction could not be created.
+	Code  string `json:"code,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+func (t *transactionController) CreateTransactions(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	body, err := readBody(w, r, maxBatchBodySize)
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	// Decode and validate the request body
+	var request batchRequest
+	if _, err := validation.Decode(bytes.NewReader(body), &request); err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+	if request.Mode == "" {
+		request.Mode = services.BatchModeAtomic
+	}
+
+	// Decode and validate every transaction, rejecting the whole request if any of them is malformed
+	transactions := make([]models.Transaction, len(request.Transactions))
+	for i, transactionData := range request.Transactions {
+		var transactionRequest batchTransactionRequest
+		if _, err := validation.Decode(bytes.NewReader(transactionData), &transactionRequest); err != nil {
+			respondWithError(w, r, apperror.From(err).WithDetails(map[string]interface{}{
+				"index": i,
+			}))
+			return
+		}
+		transactions[i] = transactionRequest.transaction(parseID(*transactionRequest.ID))
+	}
+
+	// Call the service to create the transactions
+	result, err := t.transactionService.CreateTransactions(r.Context(), transactions, request.Mode)
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	results := make([]batchItemResponse, len(result.Results))
+	for i, itemResult := range result.Results {
+		results[i] = batchItemResponse{
+			Index:  itemResult.Index,
+			ID:     itemResult.ID,
+			Status: itemResult.Status,
+		}
+		results[i].setError(r, itemResult.Err)
+	}
+
+	// An atomic batch is created as a whole or rejected as a whole; a best-effort batch reports per item
+	statusCode := http.StatusOK
+	if request.Mode == services.BatchModeAtomic {
+		statusCode = http.StatusCreated
+		if !result.Committed {
+			statusCode = http.StatusUnprocessableEntity
+		}
+	}
+	respondWithJSON(w, r, map[string]interface{}{
+		"mode":      request.Mode,
+		"committed": result.Committed,
+		"results":   results,
+	}, statusCode)
+}
+
+// setError records why the transaction could not be created, with the code and message an error response
+// to the request would carry. Unexpected errors are logged rather than described.
+func (item *batchItemResponse) setError(r *http.Request, err error) {
+	if err == nil {
+		return
+	}
+	appErr := apperror.From(err)
+	if appErr.Is(apperror.ErrInternal) {
+		log.Printf("Error creating transaction %d of %s %s: %v", item.ID, r.Method, r.URL.Path, err)
+	}
+	item.Code = appErr.Code
+	item.Error = appErr.Message
+}
+
+// readBody reads the whole body of r, failing with errBodyTooLarge rather than reading past limit bytes.
+func readBody(w http.ResponseWriter, r *http.Request, limit int64) ([]byte, error) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, limit))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return nil, errBodyTooLarge.WithDetails(map[string]interface{}{
+				"max_bytes": limit,
+			})
+		}
+		return nil, apperror.InvalidRequest("Error reading request body")
+	}
+	return body, nil
+}
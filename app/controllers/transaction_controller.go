@@ -1,20 +1,41 @@
 package controllers
 
 import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
-	"fmt"
+	"errors"
+	"math"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
+	"transaction_system/app/lib/apperror"
+	"transaction_system/app/lib/currency"
+	"transaction_system/app/lib/validation"
 	"transaction_system/app/models"
 	"transaction_system/app/repositories"
 	"transaction_system/app/services"
 
 	"github.com/julienschmidt/httprouter"
+	"github.com/shopspring/decimal"
 )
 
+// maxTransactionID is the largest transaction ID, the upper bound of the BIGINT id column.
+const maxTransactionID = math.MaxInt64
+
 type TransactionControllerI interface {
 	CreateTransaction(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	CreateTransactions(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	ImportTransactions(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	ExportTransactions(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	UpdateTransaction(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	DeleteTransaction(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	GetTransaction(w http.ResponseWriter, r *http.Request, params httprouter.Params)
 	GetTransactionsByType(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	GetTransactionTree(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	GetAncestors(w http.ResponseWriter, r *http.Request, params httprouter.Params)
 	GetTransitiveSum(w http.ResponseWriter, r *http.Request, params httprouter.Params)
 }
 
@@ -36,176 +57,257 @@ func MakeTransactionController(transactionService services.TransactionServiceI)
 
 func (t *transactionController) CreateTransaction(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
 	// Retrieve transaction ID from URL params
-	transactionID := params.ByName("transaction_id")
-	if transactionID == "" {
-		respondWithError(w, "Transaction ID is required", http.StatusBadRequest)
+	transactionID, err := transactionIDFromParams(params)
+	if err != nil {
+		respondWithError(w, r, err)
 		return
 	}
 
-	// Convert transaction ID to uint
-	transactionIDUint, err := strconv.ParseUint(transactionID, 10, 64)
-	if err != nil {
-		respondWithError(w, "Invalid transaction ID format", http.StatusBadRequest)
+	// Decode and validate the request body
+	var request transactionRequest
+	if _, err := validation.Decode(r.Body, &request); err != nil {
+		respondWithError(w, r, err)
 		return
 	}
+	newTransaction := request.transaction(transactionID)
 
-	// Decode request body
-	var transactionData map[string]interface{}
-	decoder := json.NewDecoder(r.Body)
-	if err := decoder.Decode(&transactionData); err != nil {
-		respondWithError(w, "Error decoding request body", http.StatusBadRequest)
+	// Call the service to create the transaction
+	storedTransaction, created, err := t.transactionService.CreateTransaction(r.Context(), newTransaction)
+	if err != nil {
+		respondWithError(w, r, err)
 		return
 	}
 
-	// Validate schema
-	validatedData, isValid := validateSchema(w, transactionData, "amount", "type")
+	// Respond with the created transaction status, or with the stored transaction for an identical replay
+	if created {
+		respondWithJSON(w, r, map[string]interface{}{
+			"status": getStatusMessage(created),
+		}, http.StatusCreated)
+		return
+	}
+	respondWithJSON(w, r, map[string]interface{}{
+		"status":      getStatusMessage(true),
+		"transaction": storedTransaction,
+	}, http.StatusOK)
+}
 
-	if !isValid {
+func (t *transactionController) UpdateTransaction(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	transactionID, err := transactionIDFromParams(params)
+	if err != nil {
+		respondWithError(w, r, err)
 		return
 	}
 
-	// Extract transaction details from parsed data
-	amount, ok := validatedData["amount"].(float64)
-	if !ok {
-		respondWithError(w, "Invalid amount format", http.StatusBadRequest)
+	// Decode and validate the request body; absent fields are left unchanged
+	var request transactionUpdateRequest
+	fields, err := validation.Decode(r.Body, &request)
+	if err != nil {
+		respondWithError(w, r, err)
 		return
 	}
+	if len(fields) == 0 {
+		respondWithError(w, r, apperror.InvalidRequest("At least one of 'amount', 'type', 'currency', 'parent_id' or 'occurred_at' is required"))
+		return
+	}
+	update := models.TransactionUpdate{
+		Amount:     request.Amount,
+		Type:       request.Type,
+		OccurredAt: request.OccurredAt,
+		// A null parent_id detaches the transaction from its parent
+		ParentID:    parseParentID(request.ParentID),
+		SetParentID: fields["parent_id"],
+	}
+	if request.Currency != nil {
+		currencyCode := currency.Normalize(*request.Currency)
+		update.Currency = &currencyCode
+	}
 
-	transactionType, ok := validatedData["type"].(string)
-	if !ok {
-		respondWithError(w, "Invalid type format", http.StatusBadRequest)
+	// Call the service to update the transaction
+	transaction, err := t.transactionService.UpdateTransaction(r.Context(), transactionID, update)
+	if err != nil {
+		respondWithError(w, r, err)
 		return
 	}
 
-	// Extract parent_id and set it to nil if not present
-	var parentID *uint
-	if val, exists := transactionData["parent_id"]; exists {
-		if parentIDValue, isUint := val.(float64); isUint {
-			parentIDValueUint := uint(parentIDValue)
-			parentID = &parentIDValueUint
-		} else {
-			respondWithError(w, "Invalid parent_id format", http.StatusBadRequest)
-			return
-		}
+	respondWithJSON(w, r, map[string]interface{}{
+		"status":      getStatusMessage(true),
+		"transaction": transaction,
+	}, http.StatusOK)
+}
+
+func (t *transactionController) DeleteTransaction(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	transactionID, err := transactionIDFromParams(params)
+	if err != nil {
+		respondWithError(w, r, err)
+		return
 	}
 
-	// Create a new transaction object
-	newTransaction := models.Transaction{
-		Id:       uint(transactionIDUint),
-		Amount:   amount,
-		Type:     transactionType,
-		ParentID: parentID,
+	// Decide what happens to the children of the transaction, rejecting the delete by default
+	mode := repositories.DeleteModeReject
+	if onChildren := r.URL.Query().Get("on_children"); onChildren != "" {
+		mode = repositories.DeleteMode(onChildren)
+	}
+	switch mode {
+	case repositories.DeleteModeReject, repositories.DeleteModeCascade, repositories.DeleteModeReparent:
+	default:
+		respondWithError(w, r, apperror.InvalidRequest("Invalid on_children value, expected one of 'reject', 'cascade' or 'reparent'"))
+		return
 	}
 
-	// Call the service to create the transaction
-	status, err := t.transactionService.CreateTransaction(newTransaction)
-	if err != nil {
-		if err == services.ErrParentTransactionNotFound {
-			// Handling "Parent transaction does not exist" as Bad Request
-			respondWithError(w, "Parent transaction does not exist", http.StatusBadRequest)
-			return
+	// Call the service to delete the transaction
+	if err := t.transactionService.DeleteTransaction(r.Context(), transactionID, mode); err != nil {
+		if errors.Is(err, repositories.ErrTransactionHasChildren) {
+			err = repositories.ErrTransactionHasChildren.
+				WithMessage("transaction has child transactions, use on_children=cascade or on_children=reparent").
+				Wrap(err)
 		}
-		if err == repositories.ErrTransactionAlreadyExist {
-			// Handling "transaction does not exist" as Bad Request
-			respondWithError(w, "transaction with the same ID already exists", http.StatusBadRequest)
-			return
-		}
-		respondWithError(w, fmt.Sprintf("Error creating transaction: %v", err), http.StatusInternalServerError)
+		respondWithError(w, r, err)
 		return
 	}
 
-	// Respond with the created transaction status
-	response := map[string]string{
-		"status": getStatusMessage(status),
-	}
-	jsonResponse, err := json.Marshal(response)
+	respondWithJSON(w, r, map[string]string{"status": getStatusMessage(true)}, http.StatusOK)
+}
+
+func (t *transactionController) GetTransaction(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	transactionID, err := transactionIDFromParams(params)
 	if err != nil {
-		respondWithError(w, "Error encoding JSON response", http.StatusInternalServerError)
+		respondWithError(w, r, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	_, err = w.Write(jsonResponse)
+	// Optional details, e.g. ?include=children,depth
+	var opts services.GetTransactionOptions
+	for _, include := range strings.Split(r.URL.Query().Get("include"), ",") {
+		switch strings.TrimSpace(include) {
+		case "":
+		case "children":
+			opts.IncludeChildren = true
+		case "depth":
+			opts.IncludeDepth = true
+		default:
+			respondWithError(w, r, apperror.InvalidRequest("Invalid include value '%s', expected 'children' or 'depth'", include))
+			return
+		}
+	}
+
+	// Call the service to get the transaction
+	transaction, err := t.transactionService.GetTransaction(r.Context(), transactionID, opts)
 	if err != nil {
-		respondWithError(w, "Error writing response", http.StatusInternalServerError)
+		respondWithError(w, r, err)
 		return
 	}
+
+	respondWithJSON(w, r, transaction, http.StatusOK)
 }
 
 func (t *transactionController) GetTransactionsByType(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
 	// Extract transaction type from URL params
 	transactionType := params.ByName("type")
 	if transactionType == "" {
-		http.Error(w, "Transaction type is required", http.StatusBadRequest)
+		respondWithError(w, r, apperror.InvalidRequest("Transaction type is required"))
 		return
 	}
 
-	// Call the service to get transaction IDs by type
-	transactionIDs, err := t.transactionService.GetTransactionIDsByType(transactionType)
+	// Parse pagination and filter parameters
+	query, err := parseTypeQuery(r, transactionType)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Error retrieving transaction IDs: %v", err), http.StatusInternalServerError)
+		respondWithError(w, r, err)
 		return
 	}
 
-	// Respond with the list of transaction IDs
-	response := map[string][]uint{"transaction_ids": transactionIDs}
-	jsonResponse, err := json.Marshal(response)
+	// Call the service to get a page of transaction IDs by type
+	page, err := t.transactionService.GetTransactionIDsByType(r.Context(), query)
 	if err != nil {
-		respondWithError(w, "Error encoding JSON response", http.StatusInternalServerError)
+		respondWithError(w, r, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	_, err = w.Write(jsonResponse)
+	// Respond with the page of transaction IDs and the cursor of the next page
+	var nextCursor *string
+	if page.NextAfterID != nil {
+		cursor := encodeCursor(*page.NextAfterID, query)
+		nextCursor = &cursor
+	}
+	respondWithJSON(w, r, map[string]interface{}{
+		"transaction_ids": page.TransactionIDs,
+		"next_cursor":     nextCursor,
+	}, http.StatusOK)
+}
+
+func (t *transactionController) GetTransactionTree(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	transactionID, err := transactionIDFromParams(params)
 	if err != nil {
-		respondWithError(w, "Error writing response", http.StatusInternalServerError)
+		respondWithError(w, r, err)
 		return
 	}
-}
 
-func (t *transactionController) GetTransitiveSum(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
-	transactionID := params.ByName("transaction_id")
-	if transactionID == "" {
-		respondWithError(w, "Transaction ID is required", http.StatusBadRequest)
+	values := r.URL.Query()
+	opts := services.TreeOptions{Type: values.Get("type")}
+	if maxDepth := values.Get("max_depth"); maxDepth != "" {
+		maxDepthInt, err := strconv.Atoi(maxDepth)
+		if err != nil || maxDepthInt < 1 {
+			respondWithError(w, r, apperror.InvalidRequest("Invalid max_depth, expected a positive integer"))
+			return
+		}
+		opts.MaxDepth = maxDepthInt
+	}
+
+	format := values.Get("format")
+	if format != "" && format != "nested" && format != "flat" {
+		respondWithError(w, r, apperror.InvalidRequest("Invalid format, expected 'nested' or 'flat'"))
 		return
 	}
 
-	// Convert transactionID to uint
-	transactionIDUint, err := strconv.ParseUint(transactionID, 10, 64)
+	// Call the service to get the subtree
+	nodes, err := t.transactionService.GetTransactionTree(r.Context(), transactionID, opts)
 	if err != nil {
-		respondWithError(w, "Invalid transaction ID format", http.StatusBadRequest)
+		respondWithError(w, r, err)
 		return
 	}
 
-	// Call the service to get the sum
-	sum, err := t.transactionService.GetTransitiveSum(uint(transactionIDUint))
+	if format == "flat" {
+		respondWithJSON(w, r, map[string]interface{}{"transactions": nodes}, http.StatusOK)
+		return
+	}
+	respondWithJSON(w, r, map[string]interface{}{"tree": services.NestTransactionTree(nodes)}, http.StatusOK)
+}
+
+func (t *transactionController) GetAncestors(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	transactionID, err := transactionIDFromParams(params)
 	if err != nil {
-		if err == services.ErrTransactionNotFound {
-			// Handling "Transaction does not exist" as Bad Request
-			respondWithError(w, "Transaction does not exist for given transaction ID", http.StatusBadRequest)
-			return
-		}
-		respondWithError(w, fmt.Sprintf("Error retrieving transitive sum: %v", err), http.StatusInternalServerError)
+		respondWithError(w, r, err)
 		return
 	}
 
-	// Respond with the sum
-	response := map[string]float64{"sum": sum}
-	jsonResponse, err := json.Marshal(response)
+	// Call the service to get the ancestor chain
+	chain, err := t.transactionService.GetAncestors(r.Context(), transactionID)
 	if err != nil {
-		respondWithError(w, "Error encoding JSON response", http.StatusInternalServerError)
+		respondWithError(w, r, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	_, err = w.Write(jsonResponse)
+	respondWithJSON(w, r, chain, http.StatusOK)
+}
+
+func (t *transactionController) GetTransitiveSum(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	transactionID, err := transactionIDFromParams(params)
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	// Optional currency to convert the sum into
+	targetCurrency := currency.Normalize(r.URL.Query().Get("currency"))
+
+	// Call the service to get the sum
+	sum, err := t.transactionService.GetTransitiveSum(r.Context(), transactionID, targetCurrency)
 	if err != nil {
-		respondWithError(w, "Error writing response", http.StatusInternalServerError)
+		respondWithError(w, r, err)
 		return
 	}
+
+	// Respond with the sum and its per-currency subtotals
+	respondWithJSON(w, r, sum, http.StatusOK)
 }
 
 // getStatusMessage returns a human-readable status message based on the transaction creation status.
@@ -216,39 +318,181 @@ func getStatusMessage(status bool) string {
 	return "unable to create transaction"
 }
 
-func respondWithError(w http.ResponseWriter, errMsg string, statusCode int) {
-	errorResponse := map[string]interface{}{
-		"success": "false",
-		"error":   errMsg,
-		"status":  statusCode,
+// parseTypeQuery builds a repositories.TypeQuery from the limit, cursor, order, min_amount, max_amount
+// and parent_id query parameters, returning an error when one of them is invalid or when the cursor was
+// issued for different filters.
+func parseTypeQuery(r *http.Request, transactionType string) (repositories.TypeQuery, error) {
+	values := r.URL.Query()
+	query := repositories.TypeQuery{Type: transactionType}
+
+	if limit := values.Get("limit"); limit != "" {
+		limitInt, err := strconv.Atoi(limit)
+		if err != nil || limitInt < 1 || limitInt > services.MaxPageLimit {
+			return query, apperror.InvalidRequest("Invalid limit, expected an integer between 1 and %d", services.MaxPageLimit)
+		}
+		query.Limit = limitInt
+	}
+
+	switch values.Get("order") {
+	case "", "asc":
+	case "desc":
+		query.Descending = true
+	default:
+		return query, apperror.InvalidRequest("Invalid order, expected 'asc' or 'desc'")
+	}
+
+	if minAmount := values.Get("min_amount"); minAmount != "" {
+		amount, err := decimal.NewFromString(minAmount)
+		if err != nil {
+			return query, apperror.InvalidRequest("Invalid min_amount format")
+		}
+		query.MinAmount = &amount
+	}
+
+	if maxAmount := values.Get("max_amount"); maxAmount != "" {
+		amount, err := decimal.NewFromString(maxAmount)
+		if err != nil {
+			return query, apperror.InvalidRequest("Invalid max_amount format")
+		}
+		query.MaxAmount = &amount
+	}
+
+	if parentID := values.Get("parent_id"); parentID != "" {
+		parentIDValue, err := parseTransactionID(parentID, "parent_id")
+		if err != nil {
+			return query, err
+		}
+		query.ParentID = &parentIDValue
 	}
 
-	jsonResponse, err := json.Marshal(errorResponse)
+	// A cursor only makes sense for the filters of the query it was issued for
+	if cursor := values.Get("cursor"); cursor != "" {
+		afterID, digest, ok := decodeCursor(cursor)
+		if !ok {
+			return query, apperror.InvalidRequest("Invalid cursor")
+		}
+		if digest != queryDigest(query) {
+			return query, apperror.InvalidRequest("Cursor does not match the order and filters of the query")
+		}
+		query.AfterID = &afterID
+	}
+
+	return query, nil
+}
+
+// encodeCursor turns the last ID of a page of query into an opaque pagination cursor, which also carries
+// a digest of the order and filters of query.
+func encodeCursor(afterID uint, query repositories.TypeQuery) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatUint(uint64(afterID), 10) + "." + queryDigest(query)))
+}
+
+// decodeCursor extracts the last ID of the previous page and the digest of its query from a pagination cursor.
+func decodeCursor(cursor string) (uint, string, bool) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
 	if err != nil {
-		http.Error(w, "Error encoding JSON response", http.StatusInternalServerError)
-		return
+		return 0, "", false
+	}
+	id, digest, found := strings.Cut(string(raw), ".")
+	if !found {
+		return 0, "", false
+	}
+	afterID, err := parseTransactionID(id, "cursor")
+	if err != nil {
+		return 0, "", false
+	}
+	return afterID, digest, true
+}
+
+// queryDigest hashes the type, order and filters of query, leaving out its page size and position.
+// Amounts are hashed in canonical form, so that e.g. 10 and 10.0 are the same filter.
+func queryDigest(query repositories.TypeQuery) string {
+	var minAmount, maxAmount, parentID string
+	if query.MinAmount != nil {
+		minAmount = query.MinAmount.String()
+	}
+	if query.MaxAmount != nil {
+		maxAmount = query.MaxAmount.String()
+	}
+	if query.ParentID != nil {
+		parentID = strconv.FormatUint(uint64(*query.ParentID), 10)
+	}
+	sum := sha256.Sum256([]byte(strings.Join([]string{
+		query.Type, strconv.FormatBool(query.Descending), minAmount, maxAmount, parentID,
+	}, "\x00")))
+	return hex.EncodeToString(sum[:8])
+}
+
+// transactionIDFromParams parses the transaction ID URL parameter, returning an error when it is missing or invalid.
+func transactionIDFromParams(params httprouter.Params) (uint, error) {
+	transactionID := params.ByName("transaction_id")
+	if transactionID == "" {
+		return 0, apperror.InvalidRequest("Transaction ID is required")
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(statusCode)
-	_, err = w.Write(jsonResponse)
+	return parseTransactionID(transactionID, "transaction ID")
+}
+
+// parseTransactionID parses a decimal transaction ID, returning an error naming field when it is not a
+// non-negative integer or does not fit the BIGINT id column.
+func parseTransactionID(value, field string) (uint, error) {
+	id, err := strconv.ParseUint(value, 10, 64)
+	if errors.Is(err, strconv.ErrRange) || (err == nil && id > maxTransactionID) {
+		return 0, apperror.InvalidRequest("Invalid %s, expected an integer between 0 and %d", field, maxTransactionID)
+	}
 	if err != nil {
-		http.Error(w, "Error writing response", http.StatusInternalServerError)
-		return
+		return 0, apperror.InvalidRequest("Invalid %s format", field)
 	}
+	return uint(id), nil
 }
 
-func validateSchema(w http.ResponseWriter, data map[string]interface{}, expectedFields ...string) (map[string]interface{}, bool) {
-	result := make(map[string]interface{})
+// transactionRequest is the body of a request creating a transaction.
+type transactionRequest struct {
+	Amount   *decimal.Decimal `json:"amount" validate:"required,finite,digits=38,scale=18"`
+	Type     *string          `json:"type" validate:"required,notblank,max=50"`
+	ParentID *json.Number     `json:"parent_id" validate:"integer,min=0,max=9223372036854775807"`
+	// Currency is left empty when absent so that the service applies the default currency.
+	Currency *string `json:"currency" validate:"currency"`
+	// OccurredAt is the business time of the transaction, an RFC 3339 timestamp.
+	OccurredAt *time.Time `json:"occurred_at"`
+}
 
-	for _, field := range expectedFields {
-		val, exists := data[field]
-		if !exists {
-			respondWithError(w, fmt.Sprintf("Field '%s' is missing", field), http.StatusBadRequest)
-			return nil, false
-		}
-		result[field] = val
+// transactionUpdateRequest is the body of a request updating a transaction, whose absent fields are left
+// unchanged.
+type transactionUpdateRequest struct {
+	Amount     *decimal.Decimal `json:"amount" validate:"notnull,finite,digits=38,scale=18"`
+	Type       *string          `json:"type" validate:"notnull,notblank,max=50"`
+	Currency   *string          `json:"currency" validate:"notnull,currency"`
+	ParentID   *json.Number     `json:"parent_id" validate:"integer,min=0,max=9223372036854775807"`
+	OccurredAt *time.Time       `json:"occurred_at" validate:"notnull"`
+}
+
+// transaction builds the transaction with the given ID from a validated request.
+func (request transactionRequest) transaction(transactionID uint) models.Transaction {
+	transaction := models.Transaction{
+		Id:         transactionID,
+		Amount:     *request.Amount,
+		Type:       *request.Type,
+		ParentID:   parseParentID(request.ParentID),
+		OccurredAt: request.OccurredAt,
 	}
+	if request.Currency != nil {
+		transaction.Currency = currency.Normalize(*request.Currency)
+	}
+	return transaction
+}
 
-	return result, true
+// parseID converts a JSON number into a transaction ID. The number must have been validated as an integer
+// between 0 and maxTransactionID, which the validate tags of request IDs check.
+func parseID(number json.Number) uint {
+	id, _ := decimal.NewFromString(number.String())
+	return uint(id.IntPart())
+}
+
+// parseParentID converts a validated parent_id into a transaction ID, nil when it is absent.
+func parseParentID(number *json.Number) *uint {
+	if number == nil {
+		return nil
+	}
+	parentID := parseID(*number)
+	return &parentID
 }
@@ -2,17 +2,24 @@ package controllers_test
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/golang/mock/gomock"
 	"github.com/julienschmidt/httprouter"
+	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 	"transaction_system/app/controllers"
+	"transaction_system/app/lib/audit"
+	"transaction_system/app/lib/rates"
+	"transaction_system/app/models"
 	"transaction_system/app/repositories"
 	"transaction_system/app/services"
 	"transaction_system/app/services/mock_services"
@@ -45,7 +52,7 @@ func TestCreateTransaction_Success(t *testing.T) {
 		}
 
 		// Mock expectations
-		mockTransactionService.EXPECT().CreateTransaction(gomock.Any()).Return(true, nil)
+		mockTransactionService.EXPECT().CreateTransaction(gomock.Any(), gomock.Any()).Return(&models.Transaction{}, true, nil)
 
 		req, _ := http.NewRequest("PUT", fmt.Sprintf("/transactionservice/transaction/%d", transactionID), bytes.NewBuffer(jsonRequest))
 		recorder := httptest.NewRecorder()
@@ -87,7 +94,7 @@ func TestCreateTransaction_Success(t *testing.T) {
 		}
 
 		// Mock expectations
-		mockTransactionService.EXPECT().CreateTransaction(gomock.Any()).Return(true, nil)
+		mockTransactionService.EXPECT().CreateTransaction(gomock.Any(), gomock.Any()).Return(&models.Transaction{}, true, nil)
 
 		req, _ := http.NewRequest("PUT", fmt.Sprintf("/transactionservice/transaction/%d", transactionID), bytes.NewBuffer(jsonRequest))
 		recorder := httptest.NewRecorder()
@@ -135,10 +142,115 @@ func TestCreateTransaction_InvalidTransactionID(t *testing.T) {
 	assert.Equal(t, http.StatusBadRequest, recorder.Code)
 
 	// Assert response body contains expected error message
-	expectedResponse := `{"error":"Invalid transaction ID format","status":400,"success":"false"}`
+	expectedResponse := `{"code":"invalid_request","detail":"Invalid transaction ID format","instance":"/transactionservice/transaction/invalid_id","status":400,"title":"Bad Request","type":"about:blank"}`
 	assert.Equal(t, expectedResponse, recorder.Body.String())
 }
 
+func TestCreateTransaction_TransactionIDOutOfRange(t *testing.T) {
+	for _, tc := range []struct {
+		transactionID string
+		detail        string
+	}{
+		{"9223372036854775808", "Invalid transaction ID, expected an integer between 0 and 9223372036854775807"},
+		{"18446744073709551616", "Invalid transaction ID, expected an integer between 0 and 9223372036854775807"},
+		{"-1", "Invalid transaction ID format"},
+		{"3.7", "Invalid transaction ID format"},
+	} {
+		t.Run(tc.transactionID, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			// Mocks
+			mockTransactionService := mock_services.NewMockTransactionServiceI(ctrl)
+
+			// Controller
+			transactionController := controllers.MakeTransactionController(mockTransactionService)
+
+			req, _ := http.NewRequest("PUT", "/transactionservice/transaction/"+tc.transactionID, bytes.NewBufferString(`{"amount": 100, "type": "purchase"}`))
+			recorder := httptest.NewRecorder()
+			router := httprouter.New()
+			router.Handle(http.MethodPut, "/transactionservice/transaction/:transaction_id", transactionController.CreateTransaction)
+			router.ServeHTTP(recorder, req)
+
+			// Assert status code is BadRequest and the service is not called
+			assert.Equal(t, http.StatusBadRequest, recorder.Code)
+
+			// Assert response body contains expected error message
+			expectedResponse := fmt.Sprintf(`{"code":"invalid_request","detail":%q,"instance":"/transactionservice/transaction/%s","status":400,"title":"Bad Request","type":"about:blank"}`,
+				tc.detail, tc.transactionID)
+			assert.Equal(t, expectedResponse, recorder.Body.String())
+		})
+	}
+}
+
+func TestCreateTransaction_StrictParentID(t *testing.T) {
+	for _, tc := range []struct {
+		parentID string
+		rule     string
+		message  string
+	}{
+		{"3.7", "integer", "must be an integer"},
+		{"-1", "min", "must be at least 0"},
+		{"9223372036854775808", "max", "must be at most 9223372036854775807"},
+		{"1e19", "max", "must be at most 9223372036854775807"},
+		{`"5"`, "type", "must be a number"},
+	} {
+		t.Run(tc.parentID, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			// Mocks
+			mockTransactionService := mock_services.NewMockTransactionServiceI(ctrl)
+
+			// Controller
+			transactionController := controllers.MakeTransactionController(mockTransactionService)
+
+			jsonRequest := []byte(`{"amount": 100, "type": "purchase", "parent_id": ` + tc.parentID + `}`)
+
+			req, _ := http.NewRequest("PUT", "/transactionservice/transaction/3", bytes.NewBuffer(jsonRequest))
+			recorder := httptest.NewRecorder()
+			router := httprouter.New()
+			router.Handle(http.MethodPut, "/transactionservice/transaction/:transaction_id", transactionController.CreateTransaction)
+			router.ServeHTTP(recorder, req)
+
+			// Assert status code is BadRequest and the service is not called
+			assert.Equal(t, http.StatusBadRequest, recorder.Code)
+
+			// Assert response body names the rule parent_id failed
+			expectedResponse := fmt.Sprintf(`{"code":"validation_failed","detail":"parent_id %s","errors":[{"field":"parent_id","rule":"%s","message":"%s"}],`+
+				`"instance":"/transactionservice/transaction/3","status":400,"title":"Bad Request","type":"about:blank"}`, tc.message, tc.rule, tc.message)
+			assert.Equal(t, expectedResponse, recorder.Body.String())
+		})
+	}
+}
+
+func TestCreateTransaction_LargestParentID(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Mocks
+	mockTransactionService := mock_services.NewMockTransactionServiceI(ctrl)
+
+	// Controller
+	transactionController := controllers.MakeTransactionController(mockTransactionService)
+
+	// Mock expectations
+	parentID := uint(9223372036854775807)
+	expectedTransaction := models.Transaction{Id: 9223372036854775807, Amount: decimal.NewFromInt(100), Type: "purchase", ParentID: &parentID}
+	mockTransactionService.EXPECT().CreateTransaction(gomock.Any(), expectedTransaction).Return(&expectedTransaction, true, nil)
+
+	jsonRequest := []byte(`{"amount": 100, "type": "purchase", "parent_id": 9223372036854775807}`)
+
+	req, _ := http.NewRequest("PUT", "/transactionservice/transaction/9223372036854775807", bytes.NewBuffer(jsonRequest))
+	recorder := httptest.NewRecorder()
+	router := httprouter.New()
+	router.Handle(http.MethodPut, "/transactionservice/transaction/:transaction_id", transactionController.CreateTransaction)
+	router.ServeHTTP(recorder, req)
+
+	// Assert the largest BIGINT IDs are accepted exactly
+	assert.Equal(t, http.StatusCreated, recorder.Code)
+}
+
 func TestCreateTransaction_InvalidAmountFormat(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -174,10 +286,41 @@ func TestCreateTransaction_InvalidAmountFormat(t *testing.T) {
 	assert.Equal(t, http.StatusBadRequest, recorder.Code)
 
 	// Assert response body contains expected error message
-	expectedResponse := `{"error":"Invalid amount format","status":400,"success":"false"}`
+	expectedResponse := `{"code":"validation_failed","detail":"amount must be a number","errors":[{"field":"amount","rule":"type","message":"must be a number"}],"instance":"/transactionservice/transaction/1","status":400,"title":"Bad Request","type":"about:blank"}`
 	assert.Equal(t, expectedResponse, recorder.Body.String())
 }
 
+func TestTransaction_AmountPrecision(t *testing.T) {
+	for _, tc := range []struct {
+		method string
+		body   string
+	}{
+		{"PUT", `{"amount": 1e-100000000, "type": "purchase"}`},
+		{"PATCH", `{"amount": 1e-100000000}`},
+		{"PATCH", `{"amount": 0.1234567890123456789}`},
+	} {
+		ctrl := gomock.NewController(t)
+
+		// Mocks
+		mockTransactionService := mock_services.NewMockTransactionServiceI(ctrl)
+
+		// Controller
+		transactionController := controllers.MakeTransactionController(mockTransactionService)
+
+		req, _ := http.NewRequest(tc.method, "/transactionservice/transaction/1", bytes.NewBufferString(tc.body))
+		recorder := httptest.NewRecorder()
+		router := httprouter.New()
+		router.Handle(http.MethodPut, "/transactionservice/transaction/:transaction_id", transactionController.CreateTransaction)
+		router.Handle(http.MethodPatch, "/transactionservice/transaction/:transaction_id", transactionController.UpdateTransaction)
+		router.ServeHTTP(recorder, req)
+
+		// Assert amounts with an extreme scale are rejected before reaching the service
+		assert.Equal(t, http.StatusBadRequest, recorder.Code, tc.body)
+		assert.Contains(t, recorder.Body.String(), `"field":"amount"`, tc.body)
+		ctrl.Finish()
+	}
+}
+
 func TestCreateTransaction_MissingAmountField(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -212,7 +355,37 @@ func TestCreateTransaction_MissingAmountField(t *testing.T) {
 	assert.Equal(t, http.StatusBadRequest, recorder.Code)
 
 	// Assert response body contains expected error message
-	expectedResponse := `{"error":"Field 'amount' is missing","status":400,"success":"false"}`
+	expectedResponse := `{"code":"validation_failed","detail":"amount is required","errors":[{"field":"amount","rule":"required","message":"is required"}],"instance":"/transactionservice/transaction/1","status":400,"title":"Bad Request","type":"about:blank"}`
+	assert.Equal(t, expectedResponse, recorder.Body.String())
+}
+
+func TestCreateTransaction_ReportsAllFieldErrors(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Mocks
+	mockTransactionService := mock_services.NewMockTransactionServiceI(ctrl)
+
+	// Controller
+	transactionController := controllers.MakeTransactionController(mockTransactionService)
+
+	// Request body with a non-finite amount, an overlong type, a fractional parent_id and an unknown field
+	jsonRequest := []byte(`{"amount": 1e309, "type": "` + strings.Repeat("a", 51) + `", "parent_id": 3.7, "category": "food"}`)
+
+	req, _ := http.NewRequest("PUT", "/transactionservice/transaction/1", bytes.NewBuffer(jsonRequest))
+	recorder := httptest.NewRecorder()
+	router := httprouter.New()
+	router.Handle(http.MethodPut, "/transactionservice/transaction/:transaction_id", transactionController.CreateTransaction)
+	router.ServeHTTP(recorder, req)
+
+	// Assert status code is BadRequest
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+
+	// Assert response body lists every invalid field
+	expectedResponse := `{"code":"validation_failed","detail":"amount must be a finite number; category is not a known field; parent_id must be an integer; type must contain at most 50 characters",` +
+		`"errors":[{"field":"amount","rule":"finite","message":"must be a finite number"},{"field":"category","rule":"unknown","message":"is not a known field"},` +
+		`{"field":"parent_id","rule":"integer","message":"must be an integer"},{"field":"type","rule":"max","message":"must contain at most 50 characters"}],` +
+		`"instance":"/transactionservice/transaction/1","status":400,"title":"Bad Request","type":"about:blank"}`
 	assert.Equal(t, expectedResponse, recorder.Body.String())
 }
 
@@ -252,7 +425,7 @@ func TestCreateTransaction_InvalidParentIDFormat(t *testing.T) {
 	assert.Equal(t, http.StatusBadRequest, recorder.Code)
 
 	// Assert response body contains expected error message
-	expectedResponse := `{"error":"Invalid parent_id format","status":400,"success":"false"}`
+	expectedResponse := `{"code":"validation_failed","detail":"parent_id must be a number","errors":[{"field":"parent_id","rule":"type","message":"must be a number"}],"instance":"/transactionservice/transaction/123","status":400,"title":"Bad Request","type":"about:blank"}`
 	assert.Equal(t, expectedResponse, recorder.Body.String())
 }
 
@@ -282,7 +455,7 @@ func TestCreateTransaction_ParentTransactionNotFound(t *testing.T) {
 	}
 
 	// Mock expectations
-	mockTransactionService.EXPECT().CreateTransaction(gomock.Any()).Return(false, services.ErrParentTransactionNotFound)
+	mockTransactionService.EXPECT().CreateTransaction(gomock.Any(), gomock.Any()).Return(nil, false, services.ErrParentTransactionNotFound)
 
 	req, _ := http.NewRequest("PUT", fmt.Sprintf("/transactionservice/transaction/%d", transactionID), bytes.NewBuffer(jsonRequest))
 	recorder := httptest.NewRecorder()
@@ -294,7 +467,7 @@ func TestCreateTransaction_ParentTransactionNotFound(t *testing.T) {
 	assert.Equal(t, http.StatusBadRequest, recorder.Code)
 
 	// Assert response body contains expected error message
-	expectedResponse := `{"error":"Parent transaction does not exist","status":400,"success":"false"}`
+	expectedResponse := `{"code":"parent_not_found","detail":"parent transaction does not exist","instance":"/transactionservice/transaction/1","status":400,"title":"Bad Request","type":"about:blank"}`
 	assert.Equal(t, expectedResponse, recorder.Body.String())
 }
 
@@ -324,7 +497,7 @@ func TestCreateTransaction_TransactionAlreadyExist(t *testing.T) {
 	}
 
 	// Mock expectations
-	mockTransactionService.EXPECT().CreateTransaction(gomock.Any()).Return(false, repositories.ErrTransactionAlreadyExist)
+	mockTransactionService.EXPECT().CreateTransaction(gomock.Any(), gomock.Any()).Return(nil, false, repositories.ErrTransactionAlreadyExist)
 
 	req, _ := http.NewRequest("PUT", fmt.Sprintf("/transactionservice/transaction/%d", transactionID), bytes.NewBuffer(jsonRequest))
 	recorder := httptest.NewRecorder()
@@ -332,11 +505,69 @@ func TestCreateTransaction_TransactionAlreadyExist(t *testing.T) {
 	router.Handle(http.MethodPut, "/transactionservice/transaction/:transaction_id", transactionController.CreateTransaction)
 	router.ServeHTTP(recorder, req)
 
-	// Assert status code is BadRequest
-	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	// Assert status code is Conflict
+	assert.Equal(t, http.StatusConflict, recorder.Code)
 
 	// Assert response body contains expected error message
-	expectedResponse := `{"error":"transaction with the same ID already exists","status":400,"success":"false"}`
+	expectedResponse := `{"code":"transaction_already_exists","detail":"transaction with the same ID already exists","instance":"/transactionservice/transaction/1","status":409,"title":"Conflict","type":"about:blank"}`
+	assert.Equal(t, expectedResponse, recorder.Body.String())
+}
+
+func TestCreateTransaction_IdenticalReplay(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Mocks
+	mockTransactionService := mock_services.NewMockTransactionServiceI(ctrl)
+
+	// Controller
+	transactionController := controllers.MakeTransactionController(mockTransactionService)
+
+	jsonRequest := []byte(`{"amount": 100, "type": "purchase"}`)
+
+	// Mock expectations
+	storedTransaction := &models.Transaction{Id: 1, Amount: decimal.NewFromInt(100), Type: "purchase", Currency: "USD"}
+	mockTransactionService.EXPECT().CreateTransaction(gomock.Any(), gomock.Any()).Return(storedTransaction, false, nil)
+
+	req, _ := http.NewRequest("PUT", "/transactionservice/transaction/1", bytes.NewBuffer(jsonRequest))
+	recorder := httptest.NewRecorder()
+	router := httprouter.New()
+	router.Handle(http.MethodPut, "/transactionservice/transaction/:transaction_id", transactionController.CreateTransaction)
+	router.ServeHTTP(recorder, req)
+
+	// Assert status code is OK with the stored transaction
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	expectedResponse := `{"status":"ok","transaction":{"id":1,"amount":100,"type":"purchase","parent_id":null,"currency":"USD"}}`
+	assert.Equal(t, expectedResponse, recorder.Body.String())
+}
+
+func TestCreateTransaction_ConflictingReplay(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Mocks
+	mockTransactionService := mock_services.NewMockTransactionServiceI(ctrl)
+
+	// Controller
+	transactionController := controllers.MakeTransactionController(mockTransactionService)
+
+	jsonRequest := []byte(`{"amount": 200, "type": "purchase"}`)
+
+	// Mock expectations
+	conflictErr := &services.TransactionConflictError{Diff: map[string]services.FieldDiff{
+		"amount": {Stored: decimal.NewFromInt(100), Requested: decimal.NewFromInt(200)},
+	}}
+	mockTransactionService.EXPECT().CreateTransaction(gomock.Any(), gomock.Any()).Return(nil, false, conflictErr)
+
+	req, _ := http.NewRequest("PUT", "/transactionservice/transaction/1", bytes.NewBuffer(jsonRequest))
+	recorder := httptest.NewRecorder()
+	router := httprouter.New()
+	router.Handle(http.MethodPut, "/transactionservice/transaction/:transaction_id", transactionController.CreateTransaction)
+	router.ServeHTTP(recorder, req)
+
+	// Assert status code is Conflict with the differing fields
+	assert.Equal(t, http.StatusConflict, recorder.Code)
+	expectedResponse := `{"code":"transaction_conflict","detail":"transaction with the same ID already exists with different values","diff":{"amount":{"stored":100,"requested":200}},"instance":"/transactionservice/transaction/1","status":409,"title":"Conflict","type":"about:blank"}`
 	assert.Equal(t, expectedResponse, recorder.Body.String())
 }
 
@@ -366,7 +597,7 @@ func TestCreateTransaction_InternalServerError(t *testing.T) {
 	}
 
 	// Mock expectations
-	mockTransactionService.EXPECT().CreateTransaction(gomock.Any()).Return(false, errors.New("some internal error"))
+	mockTransactionService.EXPECT().CreateTransaction(gomock.Any(), gomock.Any()).Return(nil, false, errors.New("some internal error"))
 
 	req, _ := http.NewRequest("PUT", fmt.Sprintf("/transactionservice/transaction/%d", transactionID), bytes.NewBuffer(jsonRequest))
 	recorder := httptest.NewRecorder()
@@ -378,6 +609,1235 @@ func TestCreateTransaction_InternalServerError(t *testing.T) {
 	assert.Equal(t, http.StatusInternalServerError, recorder.Code)
 
 	// Assert response body contains expected error message
-	expectedResponse := `{"error":"Error creating transaction: some internal error","status":500,"success":"false"}`
+	expectedResponse := `{"code":"internal_error","detail":"internal server error","instance":"/transactionservice/transaction/1","status":500,"title":"Internal Server Error","type":"about:blank"}`
+	assert.Equal(t, expectedResponse, recorder.Body.String())
+}
+
+func TestCreateTransaction_PreservesDecimalAmount(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Mocks
+	mockTransactionService := mock_services.NewMockTransactionServiceI(ctrl)
+
+	// Controller
+	transactionController := controllers.MakeTransactionController(mockTransactionService)
+
+	// Request body with an amount that float64 cannot represent exactly
+	jsonRequest := []byte(`{"amount": 12345678901234567.89, "type": "purchase", "currency": "eur"}`)
+
+	// Mock expectations
+	var created models.Transaction
+	mockTransactionService.EXPECT().CreateTransaction(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, transaction models.Transaction) (*models.Transaction, bool, error) {
+		created = transaction
+		return &transaction, true, nil
+	})
+
+	req, _ := http.NewRequest("PUT", "/transactionservice/transaction/1", bytes.NewBuffer(jsonRequest))
+	recorder := httptest.NewRecorder()
+	router := httprouter.New()
+	router.Handle(http.MethodPut, "/transactionservice/transaction/:transaction_id", transactionController.CreateTransaction)
+	router.ServeHTTP(recorder, req)
+
+	// Assert the amount reached the service without rounding
+	assert.Equal(t, http.StatusCreated, recorder.Code)
+	assert.Equal(t, "12345678901234567.89", created.Amount.String())
+	assert.Equal(t, "EUR", created.Currency)
+}
+
+func TestGetTransitiveSum_Success(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Mocks
+	mockTransactionService := mock_services.NewMockTransactionServiceI(ctrl)
+
+	// Controller
+	transactionController := controllers.MakeTransactionController(mockTransactionService)
+
+	// Mock expectations
+	sum, _ := decimal.NewFromString("0.3")
+	mockTransactionService.EXPECT().GetTransitiveSum(gomock.Any(), uint(1), "").Return(&services.TransitiveSum{
+		Sum:       &sum,
+		Currency:  "USD",
+		Subtotals: map[string]decimal.Decimal{"USD": sum},
+	}, nil)
+
+	req, _ := http.NewRequest("GET", "/transactionservice/sum/1", nil)
+	recorder := httptest.NewRecorder()
+	router := httprouter.New()
+	router.Handle(http.MethodGet, "/transactionservice/sum/:transaction_id", transactionController.GetTransitiveSum)
+	router.ServeHTTP(recorder, req)
+
+	// Assert the sum is rendered exactly as a JSON number
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, `{"sum":0.3,"currency":"USD","subtotals":{"USD":0.3}}`, recorder.Body.String())
+}
+
+func TestGetTransitiveSum_ConversionUnavailable(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Mocks
+	mockTransactionService := mock_services.NewMockTransactionServiceI(ctrl)
+
+	// Controller
+	transactionController := controllers.MakeTransactionController(mockTransactionService)
+
+	// Mock expectations
+	mockTransactionService.EXPECT().GetTransitiveSum(gomock.Any(), uint(1), "EUR").Return(nil, services.ErrCurrencyConversionUnavailable)
+
+	req, _ := http.NewRequest("GET", "/transactionservice/sum/1?currency=eur", nil)
+	recorder := httptest.NewRecorder()
+	router := httprouter.New()
+	router.Handle(http.MethodGet, "/transactionservice/sum/:transaction_id", transactionController.GetTransitiveSum)
+	router.ServeHTTP(recorder, req)
+
+	// Assert status code is UnprocessableEntity
+	assert.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+
+	// Assert response body contains expected error message
+	expectedResponse := `{"code":"conversion_unavailable","detail":"currency conversion is not configured","instance":"/transactionservice/sum/1","status":422,"title":"Unprocessable Entity","type":"about:blank"}`
+	assert.Equal(t, expectedResponse, recorder.Body.String())
+}
+
+func TestCreateTransaction_InvalidCurrency(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Mocks
+	mockTransactionService := mock_services.NewMockTransactionServiceI(ctrl)
+
+	// Controller
+	transactionController := controllers.MakeTransactionController(mockTransactionService)
+
+	// Request body with a currency that is not an ISO 4217 code
+	requestBody := map[string]interface{}{
+		"amount":   100.0,
+		"type":     "purchase",
+		"currency": "DOLLARS",
+	}
+
+	// Convert request body to JSON
+	jsonRequest, err := json.Marshal(requestBody)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, _ := http.NewRequest("PUT", "/transactionservice/transaction/1", bytes.NewBuffer(jsonRequest))
+	recorder := httptest.NewRecorder()
+	router := httprouter.New()
+	router.Handle(http.MethodPut, "/transactionservice/transaction/:transaction_id", transactionController.CreateTransaction)
+	router.ServeHTTP(recorder, req)
+
+	// Assert status code is BadRequest
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+
+	// Assert response body contains expected error message
+	expectedResponse := `{"code":"validation_failed","detail":"currency must be an ISO 4217 currency code","errors":[{"field":"currency","rule":"currency","message":"must be an ISO 4217 currency code"}],"instance":"/transactionservice/transaction/1","status":400,"title":"Bad Request","type":"about:blank"}`
+	assert.Equal(t, expectedResponse, recorder.Body.String())
+}
+
+func TestUpdateTransaction_Success(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Mocks
+	mockTransactionService := mock_services.NewMockTransactionServiceI(ctrl)
+
+	// Controller
+	transactionController := controllers.MakeTransactionController(mockTransactionService)
+
+	jsonRequest := []byte(`{"amount": 250.75, "parent_id": null}`)
+
+	// Mock expectations
+	updatedTransaction := &models.Transaction{Id: 3, Amount: decimal.RequireFromString("250.75"), Type: "purchase", Currency: "USD"}
+	mockTransactionService.EXPECT().UpdateTransaction(gomock.Any(), uint(3), gomock.Any()).DoAndReturn(func(ctx context.Context, transactionID uint, update models.TransactionUpdate) (*models.Transaction, error) {
+		assert.Equal(t, "250.75", update.Amount.String())
+		assert.Nil(t, update.Type)
+		assert.True(t, update.SetParentID)
+		assert.Nil(t, update.ParentID)
+		return updatedTransaction, nil
+	})
+
+	req, _ := http.NewRequest("PATCH", "/transactionservice/transaction/3", bytes.NewBuffer(jsonRequest))
+	recorder := httptest.NewRecorder()
+	router := httprouter.New()
+	router.Handle(http.MethodPatch, "/transactionservice/transaction/:transaction_id", transactionController.UpdateTransaction)
+	router.ServeHTTP(recorder, req)
+
+	// Assert status code is OK with the updated transaction
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	expectedResponse := `{"status":"ok","transaction":{"id":3,"amount":250.75,"type":"purchase","parent_id":null,"currency":"USD"}}`
+	assert.Equal(t, expectedResponse, recorder.Body.String())
+}
+
+func TestUpdateTransaction_EmptyBody(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Mocks
+	mockTransactionService := mock_services.NewMockTransactionServiceI(ctrl)
+
+	// Controller
+	transactionController := controllers.MakeTransactionController(mockTransactionService)
+
+	req, _ := http.NewRequest("PATCH", "/transactionservice/transaction/3", bytes.NewBufferString(`{}`))
+	recorder := httptest.NewRecorder()
+	router := httprouter.New()
+	router.Handle(http.MethodPatch, "/transactionservice/transaction/:transaction_id", transactionController.UpdateTransaction)
+	router.ServeHTTP(recorder, req)
+
+	// Assert status code is BadRequest
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+
+	// Assert response body contains expected error message
+	expectedResponse := `{"code":"invalid_request","detail":"At least one of 'amount', 'type', 'currency', 'parent_id' or 'occurred_at' is required","instance":"/transactionservice/transaction/3","status":400,"title":"Bad Request","type":"about:blank"}`
+	assert.Equal(t, expectedResponse, recorder.Body.String())
+}
+
+func TestUpdateTransaction_NullAmount(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Mocks
+	mockTransactionService := mock_services.NewMockTransactionServiceI(ctrl)
+
+	// Controller
+	transactionController := controllers.MakeTransactionController(mockTransactionService)
+
+	req, _ := http.NewRequest("PATCH", "/transactionservice/transaction/3", bytes.NewBufferString(`{"amount": null, "type": " "}`))
+	recorder := httptest.NewRecorder()
+	router := httprouter.New()
+	router.Handle(http.MethodPatch, "/transactionservice/transaction/:transaction_id", transactionController.UpdateTransaction)
+	router.ServeHTTP(recorder, req)
+
+	// Assert status code is BadRequest
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+
+	// Assert only parent_id may be set to null, and the type is still validated
+	expectedResponse := `{"code":"validation_failed","detail":"amount must not be null; type must not be blank",` +
+		`"errors":[{"field":"amount","rule":"notnull","message":"must not be null"},{"field":"type","rule":"notblank","message":"must not be blank"}],` +
+		`"instance":"/transactionservice/transaction/3","status":400,"title":"Bad Request","type":"about:blank"}`
 	assert.Equal(t, expectedResponse, recorder.Body.String())
 }
+
+func TestUpdateTransaction_TransactionNotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Mocks
+	mockTransactionService := mock_services.NewMockTransactionServiceI(ctrl)
+
+	// Controller
+	transactionController := controllers.MakeTransactionController(mockTransactionService)
+
+	// Mock expectations
+	mockTransactionService.EXPECT().UpdateTransaction(gomock.Any(), uint(3), gomock.Any()).Return(nil, services.ErrTransactionNotFound)
+
+	req, _ := http.NewRequest("PATCH", "/transactionservice/transaction/3", bytes.NewBufferString(`{"type": "refund"}`))
+	recorder := httptest.NewRecorder()
+	router := httprouter.New()
+	router.Handle(http.MethodPatch, "/transactionservice/transaction/:transaction_id", transactionController.UpdateTransaction)
+	router.ServeHTTP(recorder, req)
+
+	// Assert status code is NotFound
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+
+	// Assert response body contains expected error message
+	expectedResponse := `{"code":"transaction_not_found","detail":"transaction does not exist for given transaction ID","instance":"/transactionservice/transaction/3","status":404,"title":"Not Found","type":"about:blank"}`
+	assert.Equal(t, expectedResponse, recorder.Body.String())
+}
+
+func TestDeleteTransaction_Success(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Mocks
+	mockTransactionService := mock_services.NewMockTransactionServiceI(ctrl)
+
+	// Controller
+	transactionController := controllers.MakeTransactionController(mockTransactionService)
+
+	// Mock expectations
+	mockTransactionService.EXPECT().DeleteTransaction(gomock.Any(), uint(3), repositories.DeleteModeReparent).Return(nil)
+
+	req, _ := http.NewRequest("DELETE", "/transactionservice/transaction/3?on_children=reparent", nil)
+	recorder := httptest.NewRecorder()
+	router := httprouter.New()
+	router.Handle(http.MethodDelete, "/transactionservice/transaction/:transaction_id", transactionController.DeleteTransaction)
+	router.ServeHTTP(recorder, req)
+
+	// Assert status code is OK
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, `{"status":"ok"}`, recorder.Body.String())
+}
+
+func TestDeleteTransaction_HasChildren(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Mocks
+	mockTransactionService := mock_services.NewMockTransactionServiceI(ctrl)
+
+	// Controller
+	transactionController := controllers.MakeTransactionController(mockTransactionService)
+
+	// Mock expectations
+	mockTransactionService.EXPECT().DeleteTransaction(gomock.Any(), uint(3), repositories.DeleteModeReject).Return(repositories.ErrTransactionHasChildren)
+
+	req, _ := http.NewRequest("DELETE", "/transactionservice/transaction/3", nil)
+	recorder := httptest.NewRecorder()
+	router := httprouter.New()
+	router.Handle(http.MethodDelete, "/transactionservice/transaction/:transaction_id", transactionController.DeleteTransaction)
+	router.ServeHTTP(recorder, req)
+
+	// Assert status code is Conflict
+	assert.Equal(t, http.StatusConflict, recorder.Code)
+
+	// Assert response body contains expected error message
+	expectedResponse := `{"code":"transaction_has_children","detail":"transaction has child transactions, use on_children=cascade or on_children=reparent","instance":"/transactionservice/transaction/3","status":409,"title":"Conflict","type":"about:blank"}`
+	assert.Equal(t, expectedResponse, recorder.Body.String())
+}
+
+func TestDeleteTransaction_InvalidMode(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Mocks
+	mockTransactionService := mock_services.NewMockTransactionServiceI(ctrl)
+
+	// Controller
+	transactionController := controllers.MakeTransactionController(mockTransactionService)
+
+	req, _ := http.NewRequest("DELETE", "/transactionservice/transaction/3?on_children=orphan", nil)
+	recorder := httptest.NewRecorder()
+	router := httprouter.New()
+	router.Handle(http.MethodDelete, "/transactionservice/transaction/:transaction_id", transactionController.DeleteTransaction)
+	router.ServeHTTP(recorder, req)
+
+	// Assert status code is BadRequest
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+
+	// Assert response body contains expected error message
+	expectedResponse := `{"code":"invalid_request","detail":"Invalid on_children value, expected one of 'reject', 'cascade' or 'reparent'","instance":"/transactionservice/transaction/3","status":400,"title":"Bad Request","type":"about:blank"}`
+	assert.Equal(t, expectedResponse, recorder.Body.String())
+}
+
+func TestGetTransaction_Success(t *testing.T) {
+	t.Run("without optional details", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		// Mocks
+		mockTransactionService := mock_services.NewMockTransactionServiceI(ctrl)
+
+		// Controller
+		transactionController := controllers.MakeTransactionController(mockTransactionService)
+
+		// Mock expectations
+		parentID := uint(1)
+		transaction := &models.Transaction{Id: 3, Amount: decimal.NewFromInt(100), Type: "purchase", ParentID: &parentID, Currency: "USD"}
+		mockTransactionService.EXPECT().GetTransaction(gomock.Any(), uint(3), services.GetTransactionOptions{}).Return(&services.TransactionDetails{Transaction: transaction}, nil)
+
+		req, _ := http.NewRequest("GET", "/transactionservice/transaction/3", nil)
+		recorder := httptest.NewRecorder()
+		router := httprouter.New()
+		router.Handle(http.MethodGet, "/transactionservice/transaction/:transaction_id", transactionController.GetTransaction)
+		router.ServeHTTP(recorder, req)
+
+		// Assert status code is OK with the transaction
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		expectedResponse := `{"id":3,"amount":100,"type":"purchase","parent_id":1,"currency":"USD"}`
+		assert.Equal(t, expectedResponse, recorder.Body.String())
+	})
+
+	t.Run("with children and depth", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		// Mocks
+		mockTransactionService := mock_services.NewMockTransactionServiceI(ctrl)
+
+		// Controller
+		transactionController := controllers.MakeTransactionController(mockTransactionService)
+
+		// Mock expectations
+		childIDs := []uint{}
+		depth := 0
+		transaction := &models.Transaction{Id: 3, Amount: decimal.NewFromInt(100), Type: "purchase", Currency: "USD"}
+		opts := services.GetTransactionOptions{IncludeChildren: true, IncludeDepth: true}
+		mockTransactionService.EXPECT().GetTransaction(gomock.Any(), uint(3), opts).Return(&services.TransactionDetails{Transaction: transaction, ChildIDs: &childIDs, Depth: &depth}, nil)
+
+		req, _ := http.NewRequest("GET", "/transactionservice/transaction/3?include=children,depth", nil)
+		recorder := httptest.NewRecorder()
+		router := httprouter.New()
+		router.Handle(http.MethodGet, "/transactionservice/transaction/:transaction_id", transactionController.GetTransaction)
+		router.ServeHTTP(recorder, req)
+
+		// Assert status code is OK with the requested details
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		expectedResponse := `{"id":3,"amount":100,"type":"purchase","parent_id":null,"currency":"USD","children":[],"depth":0}`
+		assert.Equal(t, expectedResponse, recorder.Body.String())
+	})
+}
+
+func TestGetTransaction_TransactionNotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Mocks
+	mockTransactionService := mock_services.NewMockTransactionServiceI(ctrl)
+
+	// Controller
+	transactionController := controllers.MakeTransactionController(mockTransactionService)
+
+	// Mock expectations
+	mockTransactionService.EXPECT().GetTransaction(gomock.Any(), uint(3), gomock.Any()).Return(nil, services.ErrTransactionNotFound)
+
+	req, _ := http.NewRequest("GET", "/transactionservice/transaction/3", nil)
+	recorder := httptest.NewRecorder()
+	router := httprouter.New()
+	router.Handle(http.MethodGet, "/transactionservice/transaction/:transaction_id", transactionController.GetTransaction)
+	router.ServeHTTP(recorder, req)
+
+	// Assert status code is NotFound
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+
+	// Assert response body contains expected error message
+	expectedResponse := `{"code":"transaction_not_found","detail":"transaction does not exist for given transaction ID","instance":"/transactionservice/transaction/3","status":404,"title":"Not Found","type":"about:blank"}`
+	assert.Equal(t, expectedResponse, recorder.Body.String())
+}
+
+func TestGetTransactionsByType_Success(t *testing.T) {
+	t.Run("first page", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		// Mocks
+		mockTransactionService := mock_services.NewMockTransactionServiceI(ctrl)
+
+		// Controller
+		transactionController := controllers.MakeTransactionController(mockTransactionService)
+
+		// Mock expectations
+		nextAfterID := uint(7)
+		minAmount := decimal.RequireFromString("10.5")
+		expectedQuery := repositories.TypeQuery{Type: "purchase", Limit: 2, Descending: true, MinAmount: &minAmount}
+		mockTransactionService.EXPECT().GetTransactionIDsByType(gomock.Any(), expectedQuery).Return(&services.TransactionIDPage{
+			TransactionIDs: []uint{9, 7},
+			NextAfterID:    &nextAfterID,
+		}, nil)
+
+		req, _ := http.NewRequest("GET", "/transactionservice/types/purchase?limit=2&order=desc&min_amount=10.5", nil)
+		recorder := httptest.NewRecorder()
+		router := httprouter.New()
+		router.Handle(http.MethodGet, "/transactionservice/types/:type", transactionController.GetTransactionsByType)
+		router.ServeHTTP(recorder, req)
+
+		// Assert status code is OK with a cursor for the next page
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, `{"next_cursor":"Ny5kMWVjMTg0YzMxYWM1MjA3","transaction_ids":[9,7]}`, recorder.Body.String())
+	})
+
+	t.Run("following a cursor to the last page", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		// Mocks
+		mockTransactionService := mock_services.NewMockTransactionServiceI(ctrl)
+
+		// Controller
+		transactionController := controllers.MakeTransactionController(mockTransactionService)
+
+		// Mock expectations
+		afterID := uint(7)
+		minAmount := decimal.RequireFromString("10.50")
+		expectedQuery := repositories.TypeQuery{Type: "purchase", AfterID: &afterID, Descending: true, MinAmount: &minAmount}
+		mockTransactionService.EXPECT().GetTransactionIDsByType(gomock.Any(), expectedQuery).Return(&services.TransactionIDPage{
+			TransactionIDs: []uint{8},
+		}, nil)
+
+		// The cursor of the first page, with the same filters written differently
+		req, _ := http.NewRequest("GET", "/transactionservice/types/purchase?order=desc&min_amount=10.50&cursor=Ny5kMWVjMTg0YzMxYWM1MjA3", nil)
+		recorder := httptest.NewRecorder()
+		router := httprouter.New()
+		router.Handle(http.MethodGet, "/transactionservice/types/:type", transactionController.GetTransactionsByType)
+		router.ServeHTTP(recorder, req)
+
+		// Assert status code is OK without a next cursor
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, `{"next_cursor":null,"transaction_ids":[8]}`, recorder.Body.String())
+	})
+}
+
+func TestGetTransactionsByType_InvalidQuery(t *testing.T) {
+	testCases := map[string]string{
+		"limit=0":          `{"code":"invalid_request","detail":"Invalid limit, expected an integer between 1 and 1000","instance":"/transactionservice/types/purchase","status":400,"title":"Bad Request","type":"about:blank"}`,
+		"cursor=***":       `{"code":"invalid_request","detail":"Invalid cursor","instance":"/transactionservice/types/purchase","status":400,"title":"Bad Request","type":"about:blank"}`,
+		"cursor=Nw":        `{"code":"invalid_request","detail":"Invalid cursor","instance":"/transactionservice/types/purchase","status":400,"title":"Bad Request","type":"about:blank"}`,
+		"order=sideways":   `{"code":"invalid_request","detail":"Invalid order, expected 'asc' or 'desc'","instance":"/transactionservice/types/purchase","status":400,"title":"Bad Request","type":"about:blank"}`,
+		"max_amount=lots":  `{"code":"invalid_request","detail":"Invalid max_amount format","instance":"/transactionservice/types/purchase","status":400,"title":"Bad Request","type":"about:blank"}`,
+		"parent_id=parent": `{"code":"invalid_request","detail":"Invalid parent_id format","instance":"/transactionservice/types/purchase","status":400,"title":"Bad Request","type":"about:blank"}`,
+		// The cursor of a page of ?order=desc&min_amount=10.5, followed with other filters
+		"min_amount=10.5&cursor=Ny5kMWVjMTg0YzMxYWM1MjA3":                        `{"code":"invalid_request","detail":"Cursor does not match the order and filters of the query","instance":"/transactionservice/types/purchase","status":400,"title":"Bad Request","type":"about:blank"}`,
+		"order=desc&min_amount=11&cursor=Ny5kMWVjMTg0YzMxYWM1MjA3":               `{"code":"invalid_request","detail":"Cursor does not match the order and filters of the query","instance":"/transactionservice/types/purchase","status":400,"title":"Bad Request","type":"about:blank"}`,
+		"order=desc&min_amount=10.5&parent_id=1&cursor=Ny5kMWVjMTg0YzMxYWM1MjA3": `{"code":"invalid_request","detail":"Cursor does not match the order and filters of the query","instance":"/transactionservice/types/purchase","status":400,"title":"Bad Request","type":"about:blank"}`,
+	}
+
+	for rawQuery, expectedResponse := range testCases {
+		t.Run(rawQuery, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			// Mocks
+			mockTransactionService := mock_services.NewMockTransactionServiceI(ctrl)
+
+			// Controller
+			transactionController := controllers.MakeTransactionController(mockTransactionService)
+
+			req, _ := http.NewRequest("GET", "/transactionservice/types/purchase?"+rawQuery, nil)
+			recorder := httptest.NewRecorder()
+			router := httprouter.New()
+			router.Handle(http.MethodGet, "/transactionservice/types/:type", transactionController.GetTransactionsByType)
+			router.ServeHTTP(recorder, req)
+
+			// Assert status code is BadRequest
+			assert.Equal(t, http.StatusBadRequest, recorder.Code)
+			assert.Equal(t, expectedResponse, recorder.Body.String())
+		})
+	}
+}
+
+func TestGetTransactionTree_Success(t *testing.T) {
+	parentID := uint(1)
+	nodes := []models.TransactionNode{
+		{Transaction: models.Transaction{Id: 1, Amount: decimal.NewFromInt(10), Type: "purchase", Currency: "USD"}, Depth: 0, Path: []uint{1}},
+		{Transaction: models.Transaction{Id: 2, Amount: decimal.NewFromInt(5), Type: "fee", ParentID: &parentID, Currency: "USD"}, Depth: 1, Path: []uint{1, 2}},
+	}
+
+	testCases := map[string]string{
+		"nested": `{"tree":{"id":1,"amount":10,"type":"purchase","parent_id":null,"currency":"USD","depth":0,"children":[` +
+			`{"id":2,"amount":5,"type":"fee","parent_id":1,"currency":"USD","depth":1,"children":[]}]}}`,
+		"flat": `{"transactions":[{"id":1,"amount":10,"type":"purchase","parent_id":null,"currency":"USD","depth":0,"path":[1]},` +
+			`{"id":2,"amount":5,"type":"fee","parent_id":1,"currency":"USD","depth":1,"path":[1,2]}]}`,
+	}
+
+	for format, expectedResponse := range testCases {
+		t.Run(format, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			// Mocks
+			mockTransactionService := mock_services.NewMockTransactionServiceI(ctrl)
+
+			// Controller
+			transactionController := controllers.MakeTransactionController(mockTransactionService)
+
+			// Mock expectations
+			mockTransactionService.EXPECT().GetTransactionTree(gomock.Any(), uint(1), services.TreeOptions{MaxDepth: 2}).Return(nodes, nil)
+
+			req, _ := http.NewRequest("GET", "/transactionservice/tree/1?max_depth=2&format="+format, nil)
+			recorder := httptest.NewRecorder()
+			router := httprouter.New()
+			router.Handle(http.MethodGet, "/transactionservice/tree/:transaction_id", transactionController.GetTransactionTree)
+			router.ServeHTTP(recorder, req)
+
+			// Assert status code is OK with the tree in the requested format
+			assert.Equal(t, http.StatusOK, recorder.Code)
+			assert.Equal(t, expectedResponse, recorder.Body.String())
+		})
+	}
+}
+
+func TestGetTransactionTree_InvalidMaxDepth(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Mocks
+	mockTransactionService := mock_services.NewMockTransactionServiceI(ctrl)
+
+	// Controller
+	transactionController := controllers.MakeTransactionController(mockTransactionService)
+
+	req, _ := http.NewRequest("GET", "/transactionservice/tree/1?max_depth=-1", nil)
+	recorder := httptest.NewRecorder()
+	router := httprouter.New()
+	router.Handle(http.MethodGet, "/transactionservice/tree/:transaction_id", transactionController.GetTransactionTree)
+	router.ServeHTTP(recorder, req)
+
+	// Assert status code is BadRequest
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+
+	// Assert response body contains expected error message
+	expectedResponse := `{"code":"invalid_request","detail":"Invalid max_depth, expected a positive integer","instance":"/transactionservice/tree/1","status":400,"title":"Bad Request","type":"about:blank"}`
+	assert.Equal(t, expectedResponse, recorder.Body.String())
+}
+
+func TestGetAncestors_Success(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Mocks
+	mockTransactionService := mock_services.NewMockTransactionServiceI(ctrl)
+
+	// Controller
+	transactionController := controllers.MakeTransactionController(mockTransactionService)
+
+	// Mock expectations
+	mockTransactionService.EXPECT().GetAncestors(gomock.Any(), uint(2)).Return(&services.AncestorChain{
+		TransactionID: 2,
+		RootID:        1,
+		Ancestors:     []models.Transaction{{Id: 1, Amount: decimal.NewFromInt(10), Type: "purchase", Currency: "USD"}},
+	}, nil)
+
+	req, _ := http.NewRequest("GET", "/transactionservice/ancestors/2", nil)
+	recorder := httptest.NewRecorder()
+	router := httprouter.New()
+	router.Handle(http.MethodGet, "/transactionservice/ancestors/:transaction_id", transactionController.GetAncestors)
+	router.ServeHTTP(recorder, req)
+
+	// Assert status code is OK with the ancestor chain
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	expectedResponse := `{"transaction_id":2,"root_id":1,"ancestors":[{"id":1,"amount":10,"type":"purchase","parent_id":null,"currency":"USD"}]}`
+	assert.Equal(t, expectedResponse, recorder.Body.String())
+}
+
+func TestGetAncestors_TransactionNotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Mocks
+	mockTransactionService := mock_services.NewMockTransactionServiceI(ctrl)
+
+	// Controller
+	transactionController := controllers.MakeTransactionController(mockTransactionService)
+
+	// Mock expectations
+	mockTransactionService.EXPECT().GetAncestors(gomock.Any(), uint(2)).Return(nil, services.ErrTransactionNotFound)
+
+	req, _ := http.NewRequest("GET", "/transactionservice/ancestors/2", nil)
+	recorder := httptest.NewRecorder()
+	router := httprouter.New()
+	router.Handle(http.MethodGet, "/transactionservice/ancestors/:transaction_id", transactionController.GetAncestors)
+	router.ServeHTTP(recorder, req)
+
+	// Assert status code is NotFound
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+
+	// Assert response body contains expected error message
+	expectedResponse := `{"code":"transaction_not_found","detail":"transaction does not exist for given transaction ID","instance":"/transactionservice/ancestors/2","status":404,"title":"Not Found","type":"about:blank"}`
+	assert.Equal(t, expectedResponse, recorder.Body.String())
+}
+
+func TestCreateTransaction_MaxDepthExceeded(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Mocks
+	mockTransactionService := mock_services.NewMockTransactionServiceI(ctrl)
+
+	// Controller
+	transactionController := controllers.MakeTransactionController(mockTransactionService)
+
+	jsonRequest := []byte(`{"amount": 100, "type": "purchase", "parent_id": 2}`)
+
+	// Mock expectations
+	mockTransactionService.EXPECT().CreateTransaction(gomock.Any(), gomock.Any()).Return(nil, false, repositories.ErrMaxDepthExceeded)
+
+	req, _ := http.NewRequest("PUT", "/transactionservice/transaction/1", bytes.NewBuffer(jsonRequest))
+	recorder := httptest.NewRecorder()
+	router := httprouter.New()
+	router.Handle(http.MethodPut, "/transactionservice/transaction/:transaction_id", transactionController.CreateTransaction)
+	router.ServeHTTP(recorder, req)
+
+	// Assert status code is UnprocessableEntity
+	assert.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+
+	// Assert response body contains expected error message
+	expectedResponse := `{"code":"max_depth_exceeded","detail":"transaction hierarchy exceeds the maximum depth","instance":"/transactionservice/transaction/1","status":422,"title":"Unprocessable Entity","type":"about:blank"}`
+	assert.Equal(t, expectedResponse, recorder.Body.String())
+}
+
+func TestGetTransitiveSum_CycleDetected(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Mocks
+	mockTransactionService := mock_services.NewMockTransactionServiceI(ctrl)
+
+	// Controller
+	transactionController := controllers.MakeTransactionController(mockTransactionService)
+
+	// Mock expectations
+	mockTransactionService.EXPECT().GetTransitiveSum(gomock.Any(), uint(1), "").Return(nil, repositories.ErrCycleDetected)
+
+	req, _ := http.NewRequest("GET", "/transactionservice/sum/1", nil)
+	recorder := httptest.NewRecorder()
+	router := httprouter.New()
+	router.Handle(http.MethodGet, "/transactionservice/sum/:transaction_id", transactionController.GetTransitiveSum)
+	router.ServeHTTP(recorder, req)
+
+	// Assert status code is UnprocessableEntity
+	assert.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+
+	// Assert response body contains expected error message
+	expectedResponse := `{"code":"cycle_detected","detail":"transaction hierarchy contains a cycle","instance":"/transactionservice/sum/1","status":422,"title":"Unprocessable Entity","type":"about:blank"}`
+	assert.Equal(t, expectedResponse, recorder.Body.String())
+}
+
+func TestGetTransitiveSum_Timeout(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Mocks
+	mockTransactionService := mock_services.NewMockTransactionServiceI(ctrl)
+
+	// Controller
+	transactionController := controllers.MakeTransactionController(mockTransactionService)
+
+	// Mock expectations: the query is aborted once the request deadline passes
+	mockTransactionService.EXPECT().GetTransitiveSum(gomock.Any(), uint(1), "").DoAndReturn(func(ctx context.Context, transactionID uint, targetCurrency string) (*services.TransitiveSum, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", "/transactionservice/sum/1", nil)
+	recorder := httptest.NewRecorder()
+	router := httprouter.New()
+	router.Handle(http.MethodGet, "/transactionservice/sum/:transaction_id", transactionController.GetTransitiveSum)
+	router.ServeHTTP(recorder, req)
+
+	// Assert status code is GatewayTimeout
+	assert.Equal(t, http.StatusGatewayTimeout, recorder.Code)
+
+	// Assert response body contains expected error message
+	expectedResponse := `{"code":"request_timeout","detail":"request timed out","instance":"/transactionservice/sum/1","status":504,"title":"Gateway Timeout","type":"about:blank"}`
+	assert.Equal(t, expectedResponse, recorder.Body.String())
+}
+
+func TestCreateTransactions_Success(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Mocks
+	mockTransactionService := mock_services.NewMockTransactionServiceI(ctrl)
+
+	// Controller
+	transactionController := controllers.MakeTransactionController(mockTransactionService)
+
+	jsonRequest := []byte(`{"transactions": [{"id": 1, "amount": 100, "type": "purchase"}, {"id": 2, "amount": 50, "type": "purchase", "parent_id": 1}]}`)
+
+	// Mock expectations
+	parentID := uint(1)
+	expectedTransactions := []models.Transaction{
+		{Id: 1, Amount: decimal.NewFromInt(100), Type: "purchase"},
+		{Id: 2, Amount: decimal.NewFromInt(50), Type: "purchase", ParentID: &parentID},
+	}
+	mockTransactionService.EXPECT().CreateTransactions(gomock.Any(), expectedTransactions, services.BatchModeAtomic).Return(&services.BatchResult{
+		Committed: true,
+		Results: []services.BatchItemResult{
+			{Index: 0, ID: 1, Status: services.BatchItemCreated},
+			{Index: 1, ID: 2, Status: services.BatchItemCreated},
+		},
+	}, nil)
+
+	req, _ := http.NewRequest("POST", "/transactionservice/transactions/batch", bytes.NewBuffer(jsonRequest))
+	recorder := httptest.NewRecorder()
+	router := httprouter.New()
+	router.Handle(http.MethodPost, "/transactionservice/transactions/batch", transactionController.CreateTransactions)
+	router.ServeHTTP(recorder, req)
+
+	// Assert status code is Created
+	assert.Equal(t, http.StatusCreated, recorder.Code)
+
+	// Assert response body reports every transaction as created
+	expectedResponse := `{"committed":true,"mode":"atomic","results":[{"index":0,"id":1,"status":"created"},{"index":1,"id":2,"status":"created"}]}`
+	assert.Equal(t, expectedResponse, recorder.Body.String())
+}
+
+func TestCreateTransactions_AtomicFailure(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Mocks
+	mockTransactionService := mock_services.NewMockTransactionServiceI(ctrl)
+
+	// Controller
+	transactionController := controllers.MakeTransactionController(mockTransactionService)
+
+	jsonRequest := []byte(`{"mode": "atomic", "transactions": [{"id": 1, "amount": 100, "type": "purchase"}, {"id": 2, "amount": 50, "type": "purchase", "parent_id": 99}]}`)
+
+	// Mock expectations
+	mockTransactionService.EXPECT().CreateTransactions(gomock.Any(), gomock.Any(), services.BatchModeAtomic).Return(&services.BatchResult{
+		Committed: false,
+		Results: []services.BatchItemResult{
+			{Index: 0, ID: 1, Status: services.BatchItemRolledBack},
+			{Index: 1, ID: 2, Status: services.BatchItemFailed, Err: services.ErrParentTransactionNotFound},
+		},
+	}, nil)
+
+	req, _ := http.NewRequest("POST", "/transactionservice/transactions/batch", bytes.NewBuffer(jsonRequest))
+	recorder := httptest.NewRecorder()
+	router := httprouter.New()
+	router.Handle(http.MethodPost, "/transactionservice/transactions/batch", transactionController.CreateTransactions)
+	router.ServeHTTP(recorder, req)
+
+	// Assert status code is UnprocessableEntity
+	assert.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+
+	// Assert response body reports the failing transaction
+	expectedResponse := `{"committed":false,"mode":"atomic","results":[{"index":0,"id":1,"status":"rolled_back"},{"index":1,"id":2,"status":"failed","code":"parent_not_found","error":"parent transaction does not exist"}]}`
+	assert.Equal(t, expectedResponse, recorder.Body.String())
+}
+
+func TestCreateTransactions_InvalidItem(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Mocks
+	mockTransactionService := mock_services.NewMockTransactionServiceI(ctrl)
+
+	// Controller
+	transactionController := controllers.MakeTransactionController(mockTransactionService)
+
+	jsonRequest := []byte(`{"mode": "best_effort", "transactions": [{"id": 1, "amount": 100, "type": "purchase"}, {"id": 2, "type": "purchase"}]}`)
+
+	req, _ := http.NewRequest("POST", "/transactionservice/transactions/batch", bytes.NewBuffer(jsonRequest))
+	recorder := httptest.NewRecorder()
+	router := httprouter.New()
+	router.Handle(http.MethodPost, "/transactionservice/transactions/batch", transactionController.CreateTransactions)
+	router.ServeHTTP(recorder, req)
+
+	// Assert status code is BadRequest
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+
+	// Assert response body names the malformed transaction
+	expectedResponse := `{"code":"validation_failed","detail":"amount is required","errors":[{"field":"amount","rule":"required","message":"is required"}],"index":1,"instance":"/transactionservice/transactions/batch","status":400,"title":"Bad Request","type":"about:blank"}`
+	assert.Equal(t, expectedResponse, recorder.Body.String())
+}
+
+func TestCreateTransactions_BodyTooLarge(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Mocks
+	mockTransactionService := mock_services.NewMockTransactionServiceI(ctrl)
+
+	// Controller
+	transactionController := controllers.MakeTransactionController(mockTransactionService)
+
+	// A body of 16 MiB and one byte, past the limit of batch bodies
+	jsonRequest := append([]byte(`{"transactions": [`), bytes.Repeat([]byte(" "), 16<<20)...)
+
+	req, _ := http.NewRequest("POST", "/transactionservice/transactions/batch", bytes.NewBuffer(jsonRequest))
+	recorder := httptest.NewRecorder()
+	router := httprouter.New()
+	router.Handle(http.MethodPost, "/transactionservice/transactions/batch", transactionController.CreateTransactions)
+	router.ServeHTTP(recorder, req)
+
+	// Assert status code is RequestEntityTooLarge
+	assert.Equal(t, http.StatusRequestEntityTooLarge, recorder.Code)
+
+	// Assert response body reports the limit
+	expectedResponse := `{"code":"body_too_large","detail":"request body is too large","instance":"/transactionservice/transactions/batch","max_bytes":16777216,"status":413,"title":"Request Entity Too Large","type":"about:blank"}`
+	assert.Equal(t, expectedResponse, recorder.Body.String())
+}
+
+func TestImportTransactions_CSV(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Mocks
+	mockTransactionService := mock_services.NewMockTransactionServiceI(ctrl)
+
+	// Controller
+	transactionController := controllers.MakeTransactionController(mockTransactionService)
+
+	csvRequest := []byte("id,amount,type,parent_id\n2,50,purchase,1\n1,100,purchase,\n")
+
+	// Mock expectations
+	mockTransactionService.EXPECT().ImportTransactions(gomock.Any(), gomock.Len(2), true).Return(&services.BatchResult{
+		DryRun: true,
+		Results: []services.BatchItemResult{
+			{Index: 0, ID: 2, Status: services.BatchItemFailed, Err: services.ErrInvalidCurrency},
+			{Index: 1, ID: 1, Status: services.BatchItemCreated},
+		},
+	}, nil)
+
+	req, _ := http.NewRequest("POST", "/transactionservice/transactions/import?dry_run=true", bytes.NewBuffer(csvRequest))
+	req.Header.Set("Content-Type", "text/csv")
+	recorder := httptest.NewRecorder()
+	router := httprouter.New()
+	router.Handle(http.MethodPost, "/transactionservice/transactions/import", transactionController.ImportTransactions)
+	router.ServeHTTP(recorder, req)
+
+	// Assert status code is OK
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	// Assert response body reports each transaction with its line
+	expectedResponse := `{"committed":false,"dry_run":true,"results":[{"index":0,"line":2,"id":2,"status":"failed","code":"invalid_currency","error":"currency is not a valid ISO 4217 code"},{"index":1,"line":3,"id":1,"status":"created"}]}`
+	assert.Equal(t, expectedResponse, recorder.Body.String())
+}
+
+func TestImportTransactions_InvalidLine(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Mocks
+	mockTransactionService := mock_services.NewMockTransactionServiceI(ctrl)
+
+	// Controller
+	transactionController := controllers.MakeTransactionController(mockTransactionService)
+
+	jsonRequest := []byte("{\"id\": 1, \"amount\": 100, \"type\": \"purchase\"}\n{\"id\": 2, \"amount\": 100}\n")
+
+	req, _ := http.NewRequest("POST", "/transactionservice/transactions/import?format=ndjson", bytes.NewBuffer(jsonRequest))
+	recorder := httptest.NewRecorder()
+	router := httprouter.New()
+	router.Handle(http.MethodPost, "/transactionservice/transactions/import", transactionController.ImportTransactions)
+	router.ServeHTTP(recorder, req)
+
+	// Assert status code is BadRequest
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+
+	// Assert response body names the malformed line
+	expectedResponse := `{"code":"invalid_request","detail":"Invalid transaction: type must not be blank","instance":"/transactionservice/transactions/import","line":2,"status":400,"title":"Bad Request","type":"about:blank"}`
+	assert.Equal(t, expectedResponse, recorder.Body.String())
+}
+
+func TestImportTransactions_BodyTooLarge(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Mocks
+	mockTransactionService := mock_services.NewMockTransactionServiceI(ctrl)
+
+	// Controller
+	transactionController := controllers.MakeTransactionController(mockTransactionService)
+
+	// A body of 16 MiB and one byte, past the limit of batch bodies
+	ndjsonRequest := bytes.Repeat([]byte("\n"), 16<<20+1)
+
+	req, _ := http.NewRequest("POST", "/transactionservice/transactions/import?format=ndjson", bytes.NewBuffer(ndjsonRequest))
+	recorder := httptest.NewRecorder()
+	router := httprouter.New()
+	router.Handle(http.MethodPost, "/transactionservice/transactions/import", transactionController.ImportTransactions)
+	router.ServeHTTP(recorder, req)
+
+	// Assert status code is RequestEntityTooLarge
+	assert.Equal(t, http.StatusRequestEntityTooLarge, recorder.Code)
+
+	// Assert response body reports the limit
+	expectedResponse := `{"code":"body_too_large","detail":"request body is too large","instance":"/transactionservice/transactions/import","max_bytes":16777216,"status":413,"title":"Request Entity Too Large","type":"about:blank"}`
+	assert.Equal(t, expectedResponse, recorder.Body.String())
+}
+
+func TestExportTransactions_CSV(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Mocks
+	mockTransactionService := mock_services.NewMockTransactionServiceI(ctrl)
+
+	// Controller
+	transactionController := controllers.MakeTransactionController(mockTransactionService)
+
+	// Mock expectations: the table is read page by page from its first transaction until an empty page
+	parentID, lastID := uint(1), uint(2)
+	createdAt := time.Date(2024, 3, 2, 8, 0, 0, 0, time.UTC)
+	createdBy := "alice"
+	mockTransactionService.EXPECT().ListTransactions(gomock.Any(), gomock.Nil(), services.MaxPageLimit).Return([]models.Transaction{
+		{Id: 0, Amount: decimal.NewFromInt(1), Type: "fee", Currency: "USD"},
+		{Id: 1, Amount: decimal.NewFromInt(100), Type: "purchase", Currency: "USD", CreatedAt: &createdAt, UpdatedAt: &createdAt, CreatedBy: &createdBy},
+		{Id: 2, Amount: decimal.RequireFromString("10.5"), Type: "purchase", ParentID: &parentID, Currency: "EUR"},
+	}, nil)
+	mockTransactionService.EXPECT().ListTransactions(gomock.Any(), &lastID, services.MaxPageLimit).Return([]models.Transaction{}, nil)
+
+	req, _ := http.NewRequest("GET", "/transactionservice/transactions/export?format=csv", nil)
+	recorder := httptest.NewRecorder()
+	router := httprouter.New()
+	router.Handle(http.MethodGet, "/transactionservice/transactions/export", transactionController.ExportTransactions)
+	router.ServeHTTP(recorder, req)
+
+	// Assert status code is OK
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "text/csv", recorder.Header().Get("Content-Type"))
+
+	// Assert response body holds every transaction
+	expectedResponse := "id,amount,type,parent_id,currency,occurred_at,created_at,updated_at,created_by\n" +
+		"0,1,fee,,USD,,,,\n" +
+		"1,100,purchase,,USD,,2024-03-02T08:00:00Z,2024-03-02T08:00:00Z,alice\n" +
+		"2,10.5,purchase,1,EUR,,,,\n"
+	assert.Equal(t, expectedResponse, recorder.Body.String())
+}
+
+func TestGetTransitiveSum_WrappedErrorDetails(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Mocks
+	mockTransactionService := mock_services.NewMockTransactionServiceI(ctrl)
+
+	// Controller
+	transactionController := controllers.MakeTransactionController(mockTransactionService)
+
+	// Mock expectations: the domain error is wrapped with context on its way up
+	rateErr := rates.ErrRateNotFound.WithDetails(map[string]interface{}{"currency": "GBP"})
+	mockTransactionService.EXPECT().GetTransitiveSum(gomock.Any(), uint(1), "EUR").Return(nil, fmt.Errorf("converting GBP subtotal to EUR: %w", rateErr))
+
+	req, _ := http.NewRequest("GET", "/transactionservice/sum/1?currency=eur", nil)
+	recorder := httptest.NewRecorder()
+	router := httprouter.New()
+	router.Handle(http.MethodGet, "/transactionservice/sum/:transaction_id", transactionController.GetTransitiveSum)
+	router.ServeHTTP(recorder, req)
+
+	// Assert the status, code and details come from the wrapped error
+	assert.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+	assert.Equal(t, controllers.ProblemContentType, recorder.Header().Get("Content-Type"))
+	expectedResponse := `{"code":"rate_not_found","currency":"GBP","detail":"exchange rate not available for currency","instance":"/transactionservice/sum/1","status":422,"title":"Unprocessable Entity","type":"about:blank"}`
+	assert.Equal(t, expectedResponse, recorder.Body.String())
+}
+
+func TestRouter_ProblemResponses(t *testing.T) {
+	router := httprouter.New()
+	router.NotFound = http.HandlerFunc(controllers.NotFoundHandler)
+	router.MethodNotAllowed = http.HandlerFunc(controllers.MethodNotAllowedHandler)
+	router.PanicHandler = controllers.PanicHandler
+	router.GET("/transactionservice/sum/:transaction_id", func(http.ResponseWriter, *http.Request, httprouter.Params) {
+		panic("boom")
+	})
+
+	for _, tc := range []struct {
+		method, path string
+		expected     string
+	}{
+		{"GET", "/transactionservice/unknown", `{"code":"route_not_found","detail":"no endpoint matches the request path","instance":"/transactionservice/unknown","status":404,"title":"Not Found","type":"about:blank"}`},
+		{"POST", "/transactionservice/sum/1", `{"code":"method_not_allowed","detail":"the endpoint does not support the request method","instance":"/transactionservice/sum/1","status":405,"title":"Method Not Allowed","type":"about:blank"}`},
+		{"GET", "/transactionservice/sum/1", `{"code":"internal_error","detail":"internal server error","instance":"/transactionservice/sum/1","status":500,"title":"Internal Server Error","type":"about:blank"}`},
+	} {
+		req, _ := http.NewRequest(tc.method, tc.path, nil)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+
+		// Assert errors outside the handlers are reported as problem details too
+		assert.Equal(t, controllers.ProblemContentType, recorder.Header().Get("Content-Type"), tc.path)
+		assert.Equal(t, tc.expected, recorder.Body.String(), tc.path)
+	}
+}
+
+func TestCreateTransaction_UnknownType(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Mocks
+	mockTransactionService := mock_services.NewMockTransactionServiceI(ctrl)
+
+	// Controller
+	transactionController := controllers.MakeTransactionController(mockTransactionService)
+
+	// Mock expectations
+	mockTransactionService.EXPECT().CreateTransaction(gomock.Any(), gomock.Any()).
+		Return(nil, false, services.ErrUnknownTransactionType.WithDetails(map[string]interface{}{"transaction_type": "purchace"}))
+
+	req, _ := http.NewRequest("PUT", "/transactionservice/transaction/1", bytes.NewBufferString(`{"amount": 100, "type": "Purchace"}`))
+	recorder := httptest.NewRecorder()
+	router := httprouter.New()
+	router.Handle(http.MethodPut, "/transactionservice/transaction/:transaction_id", transactionController.CreateTransaction)
+	router.ServeHTTP(recorder, req)
+
+	// Assert status code is UnprocessableEntity and the rejected type is named
+	assert.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+	expectedResponse := `{"code":"unknown_transaction_type","detail":"transaction type is not registered","instance":"/transactionservice/transaction/1","status":422,"title":"Unprocessable Entity","transaction_type":"purchace","type":"about:blank"}`
+	assert.Equal(t, expectedResponse, recorder.Body.String())
+}
+
+func TestListTransactionTypes_Success(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Mocks
+	mockTransactionTypeService := mock_services.NewMockTransactionTypeServiceI(ctrl)
+
+	// Controller
+	transactionTypeController := controllers.MakeTransactionTypeController(mockTransactionTypeService)
+
+	// Mock expectations
+	mockTransactionTypeService.EXPECT().ListTransactionTypes(gomock.Any()).
+		Return([]models.TransactionType{{Name: "purchase"}, {Name: "refund"}}, nil)
+
+	req, _ := http.NewRequest("GET", "/transactionservice/admin/types", nil)
+	recorder := httptest.NewRecorder()
+	router := httprouter.New()
+	router.Handle(http.MethodGet, "/transactionservice/admin/types", transactionTypeController.ListTransactionTypes)
+	router.ServeHTTP(recorder, req)
+
+	// Assert status code is OK with every registered type
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, `{"types":[{"name":"purchase"},{"name":"refund"}]}`, recorder.Body.String())
+}
+
+func TestCreateTransactionType(t *testing.T) {
+	for _, tc := range []struct {
+		name             string
+		created          bool
+		expectedStatus   int
+		expectedResponse string
+	}{
+		{"new type", true, http.StatusCreated, `{"status":"ok","type":{"name":"online purchase"}}`},
+		{"registered type", false, http.StatusOK, `{"status":"ok","type":{"name":"online purchase"}}`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			// Mocks
+			mockTransactionTypeService := mock_services.NewMockTransactionTypeServiceI(ctrl)
+
+			// Controller
+			transactionTypeController := controllers.MakeTransactionTypeController(mockTransactionTypeService)
+
+			// Mock expectations
+			mockTransactionTypeService.EXPECT().AddTransactionType(gomock.Any(), "Online Purchase").
+				Return(&models.TransactionType{Name: "online purchase"}, tc.created, nil)
+
+			req, _ := http.NewRequest("POST", "/transactionservice/admin/types", bytes.NewBufferString(`{"name": "Online Purchase"}`))
+			recorder := httptest.NewRecorder()
+			router := httprouter.New()
+			router.Handle(http.MethodPost, "/transactionservice/admin/types", transactionTypeController.CreateTransactionType)
+			router.ServeHTTP(recorder, req)
+
+			// Assert the registered type is returned, created or not
+			assert.Equal(t, tc.expectedStatus, recorder.Code)
+			assert.Equal(t, tc.expectedResponse, recorder.Body.String())
+		})
+	}
+}
+
+func TestWithAdminToken(t *testing.T) {
+	for _, tc := range []struct {
+		name             string
+		token            string
+		authorization    string
+		expectedStatus   int
+		expectedResponse string
+	}{
+		{"no token configured", "", "Bearer secret", http.StatusForbidden, `{"code":"admin_disabled","detail":"admin endpoints are disabled","instance":"/transactionservice/admin/types","status":403,"title":"Forbidden","type":"about:blank"}`},
+		{"missing token", "secret", "", http.StatusUnauthorized, `{"code":"unauthorized","detail":"admin token is missing or invalid","instance":"/transactionservice/admin/types","status":401,"title":"Unauthorized","type":"about:blank"}`},
+		{"wrong token", "secret", "Bearer guess", http.StatusUnauthorized, `{"code":"unauthorized","detail":"admin token is missing or invalid","instance":"/transactionservice/admin/types","status":401,"title":"Unauthorized","type":"about:blank"}`},
+		{"valid token", "secret", "Bearer secret", http.StatusOK, `ok`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			handler := func(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+				w.Write([]byte("ok"))
+			}
+
+			req, _ := http.NewRequest("GET", "/transactionservice/admin/types", nil)
+			if tc.authorization != "" {
+				req.Header.Set("Authorization", tc.authorization)
+			}
+			recorder := httptest.NewRecorder()
+			router := httprouter.New()
+			router.Handle(http.MethodGet, "/transactionservice/admin/types", controllers.WithAdminToken(tc.token, handler))
+			router.ServeHTTP(recorder, req)
+
+			// Assert only requests carrying the configured token reach the handler
+			assert.Equal(t, tc.expectedStatus, recorder.Code)
+			assert.Equal(t, tc.expectedResponse, recorder.Body.String())
+		})
+	}
+}
+
+func TestCreateTransaction_AuditFields(t *testing.T) {
+	t.Run("when the caller and business time are provided", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		// Mocks
+		mockTransactionService := mock_services.NewMockTransactionServiceI(ctrl)
+
+		// Controller
+		transactionController := controllers.MakeTransactionController(mockTransactionService)
+
+		jsonRequest := []byte(`{"amount": 100, "type": "purchase", "occurred_at": "2024-03-01T13:30:00+01:00"}`)
+
+		// Mock expectations
+		occurredAt := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+		createdAt := time.Date(2024, 3, 2, 8, 0, 0, 500000, time.UTC)
+		createdBy := "alice"
+		storedTransaction := &models.Transaction{
+			Id:         1,
+			Amount:     decimal.NewFromInt(100),
+			Type:       "purchase",
+			Currency:   "USD",
+			OccurredAt: &occurredAt,
+			CreatedAt:  &createdAt,
+			UpdatedAt:  &createdAt,
+			CreatedBy:  &createdBy,
+		}
+		mockTransactionService.EXPECT().CreateTransaction(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, transaction models.Transaction) (*models.Transaction, bool, error) {
+			assert.Equal(t, "alice", audit.Actor(ctx))
+			assert.True(t, occurredAt.Equal(*transaction.OccurredAt))
+			return storedTransaction, false, nil
+		})
+
+		req, _ := http.NewRequest("PUT", "/transactionservice/transaction/1", bytes.NewBuffer(jsonRequest))
+		req.Header.Set(controllers.ActorHeader, " alice ")
+		recorder := httptest.NewRecorder()
+		router := httprouter.New()
+		router.Handle(http.MethodPut, "/transactionservice/transaction/:transaction_id", controllers.WithActor(transactionController.CreateTransaction))
+		router.ServeHTTP(recorder, req)
+
+		// Assert the stored transaction is returned with its audit fields
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		expectedResponse := `{"status":"ok","transaction":{"id":1,"amount":100,"type":"purchase","parent_id":null,"currency":"USD",` +
+			`"occurred_at":"2024-03-01T12:30:00Z","created_at":"2024-03-02T08:00:00.0005Z","updated_at":"2024-03-02T08:00:00.0005Z","created_by":"alice"}}`
+		assert.Equal(t, expectedResponse, recorder.Body.String())
+	})
+
+	t.Run("when occurred_at is not a timestamp", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		// Mocks
+		mockTransactionService := mock_services.NewMockTransactionServiceI(ctrl)
+
+		// Controller
+		transactionController := controllers.MakeTransactionController(mockTransactionService)
+
+		req, _ := http.NewRequest("PUT", "/transactionservice/transaction/1", bytes.NewBufferString(`{"amount": 100, "type": "purchase", "occurred_at": "01/03/2024"}`))
+		recorder := httptest.NewRecorder()
+		router := httprouter.New()
+		router.Handle(http.MethodPut, "/transactionservice/transaction/:transaction_id", controllers.WithActor(transactionController.CreateTransaction))
+		router.ServeHTTP(recorder, req)
+
+		// Assert status code is BadRequest
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), `"detail":"occurred_at must be an RFC 3339 timestamp"`)
+	})
+
+	t.Run("when the caller is too long", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		// Mocks
+		mockTransactionService := mock_services.NewMockTransactionServiceI(ctrl)
+
+		// Controller
+		transactionController := controllers.MakeTransactionController(mockTransactionService)
+
+		req, _ := http.NewRequest("PUT", "/transactionservice/transaction/1", bytes.NewBufferString(`{"amount": 100, "type": "purchase"}`))
+		req.Header.Set(controllers.ActorHeader, strings.Repeat("a", 256))
+		recorder := httptest.NewRecorder()
+		router := httprouter.New()
+		router.Handle(http.MethodPut, "/transactionservice/transaction/:transaction_id", controllers.WithActor(transactionController.CreateTransaction))
+		router.ServeHTTP(recorder, req)
+
+		// Assert the request is rejected before reaching the service
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+		expectedResponse := `{"code":"invalid_request","detail":"Header X-User-ID must be at most 255 characters","instance":"/transactionservice/transaction/1","status":400,"title":"Bad Request","type":"about:blank"}`
+		assert.Equal(t, expectedResponse, recorder.Body.String())
+	})
+}
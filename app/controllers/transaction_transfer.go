@@ -0,0 +1,148 @@
+package controllers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"transaction_system/app/lib/apperror"
+	"transaction_system/app/lib/transfer"
+	"transaction_system/app/models"
+	"transaction_system/app/services"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+func (t *transactionController) ImportTransactions(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	format, err := transferFormat(r, r.Header.Get("Content-Type"))
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	dryRun := false
+	if val := r.URL.Query().Get("dry_run"); val != "" {
+		dryRun, err = strconv.ParseBool(val)
+		if err != nil {
+			respondWithError(w, r, apperror.InvalidRequest("Invalid dry_run format"))
+			return
+		}
+	}
+
+	body, err := readBody(w, r, maxBatchBodySize)
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	// Decode every transaction of the body, rejecting the whole import if any line is malformed
+	records, err := transfer.Decode(format, bytes.NewReader(body))
+	if err != nil {
+		var lineErr *transfer.LineError
+		if errors.As(err, &lineErr) {
+			respondWithError(w, r, apperror.InvalidRequest("Invalid transaction: %v", lineErr.Err).WithDetails(map[string]interface{}{
+				"line": lineErr.Line,
+			}))
+			return
+		}
+		respondWithError(w, r, apperror.InvalidRequest("Error decoding request body"))
+		return
+	}
+	transactions := make([]models.Transaction, len(records))
+	for i, record := range records {
+		transactions[i] = record.Transaction
+	}
+
+	// Call the service to import the transactions
+	result, err := t.transactionService.ImportTransactions(r.Context(), transactions, dryRun)
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	results := make([]batchItemResponse, len(result.Results))
+	for i, itemResult := range result.Results {
+		results[i] = batchItemResponse{
+			Index:  itemResult.Index,
+			Line:   records[itemResult.Index].Line,
+			ID:     itemResult.ID,
+			Status: itemResult.Status,
+		}
+		results[i].setError(r, itemResult.Err)
+	}
+
+	statusCode := http.StatusCreated
+	if result.DryRun {
+		statusCode = http.StatusOK
+	} else if !result.Committed {
+		statusCode = http.StatusUnprocessableEntity
+	}
+	respondWithJSON(w, r, map[string]interface{}{
+		"dry_run":   result.DryRun,
+		"committed": result.Committed,
+		"results":   results,
+	}, statusCode)
+}
+
+func (t *transactionController) ExportTransactions(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	format, err := transferFormat(r, "")
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	// Fetch the first page before writing anything, so that failures can still be reported as errors
+	transactions, err := t.transactionService.ListTransactions(r.Context(), nil, services.MaxPageLimit)
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	encoder, err := transfer.NewEncoder(format, w)
+	if err != nil {
+		respondWithError(w, r, apperror.InvalidRequest("%v", err))
+		return
+	}
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="transactions.%s"`, format))
+	w.WriteHeader(http.StatusOK)
+
+	// Stream the table page by page; once the body has started, errors can only cut the export short
+	for len(transactions) > 0 {
+		for _, transaction := range transactions {
+			if err := encoder.Encode(transaction); err != nil {
+				log.Printf("Error writing export: %v", err)
+				return
+			}
+		}
+		afterID := transactions[len(transactions)-1].Id
+		transactions, err = t.transactionService.ListTransactions(r.Context(), &afterID, services.MaxPageLimit)
+		if err != nil {
+			log.Printf("Error exporting transactions after ID %d: %v", afterID, err)
+			return
+		}
+	}
+	if err := encoder.Flush(); err != nil {
+		log.Printf("Error writing export: %v", err)
+	}
+}
+
+// transferFormat reads the format query parameter, falling back to the format named by contentType and
+// then to NDJSON. It returns an error when the format is unknown.
+func transferFormat(r *http.Request, contentType string) (transfer.Format, error) {
+	name := r.URL.Query().Get("format")
+	if name == "" {
+		name = string(transfer.NDJSON)
+		if strings.HasPrefix(contentType, transfer.CSV.ContentType()) {
+			name = string(transfer.CSV)
+		}
+	}
+	format, err := transfer.ParseFormat(name)
+	if err != nil {
+		return "", apperror.InvalidRequest("Invalid format, must be 'csv' or 'ndjson'")
+	}
+	return format, nil
+}
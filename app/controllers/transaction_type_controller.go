@@ -0,0 +1,72 @@
+package controllers
+
+import (
+	"net/http"
+	"transaction_system/app/lib/validation"
+	"transaction_system/app/services"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+type TransactionTypeControllerI interface {
+	ListTransactionTypes(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	CreateTransactionType(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+}
+
+type transactionTypeController struct {
+	transactionTypeService services.TransactionTypeServiceI
+}
+
+func NewTransactionTypeController() TransactionTypeControllerI {
+	return &transactionTypeController{
+		transactionTypeService: services.NewTransactionTypeService(),
+	}
+}
+
+func MakeTransactionTypeController(transactionTypeService services.TransactionTypeServiceI) TransactionTypeControllerI {
+	return &transactionTypeController{
+		transactionTypeService: transactionTypeService,
+	}
+}
+
+// transactionTypeRequest is the body of a request registering a transaction type.
+type transactionTypeRequest struct {
+	Name *string `json:"name" validate:"required,notblank,max=50"`
+}
+
+func (t *transactionTypeController) ListTransactionTypes(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	// Call the service to list the registered types
+	transactionTypes, err := t.transactionTypeService.ListTransactionTypes(r.Context())
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, r, map[string]interface{}{"types": transactionTypes}, http.StatusOK)
+}
+
+func (t *transactionTypeController) CreateTransactionType(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	// Decode and validate the request body
+	var request transactionTypeRequest
+	if _, err := validation.Decode(r.Body, &request); err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	// Call the service to register the type
+	transactionType, created, err := t.transactionTypeService.AddTransactionType(r.Context(), *request.Name)
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	// Respond with the registered type, which already existed unless it was created
+	statusCode := http.StatusOK
+	if created {
+		statusCode = http.StatusCreated
+	}
+	respondWithJSON(w, r, map[string]interface{}{
+		"status": getStatusMessage(true),
+		"type":   transactionType,
+	}, statusCode)
+}
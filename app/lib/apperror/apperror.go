@@ -0,0 +1,101 @@
+package apperror
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Error is a domain error: a stable code identifying the problem, the HTTP status it is reported with,
+// a human-readable message and optional details. Errors are matched with errors.Is by code, so a copy
+// carrying its own message, details or cause still matches the error it was derived from.
+type Error struct {
+	Code    string
+	Status  int
+	Message string
+	// Details holds structured information about this occurrence of the problem.
+	Details map[string]interface{}
+	cause   error
+}
+
+var (
+	// ErrInvalidRequest reports a request that is malformed or fails validation.
+	ErrInvalidRequest = New("invalid_request", http.StatusBadRequest, "request is invalid")
+	// ErrInternal reports an unexpected failure, which is not described to the client.
+	ErrInternal = New("internal_error", http.StatusInternalServerError, "internal server error")
+	// ErrTimeout reports a request that ran past its deadline.
+	ErrTimeout = New("request_timeout", http.StatusGatewayTimeout, "request timed out")
+	// ErrCanceled reports a request canceled before it completed.
+	ErrCanceled = New("request_canceled", http.StatusServiceUnavailable, "request was canceled")
+)
+
+// New returns an Error with the given code, HTTP status and message.
+func New(code string, status int, message string) *Error {
+	return &Error{Code: code, Status: status, Message: message}
+}
+
+// InvalidRequest returns an ErrInvalidRequest describing what is wrong with the request.
+func InvalidRequest(format string, args ...interface{}) *Error {
+	return ErrInvalidRequest.WithMessage(fmt.Sprintf(format, args...))
+}
+
+func (e *Error) Error() string {
+	if e.cause != nil {
+		return fmt.Sprintf("%s: %v", e.Message, e.cause)
+	}
+	return e.Message
+}
+
+// Unwrap returns the error that caused e, if any.
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+// Is reports whether target is an Error with the same code.
+func (e *Error) Is(target error) bool {
+	targetErr, ok := target.(*Error)
+	return ok && targetErr.Code == e.Code
+}
+
+// WithMessage returns a copy of e with message replacing its message.
+func (e *Error) WithMessage(message string) *Error {
+	copied := *e
+	copied.Message = message
+	return &copied
+}
+
+// WithDetails returns a copy of e carrying details in addition to its own.
+func (e *Error) WithDetails(details map[string]interface{}) *Error {
+	copied := *e
+	copied.Details = make(map[string]interface{}, len(e.Details)+len(details))
+	for key, value := range e.Details {
+		copied.Details[key] = value
+	}
+	for key, value := range details {
+		copied.Details[key] = value
+	}
+	return &copied
+}
+
+// Wrap returns a copy of e caused by err, which stays reachable with errors.Is and errors.As.
+func (e *Error) Wrap(err error) *Error {
+	copied := *e
+	copied.cause = err
+	return &copied
+}
+
+// From returns the first Error in the chain of err. Context errors map to ErrTimeout and ErrCanceled, and
+// any other error to ErrInternal wrapping it.
+func From(err error) *Error {
+	var appErr *Error
+	switch {
+	case errors.As(err, &appErr):
+		return appErr
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrTimeout.Wrap(err)
+	case errors.Is(err, context.Canceled):
+		return ErrCanceled.Wrap(err)
+	}
+	return ErrInternal.Wrap(err)
+}
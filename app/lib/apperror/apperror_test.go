@@ -0,0 +1,52 @@
+package apperror_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"transaction_system/app/lib/apperror"
+)
+
+var errNotFound = apperror.New("not_found", http.StatusNotFound, "thing does not exist")
+
+func TestError_MatchesByCode(t *testing.T) {
+	cause := errors.New("database is down")
+	derived := errNotFound.WithMessage("thing 7 does not exist").WithDetails(map[string]interface{}{"id": 7}).Wrap(cause)
+	wrapped := fmt.Errorf("getting thing 7: %w", derived)
+
+	// Assert copies still match the original through wrapping, and keep their cause reachable
+	assert.ErrorIs(t, wrapped, errNotFound)
+	assert.ErrorIs(t, wrapped, cause)
+	assert.NotErrorIs(t, wrapped, apperror.ErrInvalidRequest)
+	assert.Equal(t, "thing 7 does not exist: database is down", derived.Error())
+
+	// Assert deriving copies leaves the original untouched
+	assert.Equal(t, "thing does not exist", errNotFound.Message)
+	assert.Nil(t, errNotFound.Details)
+	assert.Nil(t, errNotFound.Unwrap())
+}
+
+func TestFrom(t *testing.T) {
+	for _, tc := range []struct {
+		err      error
+		expected *apperror.Error
+	}{
+		{fmt.Errorf("getting thing: %w", errNotFound), errNotFound},
+		{fmt.Errorf("querying: %w", context.DeadlineExceeded), apperror.ErrTimeout},
+		{context.Canceled, apperror.ErrCanceled},
+		{errors.New("unexpected"), apperror.ErrInternal},
+	} {
+		appErr := apperror.From(tc.err)
+		assert.ErrorIs(t, appErr, tc.expected, tc.err.Error())
+		assert.Equal(t, tc.expected.Status, appErr.Status, tc.err.Error())
+	}
+
+	// Assert details survive wrapping
+	detailed := errNotFound.WithDetails(map[string]interface{}{"id": 7})
+	assert.Equal(t, map[string]interface{}{"id": 7}, apperror.From(fmt.Errorf("wrapped: %w", detailed)).Details)
+}
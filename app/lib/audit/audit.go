@@ -0,0 +1,18 @@
+// Package audit carries the actor on whose behalf a request is made through its context, so that the
+// transactions it creates record who created them.
+package audit
+
+import "context"
+
+type actorKey struct{}
+
+// WithActor returns a copy of ctx carrying actor.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// Actor returns the actor carried by ctx, or an empty string when there is none.
+func Actor(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
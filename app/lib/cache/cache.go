@@ -0,0 +1,166 @@
+package cache
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// DefaultMaxEntries bounds the number of values held by a memory store.
+const DefaultMaxEntries = 100000
+
+// Store holds cached values together with generation counters. Callers include the generations a value
+// depends on in its key, so bumping a generation invalidates every value stored under the old one
+// without having to find and delete them.
+type Store interface {
+	// Get returns the value stored under key and whether it was found.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set stores value under key until ttl elapses.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Generations returns the current generation of every key, zero for a key never bumped.
+	Generations(ctx context.Context, keys ...string) ([]int64, error)
+	// Bump increments the generation of every key.
+	Bump(ctx context.Context, keys ...string) error
+}
+
+// memoryEntry is a value held by a memory store.
+type memoryEntry struct {
+	value   []byte
+	expires time.Time
+}
+
+// memoryStore keeps values in process memory. Generations are never evicted, as forgetting one would
+// bring values stored under its earlier numbers back into use.
+type memoryStore struct {
+	mu          sync.Mutex
+	entries     map[string]memoryEntry
+	generations map[string]int64
+	maxEntries  int
+	now         func() time.Time
+}
+
+// NewMemoryStore returns a Store held in process memory, holding at most maxEntries values at a time.
+func NewMemoryStore(maxEntries int) Store {
+	return &memoryStore{
+		entries:     make(map[string]memoryEntry),
+		generations: make(map[string]int64),
+		maxEntries:  maxEntries,
+		now:         time.Now,
+	}
+}
+
+func (m *memoryStore) Get(_ context.Context, key string) ([]byte, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	if !m.now().Before(entry.expires) {
+		delete(m.entries, key)
+		return nil, false, nil
+	}
+	return entry.value, true, nil
+}
+
+func (m *memoryStore) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	if _, exists := m.entries[key]; !exists && len(m.entries) >= m.maxEntries {
+		for k, entry := range m.entries {
+			if !now.Before(entry.expires) {
+				delete(m.entries, k)
+			}
+		}
+		// Values are only an optimisation, so skip caching rather than grow past the bound
+		if len(m.entries) >= m.maxEntries {
+			return nil
+		}
+	}
+	m.entries[key] = memoryEntry{value: value, expires: now.Add(ttl)}
+	return nil
+}
+
+func (m *memoryStore) Generations(_ context.Context, keys ...string) ([]int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	generations := make([]int64, len(keys))
+	for i, key := range keys {
+		generations[i] = m.generations[key]
+	}
+	return generations, nil
+}
+
+func (m *memoryStore) Bump(_ context.Context, keys ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, key := range keys {
+		m.generations[key]++
+	}
+	return nil
+}
+
+// redisStore keeps values in Redis, sharing them between every instance of the service.
+type redisStore struct {
+	client redis.UniversalClient
+}
+
+// NewRedisStore returns a Store kept in the Redis database selected by client. Generations are stored
+// without an expiry, so configure Redis to only evict keys that have one, e.g. volatile-lru.
+func NewRedisStore(client redis.UniversalClient) Store {
+	return &redisStore{client: client}
+}
+
+func (r *redisStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	value, err := r.client.Get(ctx, key).Bytes()
+	if err == redis.Nil {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+func (r *redisStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return r.client.Set(ctx, key, value, ttl).Err()
+}
+
+func (r *redisStore) Generations(ctx context.Context, keys ...string) ([]int64, error) {
+	values, err := r.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	generations := make([]int64, len(keys))
+	for i, value := range values {
+		if value == nil {
+			continue
+		}
+		s, _ := value.(string)
+		generation, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		generations[i] = generation
+	}
+	return generations, nil
+}
+
+func (r *redisStore) Bump(ctx context.Context, keys ...string) error {
+	_, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, key := range keys {
+			pipe.Incr(ctx, key)
+		}
+		return nil
+	})
+	return err
+}
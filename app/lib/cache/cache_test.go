@@ -0,0 +1,97 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testStores returns every store implementation, each empty, and a function moving its clock forward.
+func testStores(t *testing.T) map[string]struct {
+	store   Store
+	advance func(time.Duration)
+} {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	clock := time.Now()
+	memory := NewMemoryStore(2)
+	memory.(*memoryStore).now = func() time.Time { return clock }
+
+	return map[string]struct {
+		store   Store
+		advance func(time.Duration)
+	}{
+		"memory": {memory, func(d time.Duration) { clock = clock.Add(d) }},
+		"redis":  {NewRedisStore(client), server.FastForward},
+	}
+}
+
+func TestStore_Values(t *testing.T) {
+	ctx := context.Background()
+	for name, tc := range testStores(t) {
+		_, found, err := tc.store.Get(ctx, "missing")
+		require.NoError(t, err, name)
+		assert.False(t, found, name)
+
+		require.NoError(t, tc.store.Set(ctx, "key", []byte("value"), time.Minute), name)
+		value, found, err := tc.store.Get(ctx, "key")
+		require.NoError(t, err, name)
+		assert.True(t, found, name)
+		assert.Equal(t, "value", string(value), name)
+
+		// Assert values expire after their ttl
+		tc.advance(2 * time.Minute)
+		_, found, err = tc.store.Get(ctx, "key")
+		require.NoError(t, err, name)
+		assert.False(t, found, name)
+	}
+}
+
+func TestStore_Generations(t *testing.T) {
+	ctx := context.Background()
+	for name, tc := range testStores(t) {
+		generations, err := tc.store.Generations(ctx, "a", "b")
+		require.NoError(t, err, name)
+		assert.Equal(t, []int64{0, 0}, generations, name)
+
+		require.NoError(t, tc.store.Bump(ctx, "a"), name)
+		require.NoError(t, tc.store.Bump(ctx, "a", "b"), name)
+
+		// Assert generations only move forward and outlive any ttl
+		tc.advance(24 * time.Hour)
+		generations, err = tc.store.Generations(ctx, "a", "b")
+		require.NoError(t, err, name)
+		assert.Equal(t, []int64{2, 1}, generations, name)
+	}
+}
+
+func TestMemoryStore_MaxEntries(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore(2)
+	clock := time.Now()
+	store.(*memoryStore).now = func() time.Time { return clock }
+
+	require.NoError(t, store.Set(ctx, "a", []byte("1"), time.Minute))
+	require.NoError(t, store.Set(ctx, "b", []byte("2"), time.Hour))
+
+	// Assert a full store skips new values but still replaces the ones it holds
+	require.NoError(t, store.Set(ctx, "c", []byte("3"), time.Hour))
+	_, found, _ := store.Get(ctx, "c")
+	assert.False(t, found)
+	require.NoError(t, store.Set(ctx, "b", []byte("4"), time.Hour))
+	value, _, _ := store.Get(ctx, "b")
+	assert.Equal(t, "4", string(value))
+
+	// Assert expired values make room for new ones
+	clock = clock.Add(2 * time.Minute)
+	require.NoError(t, store.Set(ctx, "c", []byte("3"), time.Hour))
+	_, found, _ = store.Get(ctx, "c")
+	assert.True(t, found)
+}
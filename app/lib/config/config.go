@@ -0,0 +1,148 @@
+package config
+
+import (
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultCurrency is used for transactions that do not specify a currency.
+	DefaultCurrency = "USD"
+	// DefaultMaxTreeDepth is the maximum number of ancestors a transaction may have. The migrations that
+	// backfill the descendant sums and closure tables walk trees down to this many levels whatever
+	// MAX_TREE_DEPTH is set to, so deeper trees must be repaired with the repairsums command.
+	DefaultMaxTreeDepth = 1000
+	// DefaultRequestTimeout bounds how long a single HTTP request may run.
+	DefaultRequestTimeout = 30 * time.Second
+	// DefaultCacheTTL bounds how long a cached result may be served.
+	DefaultCacheTTL = 5 * time.Minute
+)
+
+const (
+	// RepositoryModeAdjacency stores the transaction hierarchy as parent_id links only.
+	RepositoryModeAdjacency = "adjacency"
+	// RepositoryModeClosure also stores every ancestor and descendant pair in the transaction_closure table.
+	RepositoryModeClosure = "closure"
+	// RepositoryModeMemory keeps transactions in process memory, without a database.
+	RepositoryModeMemory = "memory"
+)
+
+const (
+	// TypeRegistryOff stores transaction types as given.
+	TypeRegistryOff = "off"
+	// TypeRegistryNormalize normalizes transaction types, so that e.g. "Purchase" and "purchase" are the same type.
+	TypeRegistryNormalize = "normalize"
+	// TypeRegistryStrict normalizes transaction types and rejects those missing from the type registry.
+	TypeRegistryStrict = "strict"
+)
+
+// Config holds the application settings read from the environment.
+type Config struct {
+	// DefaultCurrency is assigned to transactions created without a currency.
+	DefaultCurrency string
+	// RatesFile is the path of a static exchange rates file, empty when conversion is disabled.
+	RatesFile string
+	// RulesFile is the path of a file holding the rules of transaction types, empty when types are unconstrained.
+	RulesFile string
+	// MaxTreeDepth is the maximum number of ancestors a transaction may have. Raising it past
+	// DefaultMaxTreeDepth on an existing database calls for the repairsums command, see DefaultMaxTreeDepth.
+	MaxTreeDepth int
+	// RequestTimeout bounds how long a single HTTP request, and the queries it runs, may take.
+	RequestTimeout time.Duration
+	// MaterializedSums makes writes maintain per-transaction descendant sums that transitive sums are read
+	// from. Run the sums repair command when enabling it on a database written to while it was disabled.
+	MaterializedSums bool
+	// RepositoryMode selects how the transaction hierarchy is stored, one of the RepositoryMode constants.
+	// The closure table is only maintained in closure mode, so rebuild it with the repair command before
+	// switching to closure mode a database that was written to in adjacency mode.
+	RepositoryMode string
+	// CacheEnabled caches transitive sums and type lookups, in Redis when RedisHost is set and in process
+	// memory otherwise.
+	CacheEnabled bool
+	// CacheTTL bounds how long a cached result may be served.
+	CacheTTL time.Duration
+	// RedisHost is the host:port of the Redis server holding the cache, empty when Redis is not used.
+	RedisHost string
+	// RedisDB is the number of the Redis database holding the cache.
+	RedisDB int
+	// TypeRegistry selects how transaction types are checked against the registry of allowed types, one of
+	// the TypeRegistry constants.
+	TypeRegistry string
+	// TypesFile is the path of a file listing transaction types added to the registry on startup, empty when
+	// the registry is only managed through the API.
+	TypesFile string
+	// AdminToken is the bearer token admin endpoints require, empty when they are disabled.
+	AdminToken string
+}
+
+var (
+	config *Config
+	once   sync.Once
+)
+
+// Get returns the application configuration, loading it from the environment on first use.
+func Get() *Config {
+	once.Do(func() {
+		config = Load()
+	})
+	return config
+}
+
+// Load reads the application configuration from the environment.
+func Load() *Config {
+	return &Config{
+		DefaultCurrency:  getEnv("DEFAULT_CURRENCY", DefaultCurrency),
+		RatesFile:        os.Getenv("RATES_FILE"),
+		RulesFile:        os.Getenv("RULES_FILE"),
+		MaxTreeDepth:     getEnvInt("MAX_TREE_DEPTH", DefaultMaxTreeDepth),
+		RequestTimeout:   getEnvDuration("REQUEST_TIMEOUT", DefaultRequestTimeout),
+		MaterializedSums: getEnvBool("MATERIALIZED_SUMS", false),
+		RepositoryMode:   getEnv("REPOSITORY_MODE", RepositoryModeAdjacency),
+		CacheEnabled:     getEnvBool("CACHE_ENABLED", false),
+		CacheTTL:         getEnvDuration("CACHE_TTL", DefaultCacheTTL),
+		RedisHost:        os.Getenv("REDIS_HOST"),
+		RedisDB:          getEnvInt("REDIS_DB", 0),
+		TypeRegistry:     getEnv("TYPE_REGISTRY", TypeRegistryOff),
+		TypesFile:        os.Getenv("TYPES_FILE"),
+		AdminToken:       os.Getenv("ADMIN_TOKEN"),
+	}
+}
+
+// getEnv returns the value of the environment variable or fallback when it is not set.
+func getEnv(key, fallback string) string {
+	if value, ok := os.LookupEnv(key); ok && value != "" {
+		return value
+	}
+	return fallback
+}
+
+// getEnvInt returns the positive integer value of the environment variable or fallback when it is not set or invalid.
+func getEnvInt(key string, fallback int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil || value <= 0 {
+		return fallback
+	}
+	return value
+}
+
+// getEnvBool returns the boolean value of the environment variable, e.g. "true" or "1", or fallback when it
+// is not set or invalid.
+func getEnvBool(key string, fallback bool) bool {
+	value, err := strconv.ParseBool(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+// getEnvDuration returns the positive duration value of the environment variable, e.g. "30s", or fallback
+// when it is not set or invalid.
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(key))
+	if err != nil || value <= 0 {
+		return fallback
+	}
+	return value
+}
@@ -2,13 +2,25 @@ package db
 
 import (
 	"database/sql"
+	_ "embed"
+	"fmt"
 	"log"
+	"strings"
 
+	"github.com/glebarez/sqlite"
 	_ "github.com/lib/pq"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
+// SQLiteScheme prefixes a DATABASE_URL selecting SQLite, e.g. sqlite://transactions.db or sqlite://:memory:.
+const SQLiteScheme = "sqlite://"
+
+// sqliteSchema creates the tables of a SQLite database; the migrations in db/migrations target PostgreSQL.
+//
+//go:embed sqlite_schema.sql
+var sqliteSchema string
+
 var db *gorm.DB
 
 // Get returns a pointer to the database
@@ -19,20 +31,13 @@ func Get() *gorm.DB {
 // Connect opens a connection to the database
 func Connect(url string, maxIdleConnections, maxOpenConnections int) error {
 	var err error
-	var gormdb *sql.DB
-	sqlDB, err := sql.Open("postgres", url)
-	if err != nil {
-		return err
-	}
-	db, err = gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{
-		DisableAutomaticPing: false,
-	})
+	db, err = Open(url)
 	if err != nil {
 		return err
 	}
 
 	// Get database connection handle [*sql.DB](http://golang.org/pkg/database/sql/#DB)
-	gormdb, err = db.DB()
+	gormdb, err := db.DB()
 	if err != nil {
 		return err
 	}
@@ -45,14 +50,72 @@ func Connect(url string, maxIdleConnections, maxOpenConnections int) error {
 	//gormdb
 	//db.LogMode(false)
 	gormdb.SetMaxIdleConns(maxIdleConnections)
-	gormdb.SetMaxOpenConns(maxOpenConnections)
+	if !isMemorySQLite(url) {
+		gormdb.SetMaxOpenConns(maxOpenConnections)
+	}
 	log.Println("Connect to the database")
 	//db.SingularTable(false)
 	return nil
 }
 
+// Open opens the database at url with the driver selected by its scheme: SQLite for sqlite://, and
+// PostgreSQL otherwise. A SQLite database is created with the application schema if needed.
+func Open(url string) (*gorm.DB, error) {
+	if strings.HasPrefix(url, SQLiteScheme) {
+		return openSQLite(strings.TrimPrefix(url, SQLiteScheme), isMemorySQLite(url))
+	}
+
+	sqlDB, err := sql.Open("postgres", url)
+	if err != nil {
+		return nil, err
+	}
+	return gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{
+		DisableAutomaticPing: false,
+	})
+}
+
+// isMemorySQLite reports whether url selects an in-memory SQLite database, which only lives as long as
+// its single connection.
+func isMemorySQLite(url string) bool {
+	return strings.HasPrefix(url, SQLiteScheme+":memory:")
+}
+
+func openSQLite(dsn string, memory bool) (*gorm.DB, error) {
+	// Enforce foreign keys, which SQLite leaves off by default, and wait on locks rather than failing
+	separator := "?"
+	if strings.Contains(dsn, "?") {
+		separator = "&"
+	}
+	dsn += separator + "_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)"
+
+	sqliteDB, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	if err != nil {
+		return nil, err
+	}
+	if memory {
+		sqlDB, err := sqliteDB.DB()
+		if err != nil {
+			return nil, err
+		}
+		sqlDB.SetMaxOpenConns(1)
+	}
+
+	for _, statement := range strings.Split(sqliteSchema, ";") {
+		if strings.TrimSpace(statement) == "" {
+			continue
+		}
+		if err := sqliteDB.Exec(statement).Error; err != nil {
+			return nil, fmt.Errorf("creating SQLite schema: %w", err)
+		}
+	}
+	return sqliteDB, nil
+}
+
 // Close closes the database
 func Close() {
+	if db == nil {
+		return
+	}
 	sqldb, _ := db.DB()
 	_ = sqldb.Close()
 }
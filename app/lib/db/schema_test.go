@@ -0,0 +1,228 @@
+package db_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"transaction_system/app/lib/db"
+)
+
+// schemaColumn is the part of a column compared across databases. Column types are left out, as SQLite
+// stores amounts as text and timestamps as datetimes.
+type schemaColumn struct {
+	Name       string
+	NotNull    bool
+	HasDefault bool
+}
+
+// schemaTable describes a table by its columns in order, its primary key and its foreign keys written
+// as "column -> table(column) ON DELETE action".
+type schemaTable struct {
+	Columns     []schemaColumn
+	PrimaryKey  []string
+	ForeignKeys []string
+}
+
+// schema describes the tables of a database and its indexes, written as "table(column, ...)" by name.
+type schema struct {
+	Tables  map[string]*schemaTable
+	Indexes map[string]string
+}
+
+var (
+	createTablePattern = regexp.MustCompile(`(?is)^CREATE TABLE (?:IF NOT EXISTS )?(\w+)\s*\((.*)\)$`)
+	createIndexPattern = regexp.MustCompile(`(?is)^CREATE INDEX (?:IF NOT EXISTS )?(\w+) ON (\w+) \((.*)\)$`)
+	alterTablePattern  = regexp.MustCompile(`(?is)^ALTER TABLE (\w+) (.*)$`)
+	primaryKeyPattern  = regexp.MustCompile(`(?is)^PRIMARY KEY \((.*)\)$`)
+	foreignKeyPattern  = regexp.MustCompile(`(?is)^FOREIGN KEY \((\w+)\) REFERENCES (\w+)\((\w+)\)(?: ON DELETE (CASCADE))?$`)
+	commentPattern     = regexp.MustCompile(`--[^\n]*`)
+	doBlockPattern     = regexp.MustCompile(`(?s)\bDO \$\$.*?\$\$`)
+	spacePattern       = regexp.MustCompile(`\s+`)
+)
+
+// migratedSchema replays the table and index definitions of the up migrations in dir, in order.
+func migratedSchema(t *testing.T, dir string) schema {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.up.sql"))
+	require.NoError(t, err)
+	require.NotEmpty(t, paths)
+	sort.Strings(paths)
+
+	result := schema{Tables: map[string]*schemaTable{}, Indexes: map[string]string{}}
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		require.NoError(t, err)
+
+		// Blocks of procedural code only check the data, and hold semicolons of their own
+		content = doBlockPattern.ReplaceAll(commentPattern.ReplaceAll(content, nil), nil)
+		for _, statement := range strings.Split(string(content), ";") {
+			statement = strings.TrimSpace(spacePattern.ReplaceAllString(statement, " "))
+			if match := createTablePattern.FindStringSubmatch(statement); match != nil {
+				table := &schemaTable{}
+				for _, definition := range splitDefinitions(match[2]) {
+					addDefinition(t, table, definition)
+				}
+				result.Tables[match[1]] = table
+			} else if match := createIndexPattern.FindStringSubmatch(statement); match != nil {
+				result.Indexes[match[1]] = fmt.Sprintf("%s(%s)", match[2], strings.Join(splitDefinitions(match[3]), ", "))
+			} else if match := alterTablePattern.FindStringSubmatch(statement); match != nil {
+				table := result.Tables[match[1]]
+				require.NotNil(t, table, "%s: %s", path, statement)
+				for _, action := range splitDefinitions(match[2]) {
+					if definition := strings.TrimPrefix(action, "ADD COLUMN "); definition != action {
+						addDefinition(t, table, definition)
+						continue
+					}
+					// Changing the type of a column leaves what is compared untouched
+					require.Regexp(t, `^ALTER COLUMN \w+ TYPE `, action, "%s: %s", path, statement)
+				}
+			} else {
+				// Anything else must only move data around
+				require.Regexp(t, `^(|INSERT .*)$`, statement, path)
+			}
+		}
+	}
+	return result
+}
+
+// splitDefinitions splits a comma separated list on the commas outside parentheses.
+func splitDefinitions(list string) []string {
+	var definitions []string
+	depth, start := 0, 0
+	for i, r := range list {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				definitions = append(definitions, strings.TrimSpace(list[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	return append(definitions, strings.TrimSpace(list[start:]))
+}
+
+// addDefinition adds a column or table constraint of a CREATE TABLE or ADD COLUMN to table.
+func addDefinition(t *testing.T, table *schemaTable, definition string) {
+	if match := primaryKeyPattern.FindStringSubmatch(definition); match != nil {
+		table.PrimaryKey = splitDefinitions(match[1])
+		return
+	}
+	if match := foreignKeyPattern.FindStringSubmatch(definition); match != nil {
+		table.ForeignKeys = append(table.ForeignKeys, foreignKey(match[1], match[2], match[3], match[4]))
+		return
+	}
+
+	fields := strings.Fields(definition)
+	require.GreaterOrEqual(t, len(fields), 2, definition)
+	upper := strings.ToUpper(definition)
+	column := schemaColumn{
+		Name:       fields[0],
+		NotNull:    strings.Contains(upper, " NOT NULL"),
+		HasDefault: strings.Contains(upper, " DEFAULT "),
+	}
+	if strings.Contains(upper, " PRIMARY KEY") {
+		table.PrimaryKey = []string{column.Name}
+		column.NotNull = true
+	}
+	table.Columns = append(table.Columns, column)
+}
+
+// foreignKey describes a foreign key, an empty onDelete meaning the default NO ACTION.
+func foreignKey(column, table, reference, onDelete string) string {
+	if onDelete == "" {
+		onDelete = "NO ACTION"
+	}
+	return fmt.Sprintf("%s -> %s(%s) ON DELETE %s", column, table, reference, strings.ToUpper(onDelete))
+}
+
+// sqliteSchema reads the tables and indexes of a SQLite database from its catalog.
+func sqliteSchema(t *testing.T, sqliteDB *gorm.DB) schema {
+	var tables []string
+	require.NoError(t, sqliteDB.Raw(`SELECT name FROM sqlite_master WHERE type = 'table' ORDER BY name`).Scan(&tables).Error)
+
+	result := schema{Tables: map[string]*schemaTable{}, Indexes: map[string]string{}}
+	for _, name := range tables {
+		var columns []struct {
+			Name      string
+			NotNull   bool
+			DfltValue *string
+			Pk        int
+		}
+		require.NoError(t, sqliteDB.Raw(`SELECT name, "notnull" AS not_null, dflt_value, pk FROM pragma_table_info(?) ORDER BY cid`, name).Scan(&columns).Error)
+
+		table := &schemaTable{}
+		primaryKey := map[int]string{}
+		for _, column := range columns {
+			table.Columns = append(table.Columns, schemaColumn{
+				Name:       column.Name,
+				NotNull:    column.NotNull || column.Pk > 0,
+				HasDefault: column.DfltValue != nil,
+			})
+			if column.Pk > 0 {
+				primaryKey[column.Pk] = column.Name
+			}
+		}
+		for i := 1; i <= len(primaryKey); i++ {
+			table.PrimaryKey = append(table.PrimaryKey, primaryKey[i])
+		}
+
+		var foreignKeys []struct {
+			Table    string
+			From     string
+			To       string
+			OnDelete string
+		}
+		require.NoError(t, sqliteDB.Raw(`SELECT "table", "from", "to", on_delete FROM pragma_foreign_key_list(?) ORDER BY id DESC`, name).Scan(&foreignKeys).Error)
+		for _, key := range foreignKeys {
+			table.ForeignKeys = append(table.ForeignKeys, foreignKey(key.From, key.Table, key.To, key.OnDelete))
+		}
+		result.Tables[name] = table
+
+		var indexes []string
+		require.NoError(t, sqliteDB.Raw(`SELECT name FROM pragma_index_list(?) WHERE origin = 'c'`, name).Scan(&indexes).Error)
+		for _, index := range indexes {
+			var indexColumns []string
+			require.NoError(t, sqliteDB.Raw(`SELECT name FROM pragma_index_info(?) ORDER BY seqno`, index).Scan(&indexColumns).Error)
+			result.Indexes[index] = fmt.Sprintf("%s(%s)", name, strings.Join(indexColumns, ", "))
+		}
+	}
+	return result
+}
+
+func TestSQLiteSchema_MatchesMigrations(t *testing.T) {
+	sqliteDB, err := db.Open(db.SQLiteScheme + ":memory:")
+	require.NoError(t, err)
+	sqlDB, err := sqliteDB.DB()
+	require.NoError(t, err)
+	defer sqlDB.Close()
+
+	// Assert the SQLite schema has the tables, columns, keys and indexes the migrations build
+	expected := migratedSchema(t, filepath.Join("..", "..", "..", "db", "migrations"))
+	actual := sqliteSchema(t, sqliteDB)
+	for name, table := range expected.Tables {
+		assert.Equal(t, table, actual.Tables[name], name)
+	}
+	assert.ElementsMatch(t, keys(expected.Tables), keys(actual.Tables))
+	assert.Equal(t, expected.Indexes, actual.Indexes)
+}
+
+// keys returns the names of tables.
+func keys(tables map[string]*schemaTable) []string {
+	names := make([]string, 0, len(tables))
+	for name := range tables {
+		names = append(names, name)
+	}
+	return names
+}
@@ -0,0 +1,50 @@
+-- Schema of a SQLite database, matching the PostgreSQL schema built by db/migrations, as checked by
+-- TestSQLiteSchema_MatchesMigrations.
+-- Amounts are stored as text so that every digit is kept, and are added up by the application.
+
+CREATE TABLE IF NOT EXISTS transactions(
+    id INTEGER PRIMARY KEY,
+    amount TEXT NOT NULL,
+    type VARCHAR(50) NOT NULL,
+    parent_id BIGINT,
+    currency CHAR(3) NOT NULL DEFAULT 'USD',
+    occurred_at DATETIME,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_by VARCHAR(255),
+    FOREIGN KEY (parent_id) REFERENCES transactions(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_transaction_id ON transactions (id);
+
+CREATE INDEX IF NOT EXISTS idx_transaction_type ON transactions (type);
+
+CREATE INDEX IF NOT EXISTS idx_transaction_id_parent_id ON transactions (id, parent_id);
+
+CREATE INDEX IF NOT EXISTS idx_transaction_parent_id_currency ON transactions (parent_id, currency);
+
+CREATE INDEX IF NOT EXISTS idx_transaction_type_id ON transactions (type, id);
+
+CREATE TABLE IF NOT EXISTS transaction_descendant_sums(
+    transaction_id BIGINT NOT NULL,
+    currency CHAR(3) NOT NULL,
+    amount TEXT NOT NULL,
+    transaction_count BIGINT NOT NULL,
+    PRIMARY KEY (transaction_id, currency),
+    FOREIGN KEY (transaction_id) REFERENCES transactions(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS transaction_closure(
+    ancestor_id BIGINT NOT NULL,
+    descendant_id BIGINT NOT NULL,
+    depth INT NOT NULL,
+    PRIMARY KEY (ancestor_id, descendant_id),
+    FOREIGN KEY (ancestor_id) REFERENCES transactions(id) ON DELETE CASCADE,
+    FOREIGN KEY (descendant_id) REFERENCES transactions(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_transaction_closure_descendant_id_depth ON transaction_closure (descendant_id, depth);
+
+CREATE TABLE IF NOT EXISTS transaction_types(
+    name VARCHAR(50) PRIMARY KEY
+);
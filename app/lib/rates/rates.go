@@ -0,0 +1,92 @@
+package rates
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"transaction_system/app/lib/apperror"
+	"transaction_system/app/lib/currency"
+
+	"github.com/shopspring/decimal"
+)
+
+var ErrRateNotFound = apperror.New("rate_not_found", http.StatusUnprocessableEntity, "exchange rate not available for currency")
+
+// Provider converts amounts between currencies.
+type Provider interface {
+	// Convert converts amount from one currency to another.
+	Convert(amount decimal.Decimal, from, to string) (decimal.Decimal, error)
+}
+
+// staticProvider converts amounts using a fixed table of rates relative to a base currency.
+type staticProvider struct {
+	base  string
+	rates map[string]decimal.Decimal
+}
+
+// staticRatesFile is the on-disk format of a static rates file, e.g.
+//
+//	{"base": "USD", "rates": {"EUR": 0.92, "GBP": 0.79}}
+//
+// where each rate is the number of units of that currency per unit of the base currency.
+type staticRatesFile struct {
+	Base  string                     `json:"base"`
+	Rates map[string]decimal.Decimal `json:"rates"`
+}
+
+// NewStaticProvider builds a Provider from a fixed set of rates relative to base.
+func NewStaticProvider(base string, rates map[string]decimal.Decimal) Provider {
+	table := make(map[string]decimal.Decimal, len(rates)+1)
+	for code, rate := range rates {
+		table[code] = rate
+	}
+	table[base] = decimal.NewFromInt(1)
+	return &staticProvider{base: base, rates: table}
+}
+
+// LoadStaticProvider reads a static rates file from path. Currencies must be ISO 4217 codes in canonical
+// form, as transaction currencies are, and rates must be positive.
+func LoadStaticProvider(path string) (Provider, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file staticRatesFile
+	if err := json.Unmarshal(content, &file); err != nil {
+		return nil, fmt.Errorf("error decoding rates file %s: %w", path, err)
+	}
+	if file.Base == "" {
+		return nil, fmt.Errorf("rates file %s does not define a base currency", path)
+	}
+	if !currency.IsValid(file.Base) {
+		return nil, fmt.Errorf("rates file %s has a base currency %q that is not an ISO 4217 code", path, file.Base)
+	}
+	for code, rate := range file.Rates {
+		if !currency.IsValid(code) {
+			return nil, fmt.Errorf("rates file %s has a rate for %q, which is not an ISO 4217 code", path, code)
+		}
+		if !rate.IsPositive() {
+			return nil, fmt.Errorf("rates file %s has a non-positive rate for %s", path, code)
+		}
+	}
+
+	return NewStaticProvider(file.Base, file.Rates), nil
+}
+
+// Convert converts amount from one currency to another through the base currency.
+func (s *staticProvider) Convert(amount decimal.Decimal, from, to string) (decimal.Decimal, error) {
+	if from == to {
+		return amount, nil
+	}
+	fromRate, ok := s.rates[from]
+	if !ok {
+		return decimal.Zero, ErrRateNotFound.WithDetails(map[string]interface{}{"currency": from})
+	}
+	toRate, ok := s.rates[to]
+	if !ok {
+		return decimal.Zero, ErrRateNotFound.WithDetails(map[string]interface{}{"currency": to})
+	}
+	return amount.Mul(toRate).Div(fromRate), nil
+}
@@ -0,0 +1,99 @@
+package rates_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"transaction_system/app/lib/apperror"
+	"transaction_system/app/lib/rates"
+)
+
+// writeRates writes content to a rates file and returns its path.
+func writeRates(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "rates.json")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestStaticProvider_Convert(t *testing.T) {
+	provider, err := rates.LoadStaticProvider(writeRates(t, `{"base": "USD", "rates": {"EUR": 0.92, "GBP": 0.79}}`))
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		amount, from, to string
+		expected         string
+	}{
+		{"100", "USD", "EUR", "92"},
+		{"92", "EUR", "USD", "100"},
+		{"92", "EUR", "GBP", "79"},
+		{"12.34", "GBP", "GBP", "12.34"},
+		{"1", "JPY", "JPY", "1"},
+	} {
+		converted, err := provider.Convert(decimal.RequireFromString(tc.amount), tc.from, tc.to)
+
+		// Assert amounts are converted through the base currency
+		require.NoError(t, err, "%s %s to %s", tc.amount, tc.from, tc.to)
+		assert.Equal(t, tc.expected, converted.String(), "%s %s to %s", tc.amount, tc.from, tc.to)
+	}
+}
+
+func TestStaticProvider_MissingRate(t *testing.T) {
+	provider := rates.NewStaticProvider("USD", map[string]decimal.Decimal{"EUR": decimal.RequireFromString("0.92")})
+
+	for _, tc := range []struct {
+		from, to string
+		missing  string
+	}{
+		{"JPY", "USD", "JPY"},
+		{"EUR", "JPY", "JPY"},
+		{"CHF", "JPY", "CHF"},
+	} {
+		_, err := provider.Convert(decimal.NewFromInt(1), tc.from, tc.to)
+
+		// Assert the error names the currency without a rate
+		var appErr *apperror.Error
+		require.ErrorAs(t, err, &appErr)
+		assert.ErrorIs(t, err, rates.ErrRateNotFound)
+		assert.Equal(t, map[string]interface{}{"currency": tc.missing}, appErr.Details, "%s to %s", tc.from, tc.to)
+	}
+}
+
+func TestStaticProvider_Precision(t *testing.T) {
+	provider := rates.NewStaticProvider("USD", map[string]decimal.Decimal{
+		"EUR": decimal.RequireFromString("0.92"),
+		"JPY": decimal.RequireFromString("149.123456789"),
+	})
+
+	// Assert rates with many decimal places are applied exactly
+	converted, err := provider.Convert(decimal.RequireFromString("0.01"), "USD", "JPY")
+	require.NoError(t, err)
+	assert.Equal(t, "1.49123456789", converted.String())
+
+	// Assert conversions that do not terminate are rounded to the division precision
+	converted, err = provider.Convert(decimal.NewFromInt(100), "EUR", "USD")
+	require.NoError(t, err)
+	assert.Equal(t, "108.6956521739130435", converted.String())
+}
+
+func TestLoadStaticProvider_Invalid(t *testing.T) {
+	for _, content := range []string{
+		`{"rates": {"EUR": 0.92}}`,
+		`{"base": "usd", "rates": {"EUR": 0.92}}`,
+		`{"base": "DOLLAR", "rates": {"EUR": 0.92}}`,
+		`{"base": "USD", "rates": {"eur": 0.92}}`,
+		`{"base": "USD", "rates": {"XYZ": 0.92}}`,
+		`{"base": "USD", "rates": {"EUR": 0}}`,
+		`{"base": "USD", "rates": {"EUR": -0.92}}`,
+		`{"base": "USD", "rates": []}`,
+	} {
+		_, err := rates.LoadStaticProvider(writeRates(t, content))
+
+		// Assert unknown currencies and unusable rates are refused when loading
+		assert.Error(t, err, content)
+	}
+}
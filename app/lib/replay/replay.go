@@ -0,0 +1,382 @@
+// Package replay sends a JSONL capture of HTTP requests to the service and compares the responses with the
+// recorded ones.
+//
+// Each line of a capture is a JSON object such as
+//
+//	{"method": "PUT", "path": "/transactionservice/transaction/1", "headers": {"Content-Type": "application/json"},
+//	 "body": {"amount": 100, "type": "purchase"}, "response": {"status": 201, "body": {"status": "ok"}}}
+//
+// A body holding a JSON string is sent as is, any other JSON value is sent encoded. The response is optional;
+// without one the request is replayed but not compared. Lines without a method and path are skipped.
+package replay
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Entry is a captured request together with the response it received.
+type Entry struct {
+	// Line is the line of the capture the entry was read from.
+	Line     int               `json:"-"`
+	Method   string            `json:"method"`
+	Path     string            `json:"path"`
+	Headers  map[string]string `json:"headers"`
+	Body     json.RawMessage   `json:"body"`
+	Response *Response         `json:"response"`
+}
+
+// Response is a recorded response.
+type Response struct {
+	Status int             `json:"status"`
+	Body   json.RawMessage `json:"body"`
+}
+
+// requestBody returns the bytes to send as the body of the entry's request.
+func (e Entry) requestBody() []byte {
+	if len(e.Body) == 0 || string(e.Body) == "null" {
+		return nil
+	}
+	var raw string
+	if err := json.Unmarshal(e.Body, &raw); err == nil {
+		return []byte(raw)
+	}
+	return e.Body
+}
+
+// Load reads the entries of a capture, also returning the number of lines that are not requests.
+func Load(r io.Reader) ([]Entry, int, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	var entries []Entry
+	skipped := 0
+	line := 0
+	for scanner.Scan() {
+		line++
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+
+		var entry Entry
+		if err := json.Unmarshal(data, &entry); err != nil {
+			return nil, 0, fmt.Errorf("line %d: %w", line, err)
+		}
+		if entry.Method == "" || entry.Path == "" {
+			skipped++
+			continue
+		}
+		entry.Line = line
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, 0, err
+	}
+	return entries, skipped, nil
+}
+
+// Target sends a replayed request and returns the response status and body.
+type Target interface {
+	Do(ctx context.Context, req *http.Request) (int, []byte, error)
+}
+
+// HandlerTarget serves replayed requests in process.
+type HandlerTarget struct {
+	Handler http.Handler
+}
+
+func (t HandlerTarget) Do(ctx context.Context, req *http.Request) (int, []byte, error) {
+	recorder := httptest.NewRecorder()
+	t.Handler.ServeHTTP(recorder, req.WithContext(ctx))
+	return recorder.Code, recorder.Body.Bytes(), nil
+}
+
+// URLTarget sends replayed requests to a running instance.
+type URLTarget struct {
+	BaseURL string
+	Client  *http.Client
+}
+
+func (t URLTarget) Do(ctx context.Context, req *http.Request) (int, []byte, error) {
+	outgoing, err := http.NewRequestWithContext(ctx, req.Method, strings.TrimSuffix(t.BaseURL, "/")+req.URL.RequestURI(), req.Body)
+	if err != nil {
+		return 0, nil, err
+	}
+	outgoing.Header = req.Header
+
+	client := t.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(outgoing)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, nil, err
+	}
+	return resp.StatusCode, body, nil
+}
+
+// Outcome is the result of replaying one entry.
+type Outcome string
+
+const (
+	// Matched means the response matched the recorded one.
+	Matched Outcome = "matched"
+	// Mismatched means the response differed from the recorded one, Diffs telling how.
+	Mismatched Outcome = "mismatched"
+	// Unrecorded means the entry had no recorded response to compare with.
+	Unrecorded Outcome = "unrecorded"
+	// Failed means the request could not be sent, Err telling why.
+	Failed Outcome = "failed"
+)
+
+// Result is the result of replaying one entry.
+type Result struct {
+	Entry   Entry
+	Outcome Outcome
+	Status  int
+	Diffs   []string
+	Err     error
+	Latency time.Duration
+}
+
+// Summary aggregates the results of a replay.
+type Summary struct {
+	Total      int
+	Outcomes   map[Outcome]int
+	Duration   time.Duration
+	MaxLatency time.Duration
+	latencies  time.Duration
+}
+
+// MeanLatency returns the mean time taken by a replayed request.
+func (s Summary) MeanLatency() time.Duration {
+	if s.Total == 0 {
+		return 0
+	}
+	return s.latencies / time.Duration(s.Total)
+}
+
+// Replayer replays capture entries against a Target one at a time.
+type Replayer struct {
+	Target Target
+	// Rate bounds the number of requests sent per second, zero meaning no bound.
+	Rate float64
+	// Ignore lists dot-separated body fields, such as "transaction.id", left out of the comparison.
+	Ignore []string
+}
+
+// Run replays entries in order, calling report with the result of each, and returns a summary. It stops
+// early, returning the summary so far, when ctx is done.
+func (r *Replayer) Run(ctx context.Context, entries []Entry, report func(Result)) Summary {
+	summary := Summary{Outcomes: make(map[Outcome]int)}
+	start := time.Now()
+
+	var ticker *time.Ticker
+	if r.Rate > 0 {
+		ticker = time.NewTicker(time.Duration(float64(time.Second) / r.Rate))
+		defer ticker.Stop()
+	}
+
+	for i, entry := range entries {
+		if ticker != nil && i > 0 {
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+			}
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		result := r.replay(ctx, entry)
+		summary.Total++
+		summary.Outcomes[result.Outcome]++
+		summary.latencies += result.Latency
+		if result.Latency > summary.MaxLatency {
+			summary.MaxLatency = result.Latency
+		}
+		if report != nil {
+			report(result)
+		}
+	}
+
+	summary.Duration = time.Since(start)
+	return summary
+}
+
+func (r *Replayer) replay(ctx context.Context, entry Entry) Result {
+	result := Result{Entry: entry}
+
+	req, err := http.NewRequest(entry.Method, entry.Path, bytes.NewReader(entry.requestBody()))
+	if err != nil {
+		result.Outcome = Failed
+		result.Err = err
+		return result
+	}
+	for name, value := range entry.Headers {
+		req.Header.Set(name, value)
+	}
+
+	start := time.Now()
+	status, body, err := r.Target.Do(ctx, req)
+	result.Latency = time.Since(start)
+	if err != nil {
+		result.Outcome = Failed
+		result.Err = err
+		return result
+	}
+	result.Status = status
+
+	if entry.Response == nil {
+		result.Outcome = Unrecorded
+		return result
+	}
+	result.Diffs = Diff(*entry.Response, status, body, r.Ignore)
+	result.Outcome = Matched
+	if len(result.Diffs) > 0 {
+		result.Outcome = Mismatched
+	}
+	return result
+}
+
+// Diff compares a response with the recorded one, describing each difference. JSON bodies are compared
+// structurally, numbers by value, and other bodies as text with surrounding whitespace trimmed.
+func Diff(recorded Response, status int, body []byte, ignore []string) []string {
+	var diffs []string
+	if recorded.Status != 0 && recorded.Status != status {
+		diffs = append(diffs, fmt.Sprintf("status: recorded %d, got %d", recorded.Status, status))
+	}
+	if len(recorded.Body) == 0 {
+		return diffs
+	}
+
+	// A recorded JSON string holds a non-JSON body, unless the response body is that same JSON string
+	recordedValue, recordedIsJSON := decodeJSON(recorded.Body)
+	if text, isString := recordedValue.(string); recordedIsJSON && isString {
+		if _, isJSON := decodeJSON(body); !isJSON {
+			if strings.TrimSpace(text) != strings.TrimSpace(string(body)) {
+				diffs = append(diffs, fmt.Sprintf("body: recorded %q, got %q", strings.TrimSpace(text), strings.TrimSpace(string(body))))
+			}
+			return diffs
+		}
+	}
+
+	actualValue, actualIsJSON := decodeJSON(body)
+	if !actualIsJSON {
+		return append(diffs, fmt.Sprintf("body: recorded %s, got non-JSON %q", recorded.Body, strings.TrimSpace(string(body))))
+	}
+
+	ignored := make(map[string]bool, len(ignore))
+	for _, path := range ignore {
+		ignored["body."+path] = true
+	}
+	return append(diffs, diffValues("body", recordedValue, actualValue, ignored)...)
+}
+
+func decodeJSON(data []byte) (interface{}, bool) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, false
+	}
+	if decoder.More() {
+		return nil, false
+	}
+	return value, true
+}
+
+func diffValues(path string, recorded, actual interface{}, ignored map[string]bool) []string {
+	if ignored[path] {
+		return nil
+	}
+
+	switch recordedValue := recorded.(type) {
+	case map[string]interface{}:
+		actualValue, ok := actual.(map[string]interface{})
+		if !ok {
+			break
+		}
+		keys := make([]string, 0, len(recordedValue)+len(actualValue))
+		for key := range recordedValue {
+			keys = append(keys, key)
+		}
+		for key := range actualValue {
+			if _, exists := recordedValue[key]; !exists {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+
+		var diffs []string
+		for _, key := range keys {
+			fieldPath := path + "." + key
+			recordedField, recordedExists := recordedValue[key]
+			actualField, actualExists := actualValue[key]
+			switch {
+			case ignored[fieldPath]:
+			case !recordedExists:
+				diffs = append(diffs, fmt.Sprintf("%s: not recorded, got %s", fieldPath, encode(actualField)))
+			case !actualExists:
+				diffs = append(diffs, fmt.Sprintf("%s: recorded %s, missing", fieldPath, encode(recordedField)))
+			default:
+				diffs = append(diffs, diffValues(fieldPath, recordedField, actualField, ignored)...)
+			}
+		}
+		return diffs
+	case []interface{}:
+		actualValue, ok := actual.([]interface{})
+		if !ok {
+			break
+		}
+		if len(recordedValue) != len(actualValue) {
+			return []string{fmt.Sprintf("%s: recorded %d elements, got %d", path, len(recordedValue), len(actualValue))}
+		}
+		var diffs []string
+		for i := range recordedValue {
+			diffs = append(diffs, diffValues(fmt.Sprintf("%s[%d]", path, i), recordedValue[i], actualValue[i], ignored)...)
+		}
+		return diffs
+	case json.Number:
+		actualValue, ok := actual.(json.Number)
+		if !ok {
+			break
+		}
+		recordedNumber, recordedErr := decimal.NewFromString(recordedValue.String())
+		actualNumber, actualErr := decimal.NewFromString(actualValue.String())
+		if recordedErr == nil && actualErr == nil && recordedNumber.Equal(actualNumber) {
+			return nil
+		}
+	default:
+		if recorded == actual {
+			return nil
+		}
+	}
+	return []string{fmt.Sprintf("%s: recorded %s, got %s", path, encode(recorded), encode(actual))}
+}
+
+func encode(value interface{}) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
@@ -0,0 +1,95 @@
+package replay_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"transaction_system/app/lib/replay"
+)
+
+func TestLoad(t *testing.T) {
+	capture := `{"method": "GET", "path": "/health-check", "response": {"status": 200, "body": "Hi"}}
+
+{"request_id": "user-001", "title": "not a request"}
+{"method": "PUT", "path": "/transactionservice/transaction/1", "body": {"amount": 100, "type": "purchase"}}
+`
+
+	entries, skipped, err := replay.Load(strings.NewReader(capture))
+
+	// Assert requests keep their line numbers and other lines are skipped
+	assert.NoError(t, err)
+	assert.Equal(t, 1, skipped)
+	assert.Len(t, entries, 2)
+	assert.Equal(t, 1, entries[0].Line)
+	assert.Equal(t, 4, entries[1].Line)
+	assert.Nil(t, entries[1].Response)
+}
+
+func TestDiff(t *testing.T) {
+	recorded := replay.Response{
+		Status: 200,
+		Body:   []byte(`{"status": "ok", "transaction": {"id": 1, "amount": 100.0, "type": "purchase"}}`),
+	}
+
+	t.Run("when the response matches", func(t *testing.T) {
+		diffs := replay.Diff(recorded, 200, []byte(`{"transaction":{"type":"purchase","amount":100,"id":1},"status":"ok"}`), nil)
+
+		// Assert key order and number formatting are not differences
+		assert.Empty(t, diffs)
+	})
+
+	t.Run("when the response differs", func(t *testing.T) {
+		diffs := replay.Diff(recorded, 201, []byte(`{"status":"ok","transaction":{"id":2,"amount":100,"currency":"USD"}}`), []string{"transaction.id"})
+
+		// Assert every difference but the ignored field is reported
+		assert.Equal(t, []string{
+			"status: recorded 200, got 201",
+			`body.transaction.currency: not recorded, got "USD"`,
+			`body.transaction.type: recorded "purchase", missing`,
+		}, diffs)
+	})
+
+	t.Run("when the body is not JSON", func(t *testing.T) {
+		diffs := replay.Diff(replay.Response{Body: []byte(`"Welcome to transaction system"`)}, 200, []byte("Welcome to transaction system"), nil)
+
+		// Assert the recorded string is compared as text
+		assert.Empty(t, diffs)
+	})
+}
+
+func TestReplayer_Run(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method == http.MethodPut {
+			w.WriteHeader(http.StatusCreated)
+		}
+		w.Write([]byte(`{"echo":` + string(body) + `}`))
+	})
+
+	capture := `{"method": "PUT", "path": "/a", "body": {"amount": 1}, "response": {"status": 201, "body": {"echo": {"amount": 1}}}}
+{"method": "PUT", "path": "/b", "body": {"amount": 2}, "response": {"status": 201, "body": {"echo": {"amount": 3}}}}
+{"method": "GET", "path": "/c", "body": "null"}
+`
+	entries, _, err := replay.Load(strings.NewReader(capture))
+	assert.NoError(t, err)
+
+	var results []replay.Result
+	replayer := &replay.Replayer{Target: replay.HandlerTarget{Handler: handler}, Rate: 1000}
+	summary := replayer.Run(context.Background(), entries, func(result replay.Result) {
+		results = append(results, result)
+	})
+
+	// Assert each request is compared with its recording
+	assert.Equal(t, 3, summary.Total)
+	assert.Equal(t, replay.Matched, results[0].Outcome)
+	assert.Equal(t, replay.Mismatched, results[1].Outcome)
+	assert.Equal(t, []string{"body.echo.amount: recorded 3, got 2"}, results[1].Diffs)
+	assert.Equal(t, replay.Unrecorded, results[2].Outcome)
+	assert.Equal(t, map[replay.Outcome]int{replay.Matched: 1, replay.Mismatched: 1, replay.Unrecorded: 1}, summary.Outcomes)
+}
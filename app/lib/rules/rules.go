@@ -0,0 +1,179 @@
+// Package rules checks transactions against per-type constraints on their parent, amount and children.
+package rules
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"transaction_system/app/lib/apperror"
+	"transaction_system/app/models"
+)
+
+// ErrRuleViolation is returned when a transaction breaks the rules of its type or of its parent's type. Its
+// "violations" detail lists every broken rule as Violation values.
+var ErrRuleViolation = apperror.New("rule_violation", http.StatusUnprocessableEntity, "transaction breaks the rules of its type")
+
+// AmountSign constrains the sign of the amount of a transaction.
+type AmountSign string
+
+const (
+	AmountSignPositive    AmountSign = "positive"
+	AmountSignNegative    AmountSign = "negative"
+	AmountSignNonNegative AmountSign = "non_negative"
+	AmountSignNonPositive AmountSign = "non_positive"
+)
+
+// TypeRules constrains the transactions of one type. Zero values leave the transactions unconstrained.
+type TypeRules struct {
+	// ParentTypes lists the types the parent of the transaction may have.
+	ParentTypes []string `json:"parent_types"`
+	// RequireParent forbids the transaction from being a root.
+	RequireParent bool `json:"require_parent"`
+	// RequireRoot forbids the transaction from having a parent.
+	RequireRoot bool `json:"require_root"`
+	// AmountSign constrains the sign of the amount of the transaction.
+	AmountSign AmountSign `json:"amount_sign"`
+	// MaxChildren bounds the number of direct children of the transaction.
+	MaxChildren *int `json:"max_children"`
+}
+
+// RuleSet holds the rules of every constrained transaction type. A rules file holds a RuleSet, e.g.
+//
+//	{"types": {
+//	    "refund": {"parent_types": ["purchase"], "require_parent": true, "amount_sign": "negative", "max_children": 0},
+//	    "fee": {"require_parent": true},
+//	    "purchase": {"amount_sign": "positive"}
+//	}}
+//
+// Types are matched exactly, so they must be written in the form transactions are stored under.
+type RuleSet struct {
+	Types map[string]TypeRules `json:"types"`
+}
+
+// Violation describes a rule broken by a transaction.
+type Violation struct {
+	// Rule is the name of the TypeRules field that is broken, e.g. "parent_types".
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// Load reads a rules file from path.
+func Load(path string) (*RuleSet, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var ruleSet RuleSet
+	if err := json.Unmarshal(content, &ruleSet); err != nil {
+		return nil, fmt.Errorf("error decoding rules file %s: %w", path, err)
+	}
+	for transactionType, typeRules := range ruleSet.Types {
+		switch typeRules.AmountSign {
+		case "", AmountSignPositive, AmountSignNegative, AmountSignNonNegative, AmountSignNonPositive:
+		default:
+			return nil, fmt.Errorf("rules file %s has an invalid amount_sign for %s", path, transactionType)
+		}
+		if typeRules.MaxChildren != nil && *typeRules.MaxChildren < 0 {
+			return nil, fmt.Errorf("rules file %s has a negative max_children for %s", path, transactionType)
+		}
+		if typeRules.RequireParent && typeRules.RequireRoot {
+			return nil, fmt.Errorf("rules file %s both requires and forbids a parent for %s", path, transactionType)
+		}
+		if typeRules.RequireRoot && len(typeRules.ParentTypes) > 0 {
+			return nil, fmt.Errorf("rules file %s has parent_types for %s, which requires a root", path, transactionType)
+		}
+	}
+	return &ruleSet, nil
+}
+
+// MaxChildren returns the maximum number of direct children of a transaction of the given type, and
+// whether there is one.
+func (s *RuleSet) MaxChildren(transactionType string) (int, bool) {
+	maxChildren := s.Types[transactionType].MaxChildren
+	if maxChildren == nil {
+		return 0, false
+	}
+	return *maxChildren, true
+}
+
+// Check returns ErrRuleViolation listing every rule broken by a transaction about to be written under
+// parent, which is nil for a root transaction and otherwise already has parentChildren other direct children.
+func (s *RuleSet) Check(transaction models.Transaction, parent *models.Transaction, parentChildren int) error {
+	var violations []Violation
+	typeRules := s.Types[transaction.Type]
+
+	switch {
+	case parent == nil && typeRules.RequireParent:
+		violations = append(violations, Violation{
+			Rule:    "require_parent",
+			Message: fmt.Sprintf("a %s transaction must have a parent", transaction.Type),
+		})
+	case parent != nil && typeRules.RequireRoot:
+		violations = append(violations, Violation{
+			Rule:    "require_root",
+			Message: fmt.Sprintf("a %s transaction cannot have a parent", transaction.Type),
+		})
+	case parent != nil && len(typeRules.ParentTypes) > 0 && !contains(typeRules.ParentTypes, parent.Type):
+		violations = append(violations, Violation{
+			Rule: "parent_types",
+			Message: fmt.Sprintf("a %s transaction cannot have a %s parent, expected one of %s",
+				transaction.Type, parent.Type, strings.Join(sorted(typeRules.ParentTypes), ", ")),
+		})
+	}
+
+	if !hasSign(transaction, typeRules.AmountSign) {
+		violations = append(violations, Violation{
+			Rule:    "amount_sign",
+			Message: fmt.Sprintf("the amount of a %s transaction must be %s", transaction.Type, strings.ReplaceAll(string(typeRules.AmountSign), "_", "-")),
+		})
+	}
+
+	if parent != nil {
+		if maxChildren, ok := s.MaxChildren(parent.Type); ok && parentChildren >= maxChildren {
+			violations = append(violations, Violation{
+				Rule:    "max_children",
+				Message: fmt.Sprintf("a %s transaction cannot have more children than its limit of %d", parent.Type, maxChildren),
+			})
+		}
+	}
+
+	if len(violations) == 0 {
+		return nil
+	}
+	return ErrRuleViolation.WithDetails(map[string]interface{}{"violations": violations})
+}
+
+// hasSign reports whether the amount of transaction has the given sign, which may be empty.
+func hasSign(transaction models.Transaction, sign AmountSign) bool {
+	switch sign {
+	case AmountSignPositive:
+		return transaction.Amount.IsPositive()
+	case AmountSignNegative:
+		return transaction.Amount.IsNegative()
+	case AmountSignNonNegative:
+		return !transaction.Amount.IsNegative()
+	case AmountSignNonPositive:
+		return !transaction.Amount.IsPositive()
+	}
+	return true
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// sorted returns a sorted copy of values.
+func sorted(values []string) []string {
+	copied := append([]string(nil), values...)
+	sort.Strings(copied)
+	return copied
+}
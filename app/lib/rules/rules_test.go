@@ -0,0 +1,89 @@
+package rules_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"transaction_system/app/lib/apperror"
+	"transaction_system/app/lib/rules"
+	"transaction_system/app/models"
+)
+
+// writeRules writes content to a rules file and returns its path.
+func writeRules(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "rules.json")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+// violations returns the violations carried by err.
+func violations(t *testing.T, err error) []rules.Violation {
+	var appErr *apperror.Error
+	if !errors.As(err, &appErr) {
+		t.Fatalf("expected an apperror.Error, got %v", err)
+	}
+	assert.ErrorIs(t, err, rules.ErrRuleViolation)
+	return appErr.Details["violations"].([]rules.Violation)
+}
+
+func TestRuleSet_Check(t *testing.T) {
+	ruleSet, err := rules.Load(writeRules(t, `{"types": {
+		"refund": {"parent_types": ["purchase", "order"], "require_parent": true, "amount_sign": "negative"},
+		"fee": {"require_parent": true, "amount_sign": "non_negative"},
+		"purchase": {"require_root": true, "max_children": 1}
+	}}`))
+	require.NoError(t, err)
+
+	purchase := &models.Transaction{Id: 1, Amount: decimal.NewFromInt(100), Type: "purchase"}
+	fee := &models.Transaction{Id: 2, Amount: decimal.NewFromInt(1), Type: "fee", ParentID: &purchase.Id}
+
+	// Assert transactions following the rules pass, as do types without rules
+	assert.NoError(t, ruleSet.Check(*purchase, nil, 0))
+	assert.NoError(t, ruleSet.Check(models.Transaction{Amount: decimal.NewFromInt(-5), Type: "refund"}, purchase, 0))
+	assert.NoError(t, ruleSet.Check(models.Transaction{Amount: decimal.NewFromInt(-5), Type: "cars"}, nil, 0))
+
+	// Assert every broken rule is reported
+	err = ruleSet.Check(models.Transaction{Amount: decimal.NewFromInt(5), Type: "refund"}, fee, 0)
+	assert.Equal(t, []rules.Violation{
+		{Rule: "parent_types", Message: "a refund transaction cannot have a fee parent, expected one of order, purchase"},
+		{Rule: "amount_sign", Message: "the amount of a refund transaction must be negative"},
+	}, violations(t, err))
+
+	err = ruleSet.Check(models.Transaction{Amount: decimal.NewFromInt(-1), Type: "fee"}, nil, 0)
+	assert.Equal(t, []rules.Violation{
+		{Rule: "require_parent", Message: "a fee transaction must have a parent"},
+		{Rule: "amount_sign", Message: "the amount of a fee transaction must be non-negative"},
+	}, violations(t, err))
+
+	// Assert the children of a parent are bounded by the rules of its type
+	err = ruleSet.Check(*fee, purchase, 1)
+	assert.Equal(t, []rules.Violation{
+		{Rule: "max_children", Message: "a purchase transaction cannot have more children than its limit of 1"},
+	}, violations(t, err))
+
+	err = ruleSet.Check(models.Transaction{Amount: decimal.NewFromInt(1), Type: "purchase"}, fee, 0)
+	assert.Equal(t, []rules.Violation{
+		{Rule: "require_root", Message: "a purchase transaction cannot have a parent"},
+	}, violations(t, err))
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	for _, content := range []string{
+		`{"types": {"refund": {"amount_sign": "up"}}}`,
+		`{"types": {"refund": {"max_children": -1}}}`,
+		`{"types": {"refund": {"require_parent": true, "require_root": true}}}`,
+		`{"types": {"refund": {"require_root": true, "parent_types": ["purchase"]}}}`,
+		`{"types": []}`,
+	} {
+		_, err := rules.Load(writeRules(t, content))
+
+		// Assert inconsistent rules are refused when loading
+		assert.Error(t, err, content)
+	}
+}
@@ -0,0 +1,78 @@
+package transfer
+
+import (
+	"fmt"
+	"transaction_system/app/models"
+)
+
+// CycleError reports transactions whose parent references form a cycle, so that no order places every
+// parent before its children.
+type CycleError struct {
+	TransactionIDs []uint
+}
+
+func (e *CycleError) Error() string {
+	return fmt.Sprintf("transactions %v reference each other as parents", e.TransactionIDs)
+}
+
+// visit states used by Order
+const (
+	unvisited = iota
+	visiting
+	visited
+)
+
+// Order returns the indexes of transactions arranged so that every transaction comes after its parent
+// when the parent is part of transactions too. Transactions keep their relative input order otherwise.
+// When an ID appears more than once, its first occurrence is the one children are ordered after.
+func Order(transactions []models.Transaction) ([]int, error) {
+	indexByID := make(map[uint]int, len(transactions))
+	for i, transaction := range transactions {
+		if _, exists := indexByID[transaction.Id]; !exists {
+			indexByID[transaction.Id] = i
+		}
+	}
+
+	parentIndex := func(i int) (int, bool) {
+		if transactions[i].ParentID == nil {
+			return 0, false
+		}
+		index, ok := indexByID[*transactions[i].ParentID]
+		return index, ok
+	}
+
+	order := make([]int, 0, len(transactions))
+	state := make([]int, len(transactions))
+	for i := range transactions {
+		// Walk up the parent chain until an ordered transaction or one outside of the input is reached,
+		// then emit the chain from the top down
+		var chain []int
+		for current, ok := i, true; ok && state[current] != visited; current, ok = parentIndex(current) {
+			if state[current] == visiting {
+				return nil, cycleError(transactions, chain, current)
+			}
+			state[current] = visiting
+			chain = append(chain, current)
+		}
+		for j := len(chain) - 1; j >= 0; j-- {
+			state[chain[j]] = visited
+			order = append(order, chain[j])
+		}
+	}
+	return order, nil
+}
+
+// cycleError builds the CycleError for a parent chain that leads back to start.
+func cycleError(transactions []models.Transaction, chain []int, start int) error {
+	cycle := &CycleError{}
+	found := false
+	for _, index := range chain {
+		if index == start {
+			found = true
+		}
+		if found {
+			cycle.TransactionIDs = append(cycle.TransactionIDs, transactions[index].Id)
+		}
+	}
+	return cycle
+}
@@ -0,0 +1,309 @@
+// Package transfer reads and writes transactions as CSV or newline-delimited JSON for bulk import and export.
+package transfer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"transaction_system/app/lib/currency"
+	"transaction_system/app/lib/validation"
+	"transaction_system/app/models"
+
+	"github.com/shopspring/decimal"
+)
+
+var ErrUnknownFormat = errors.New("format must be 'csv' or 'ndjson'")
+
+// Format is an encoding of a list of transactions.
+type Format string
+
+const (
+	// CSV encodes one transaction per row under an id,amount,type,parent_id,currency,occurred_at,created_at,
+	// updated_at,created_by header. Timestamps are written in RFC 3339.
+	CSV Format = "csv"
+	// NDJSON encodes one transaction per line as a JSON object.
+	NDJSON Format = "ndjson"
+)
+
+// columns are the CSV columns written on export, in order.
+var columns = []string{"id", "amount", "type", "parent_id", "currency", "occurred_at", "created_at", "updated_at", "created_by"}
+
+// ParseFormat returns the Format named by name, accepting "jsonl" as an alias of NDJSON.
+func ParseFormat(name string) (Format, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "csv":
+		return CSV, nil
+	case "ndjson", "jsonl":
+		return NDJSON, nil
+	}
+	return "", ErrUnknownFormat
+}
+
+// ContentType returns the MIME type of format.
+func (f Format) ContentType() string {
+	if f == CSV {
+		return "text/csv"
+	}
+	return "application/x-ndjson"
+}
+
+// Record is a decoded transaction together with the line of the input it was read from.
+type Record struct {
+	Line        int
+	Transaction models.Transaction
+}
+
+// LineError reports a line of the input that could not be decoded.
+type LineError struct {
+	Line int
+	Err  error
+}
+
+func (e *LineError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e *LineError) Unwrap() error {
+	return e.Err
+}
+
+// Decode reads every transaction of r, which is encoded in format. Currencies are normalized but left
+// empty when absent, so that the default currency is applied on creation.
+func Decode(format Format, r io.Reader) ([]Record, error) {
+	switch format {
+	case CSV:
+		return decodeCSV(r)
+	case NDJSON:
+		return decodeNDJSON(r)
+	}
+	return nil, ErrUnknownFormat
+}
+
+func decodeCSV(r io.Reader) ([]Record, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return []Record{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Map column names to their position, so that columns may come in any order
+	positions := make(map[string]int, len(header))
+	for i, name := range header {
+		positions[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range []string{"id", "amount", "type"} {
+		if _, ok := positions[name]; !ok {
+			return nil, &LineError{Line: 1, Err: fmt.Errorf("column '%s' is missing", name)}
+		}
+	}
+	field := func(row []string, name string) string {
+		if i, ok := positions[name]; ok && i < len(row) {
+			return strings.TrimSpace(row[i])
+		}
+		return ""
+	}
+
+	records := []Record{}
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+
+		transaction, err := transactionFromFields(field(row, "id"), field(row, "amount"), field(row, "type"), field(row, "parent_id"), field(row, "currency"), field(row, "occurred_at"))
+		if err != nil {
+			return nil, &LineError{Line: line, Err: err}
+		}
+		records = append(records, Record{Line: line, Transaction: transaction})
+	}
+}
+
+// transactionFromFields parses the textual fields of a CSV row into a transaction. The audit columns of an
+// export are not read back, as they are set when the transaction is stored.
+func transactionFromFields(id, amount, transactionType, parentID, currencyCode, occurredAt string) (models.Transaction, error) {
+	transactionID, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return models.Transaction{}, errors.New("invalid id")
+	}
+	transactionAmount, err := decimal.NewFromString(amount)
+	if err != nil {
+		return models.Transaction{}, errors.New("invalid amount")
+	}
+	transaction := models.Transaction{
+		Id:     uint(transactionID),
+		Amount: transactionAmount,
+		Type:   transactionType,
+	}
+	if parentID != "" {
+		parentIDValue, err := strconv.ParseUint(parentID, 10, 64)
+		if err != nil {
+			return models.Transaction{}, errors.New("invalid parent_id")
+		}
+		parent := uint(parentIDValue)
+		transaction.ParentID = &parent
+	}
+	if occurredAt != "" {
+		occurredAtValue, err := time.Parse(time.RFC3339, occurredAt)
+		if err != nil {
+			return models.Transaction{}, errors.New("invalid occurred_at")
+		}
+		transaction.OccurredAt = &occurredAtValue
+	}
+	if err := validation.Struct(&transaction); err != nil {
+		return models.Transaction{}, err
+	}
+	if err := setCurrency(&transaction, currencyCode); err != nil {
+		return models.Transaction{}, err
+	}
+	return transaction, nil
+}
+
+func decodeNDJSON(r io.Reader) ([]Record, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	records := []Record{}
+	line := 0
+	for scanner.Scan() {
+		line++
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+
+		// The audit fields of an export are accepted, and replaced when the transaction is stored
+		var transaction models.Transaction
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&transaction); err != nil {
+			return nil, &LineError{Line: line, Err: err}
+		}
+		if err := validation.Struct(&transaction); err != nil {
+			return nil, &LineError{Line: line, Err: err}
+		}
+		if err := setCurrency(&transaction, transaction.Currency); err != nil {
+			return nil, &LineError{Line: line, Err: err}
+		}
+		records = append(records, Record{Line: line, Transaction: transaction})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// setCurrency normalizes and validates currencyCode before storing it on transaction.
+func setCurrency(transaction *models.Transaction, currencyCode string) error {
+	if currencyCode == "" {
+		transaction.Currency = ""
+		return nil
+	}
+	transaction.Currency = currency.Normalize(currencyCode)
+	if !currency.IsValid(transaction.Currency) {
+		return errors.New("invalid currency")
+	}
+	return nil
+}
+
+// Encoder writes transactions to an output in a given format.
+type Encoder interface {
+	Encode(transaction models.Transaction) error
+	// Flush writes any buffered data to the underlying output.
+	Flush() error
+}
+
+// NewEncoder returns an Encoder writing transactions to w in format. The CSV header is written with the
+// first transaction, or on Flush when there is none.
+func NewEncoder(format Format, w io.Writer) (Encoder, error) {
+	switch format {
+	case CSV:
+		return &csvEncoder{writer: csv.NewWriter(w)}, nil
+	case NDJSON:
+		writer := bufio.NewWriter(w)
+		return &ndjsonEncoder{writer: writer, encoder: json.NewEncoder(writer)}, nil
+	}
+	return nil, ErrUnknownFormat
+}
+
+type csvEncoder struct {
+	writer        *csv.Writer
+	headerWritten bool
+}
+
+func (e *csvEncoder) writeHeader() error {
+	if e.headerWritten {
+		return nil
+	}
+	e.headerWritten = true
+	return e.writer.Write(columns)
+}
+
+func (e *csvEncoder) Encode(transaction models.Transaction) error {
+	if err := e.writeHeader(); err != nil {
+		return err
+	}
+	parentID := ""
+	if transaction.ParentID != nil {
+		parentID = strconv.FormatUint(uint64(*transaction.ParentID), 10)
+	}
+	createdBy := ""
+	if transaction.CreatedBy != nil {
+		createdBy = *transaction.CreatedBy
+	}
+	return e.writer.Write([]string{
+		strconv.FormatUint(uint64(transaction.Id), 10),
+		transaction.Amount.String(),
+		transaction.Type,
+		parentID,
+		transaction.Currency,
+		formatTime(transaction.OccurredAt),
+		formatTime(transaction.CreatedAt),
+		formatTime(transaction.UpdatedAt),
+		createdBy,
+	})
+}
+
+// formatTime formats an optional timestamp as an RFC 3339 CSV field.
+func formatTime(value *time.Time) string {
+	if value == nil {
+		return ""
+	}
+	return value.Format(time.RFC3339Nano)
+}
+
+func (e *csvEncoder) Flush() error {
+	if err := e.writeHeader(); err != nil {
+		return err
+	}
+	e.writer.Flush()
+	return e.writer.Error()
+}
+
+type ndjsonEncoder struct {
+	writer  *bufio.Writer
+	encoder *json.Encoder
+}
+
+func (e *ndjsonEncoder) Encode(transaction models.Transaction) error {
+	return e.encoder.Encode(transaction)
+}
+
+func (e *ndjsonEncoder) Flush() error {
+	return e.writer.Flush()
+}
@@ -0,0 +1,133 @@
+package transfer_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+
+	"transaction_system/app/lib/transfer"
+	"transaction_system/app/models"
+)
+
+func TestDecodeCSV(t *testing.T) {
+	input := "type,id,amount,parent_id,currency\npurchase,2,10.50,1,eur\npurchase,1,100,,\n"
+
+	records, err := transfer.Decode(transfer.CSV, strings.NewReader(input))
+
+	// Assert columns are matched by name and rows keep their line numbers
+	assert.NoError(t, err)
+	assert.Len(t, records, 2)
+	assert.Equal(t, 2, records[0].Line)
+	assert.Equal(t, uint(2), records[0].Transaction.Id)
+	assert.True(t, decimal.RequireFromString("10.50").Equal(records[0].Transaction.Amount))
+	assert.Equal(t, uint(1), *records[0].Transaction.ParentID)
+	assert.Equal(t, "EUR", records[0].Transaction.Currency)
+	assert.Nil(t, records[1].Transaction.ParentID)
+	assert.Equal(t, "", records[1].Transaction.Currency)
+}
+
+func TestDecodeNDJSON_InvalidLine(t *testing.T) {
+	input := "{\"id\": 1, \"amount\": 100, \"type\": \"purchase\"}\n\n{\"id\": 2, \"amount\": \"abc\", \"type\": \"purchase\"}\n"
+
+	_, err := transfer.Decode(transfer.NDJSON, strings.NewReader(input))
+
+	// Assert the error names the offending line
+	var lineErr *transfer.LineError
+	assert.ErrorAs(t, err, &lineErr)
+	assert.Equal(t, 3, lineErr.Line)
+}
+
+func TestDecode_IDOutOfRange(t *testing.T) {
+	for _, tc := range []struct {
+		format transfer.Format
+		input  string
+	}{
+		{transfer.NDJSON, "{\"id\": 1, \"amount\": 100, \"type\": \"purchase\", \"parent_id\": 9223372036854775808}\n"},
+		{transfer.NDJSON, "{\"id\": 1, \"amount\": 100, \"type\": \"purchase\", \"parent_id\": 3.7}\n"},
+		{transfer.CSV, "id,amount,type,parent_id\n9223372036854775808,100,purchase,\n"},
+		{transfer.CSV, "id,amount,type,parent_id\n1,100,purchase,-1\n"},
+		{transfer.CSV, "id,amount,type,parent_id\n1,100,purchase,9223372036854775808\n"},
+	} {
+		_, err := transfer.Decode(tc.format, strings.NewReader(tc.input))
+
+		// Assert IDs that are not integers within the BIGINT range are rejected
+		var lineErr *transfer.LineError
+		assert.ErrorAs(t, err, &lineErr, tc.input)
+	}
+}
+
+func TestEncodeDecode_RoundTrip(t *testing.T) {
+	parentID := uint(1)
+	occurredAt := time.Date(2024, 3, 1, 12, 30, 0, 250000000, time.FixedZone("CET", 3600))
+	transactions := []models.Transaction{
+		{Id: 1, Amount: decimal.RequireFromString("100.25"), Type: "purchase", Currency: "USD", OccurredAt: &occurredAt},
+		{Id: 2, Amount: decimal.RequireFromString("-3"), Type: "refund, partial", ParentID: &parentID, Currency: "EUR"},
+	}
+
+	for _, format := range []transfer.Format{transfer.CSV, transfer.NDJSON} {
+		t.Run(string(format), func(t *testing.T) {
+			var buffer bytes.Buffer
+			encoder, err := transfer.NewEncoder(format, &buffer)
+			assert.NoError(t, err)
+			for _, transaction := range transactions {
+				assert.NoError(t, encoder.Encode(transaction))
+			}
+			assert.NoError(t, encoder.Flush())
+
+			records, err := transfer.Decode(format, &buffer)
+
+			// Assert every transaction survives the round trip
+			assert.NoError(t, err)
+			assert.Len(t, records, len(transactions))
+			for i, record := range records {
+				assert.Equal(t, transactions[i].Id, record.Transaction.Id)
+				assert.True(t, transactions[i].Amount.Equal(record.Transaction.Amount))
+				assert.Equal(t, transactions[i].Type, record.Transaction.Type)
+				assert.Equal(t, transactions[i].ParentID, record.Transaction.ParentID)
+				assert.Equal(t, transactions[i].Currency, record.Transaction.Currency)
+				if transactions[i].OccurredAt == nil {
+					assert.Nil(t, record.Transaction.OccurredAt)
+				} else {
+					assert.WithinDuration(t, *transactions[i].OccurredAt, *record.Transaction.OccurredAt, 0)
+				}
+			}
+		})
+	}
+}
+
+func TestOrder(t *testing.T) {
+	t.Run("parents come before their children", func(t *testing.T) {
+		one, two, external := uint(1), uint(2), uint(99)
+		transactions := []models.Transaction{
+			{Id: 3, ParentID: &two},
+			{Id: 2, ParentID: &one},
+			{Id: 4, ParentID: &external},
+			{Id: 1},
+		}
+
+		order, err := transfer.Order(transactions)
+
+		// Assert 1 -> 2 -> 3 is inserted top down, the transaction with an external parent keeping its place
+		assert.NoError(t, err)
+		assert.Equal(t, []int{3, 1, 0, 2}, order)
+	})
+
+	t.Run("when parents form a cycle", func(t *testing.T) {
+		one, two := uint(1), uint(2)
+		transactions := []models.Transaction{
+			{Id: 1, ParentID: &two},
+			{Id: 2, ParentID: &one},
+		}
+
+		_, err := transfer.Order(transactions)
+
+		// Assert the cycle is reported
+		var cycleErr *transfer.CycleError
+		assert.ErrorAs(t, err, &cycleErr)
+		assert.ElementsMatch(t, []uint{1, 2}, cycleErr.TransactionIDs)
+	})
+}
@@ -0,0 +1,424 @@
+// Package validation decodes request bodies into structs and checks them against the rules declared in
+// their `validate` struct tags, reporting every invalid field at once.
+//
+// The rules of a field are separated by commas and checked in order, stopping at the first that fails:
+//
+//	required   the field must be present and not null
+//	notnull    the field may be absent but must not be null when present
+//	notblank   a string must contain a non-whitespace character
+//	max=N      a string must have at most N characters, a slice at most N items, a number be at most N
+//	min=N      a string must have at least N characters, a slice at least N items, a number be at least N
+//	integer    a number must have no fractional part
+//	finite     a number must be representable as a finite float64
+//	digits=N   a number must have at most N digits, counting those after its decimal point
+//	scale=N    a number must have at most N digits after its decimal point
+//	currency   a string must be an ISO 4217 currency code, in any case
+//
+// Rules other than required are skipped for nil fields, so optional fields are declared as pointers.
+package validation
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"transaction_system/app/lib/apperror"
+	"transaction_system/app/lib/currency"
+	"unicode/utf8"
+
+	"github.com/shopspring/decimal"
+)
+
+// ErrValidation is returned when fields fail their rules. Its message lists every invalid field, and its
+// "errors" detail holds them as FieldError values.
+var ErrValidation = apperror.New("validation_failed", http.StatusBadRequest, "request failed validation")
+
+// FieldError describes a field that failed one of its rules.
+type FieldError struct {
+	Field string `json:"field"`
+	// Rule is the rule that failed, "type" when the value has the wrong JSON type and "unknown" when the
+	// field is not part of the request.
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// Fields is the set of fields present in a decoded body, including those that were null.
+type Fields map[string]bool
+
+var (
+	numberType  = reflect.TypeOf(json.Number(""))
+	decimalType = reflect.TypeOf(decimal.Decimal{})
+	timeType    = reflect.TypeOf(time.Time{})
+	maxFloat64  = decimal.NewFromFloat(math.MaxFloat64)
+)
+
+// field is a struct field decoded from the JSON member called name.
+type field struct {
+	name  string
+	index []int
+	rules string
+}
+
+// Decode reads a JSON object from r into the struct pointed to by v and validates it. Members that are
+// not fields of v, values of the wrong JSON type and fields failing their rules are all reported in a
+// single ErrValidation. Numbers must not be quoted. It returns the fields present in the object.
+func Decode(r io.Reader, v interface{}) (Fields, error) {
+	var members map[string]json.RawMessage
+	if err := json.NewDecoder(r).Decode(&members); err != nil || members == nil {
+		return nil, apperror.InvalidRequest("Error decoding request body")
+	}
+
+	target := reflect.ValueOf(v).Elem()
+	fields := structFields(target.Type())
+	byName := make(map[string]field, len(fields))
+	for _, f := range fields {
+		byName[f.name] = f
+	}
+
+	present := make(Fields, len(members))
+	reported := make(map[string]bool)
+	var errs []FieldError
+	for name, raw := range members {
+		f, ok := byName[name]
+		if !ok {
+			errs = append(errs, FieldError{Field: name, Rule: "unknown", Message: "is not a known field"})
+			continue
+		}
+		present[name] = true
+		value := target.FieldByIndex(f.index)
+		if !decodeValue(raw, value) {
+			errs = append(errs, FieldError{Field: name, Rule: "type", Message: typeMessage(value.Type())})
+			reported[name] = true
+		} else if string(bytes.TrimSpace(raw)) == "null" && hasRule(f, "notnull") {
+			errs = append(errs, FieldError{Field: name, Rule: "notnull", Message: "must not be null"})
+			reported[name] = true
+		}
+	}
+
+	// Fields of the wrong type or null are already reported, so their rules are not checked
+	for _, f := range fields {
+		if reported[f.name] {
+			continue
+		}
+		if fieldErr, ok := check(f, target.FieldByIndex(f.index)); !ok {
+			errs = append(errs, fieldErr)
+		}
+	}
+	return present, newError(errs)
+}
+
+// Struct validates the struct pointed to by v against the rules of its fields.
+func Struct(v interface{}) error {
+	target := reflect.ValueOf(v).Elem()
+	var errs []FieldError
+	for _, f := range structFields(target.Type()) {
+		if fieldErr, ok := check(f, target.FieldByIndex(f.index)); !ok {
+			errs = append(errs, fieldErr)
+		}
+	}
+	return newError(errs)
+}
+
+// newError returns ErrValidation describing errs, or nil when there are none. Errors are ordered by field
+// name so that responses are stable.
+func newError(errs []FieldError) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	sort.SliceStable(errs, func(i, j int) bool {
+		return errs[i].Field < errs[j].Field
+	})
+	messages := make([]string, len(errs))
+	for i, fieldErr := range errs {
+		messages[i] = fieldErr.Field + " " + fieldErr.Message
+	}
+	return ErrValidation.WithMessage(strings.Join(messages, "; ")).WithDetails(map[string]interface{}{
+		"errors": errs,
+	})
+}
+
+// structFields lists the JSON fields of t, flattening embedded structs the way encoding/json does.
+func structFields(t reflect.Type) []field {
+	var fields []field
+	for i := 0; i < t.NumField(); i++ {
+		structField := t.Field(i)
+		name, _, _ := strings.Cut(structField.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if structField.Anonymous && structField.Type.Kind() == reflect.Struct && name == "" {
+			for _, embedded := range structFields(structField.Type) {
+				embedded.index = append([]int{i}, embedded.index...)
+				fields = append(fields, embedded)
+			}
+			continue
+		}
+		if !structField.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = structField.Name
+		}
+		fields = append(fields, field{name: name, index: []int{i}, rules: structField.Tag.Get("validate")})
+	}
+	return fields
+}
+
+// decodeValue unmarshals raw into value, reporting whether raw has a type value accepts. Quoted numbers
+// are rejected even for types that would accept them.
+func decodeValue(raw json.RawMessage, value reflect.Value) bool {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) > 0 && raw[0] == '"' && isNumber(value.Type()) {
+		return false
+	}
+	return json.Unmarshal(raw, value.Addr().Interface()) == nil
+}
+
+// typeMessage describes the JSON type expected by a field of type t.
+func typeMessage(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch {
+	case isNumber(t):
+		return "must be a number"
+	case t == timeType:
+		return "must be an RFC 3339 timestamp"
+	case t.Kind() == reflect.String:
+		return "must be a string"
+	case t.Kind() == reflect.Bool:
+		return "must be a boolean"
+	case t.Kind() == reflect.Slice:
+		return "must be an array"
+	}
+	return "has an invalid type"
+}
+
+func isNumber(t reflect.Type) bool {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return t == numberType || t == decimalType
+}
+
+// check applies the rules of f to value, returning the error of the first rule that fails.
+func check(f field, value reflect.Value) (FieldError, bool) {
+	if f.rules == "" {
+		return FieldError{}, true
+	}
+	for _, rule := range strings.Split(f.rules, ",") {
+		name, param, _ := strings.Cut(strings.TrimSpace(rule), "=")
+		if message := apply(name, param, value); message != "" {
+			return FieldError{Field: f.name, Rule: name, Message: message}, false
+		}
+	}
+	return FieldError{}, true
+}
+
+// hasRule reports whether rule is one of the rules of f.
+func hasRule(f field, rule string) bool {
+	for _, r := range strings.Split(f.rules, ",") {
+		if name, _, _ := strings.Cut(strings.TrimSpace(r), "="); name == rule {
+			return true
+		}
+	}
+	return false
+}
+
+// apply checks value against a single rule, returning a message when it fails.
+func apply(rule, param string, value reflect.Value) string {
+	for value.Kind() == reflect.Pointer || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			if rule == "required" {
+				return "is required"
+			}
+			return ""
+		}
+		value = value.Elem()
+	}
+
+	switch rule {
+	case "notnull":
+		// Only decoded bodies tell a null field from an absent one, so Decode checks it
+		return ""
+	case "required":
+		if value.Kind() == reflect.Slice && value.IsNil() {
+			return "is required"
+		}
+		return ""
+	case "notblank":
+		if value.Kind() == reflect.String && strings.TrimSpace(value.String()) == "" {
+			return "must not be blank"
+		}
+		return ""
+	case "currency":
+		if value.Kind() == reflect.String && !currency.IsValid(currency.Normalize(value.String())) {
+			return "must be an ISO 4217 currency code"
+		}
+		return ""
+	case "min", "max":
+		return checkBound(rule, param, value)
+	case "integer", "finite":
+		number, ok := numberValue(value)
+		if !ok {
+			return ""
+		}
+		if rule == "integer" && !isInteger(number) {
+			return "must be an integer"
+		}
+		if rule == "finite" && compare(number.Abs(), maxFloat64) > 0 {
+			return "must be a finite number"
+		}
+		return ""
+	case "digits", "scale":
+		number, ok := numberValue(value)
+		if !ok {
+			return ""
+		}
+		limit, err := strconv.ParseInt(param, 10, 64)
+		if err != nil {
+			panic(fmt.Sprintf("validation: invalid %s parameter %q", rule, param))
+		}
+		digits, scale := precision(number)
+		if rule == "digits" && digits > limit {
+			return fmt.Sprintf("must have at most %d digits", limit)
+		}
+		if rule == "scale" && scale > limit {
+			return fmt.Sprintf("must have at most %d decimal places", limit)
+		}
+		return ""
+	}
+	panic(fmt.Sprintf("validation: unknown rule %q", rule))
+}
+
+// checkBound applies a min or max rule, which bounds the length of strings and slices and the value of
+// numbers.
+func checkBound(rule, param string, value reflect.Value) string {
+	if !isNumber(value.Type()) && (value.Kind() == reflect.String || value.Kind() == reflect.Slice) {
+		limit, err := strconv.Atoi(param)
+		if err != nil {
+			panic(fmt.Sprintf("validation: invalid %s parameter %q", rule, param))
+		}
+		length, unit := value.Len(), "items"
+		if value.Kind() == reflect.String {
+			length, unit = utf8.RuneCountInString(value.String()), "characters"
+		}
+		if rule == "min" && length < limit {
+			return fmt.Sprintf("must contain at least %d %s", limit, unit)
+		}
+		if rule == "max" && length > limit {
+			return fmt.Sprintf("must contain at most %d %s", limit, unit)
+		}
+		return ""
+	}
+
+	number, ok := numberValue(value)
+	if !ok {
+		return ""
+	}
+	limit, err := decimal.NewFromString(param)
+	if err != nil {
+		panic(fmt.Sprintf("validation: invalid %s parameter %q", rule, param))
+	}
+	if rule == "min" && compare(number, limit) < 0 {
+		return "must be at least " + param
+	}
+	if rule == "max" && compare(number, limit) > 0 {
+		return "must be at most " + param
+	}
+	return ""
+}
+
+// numberValue returns the numeric value of value as a decimal.
+func numberValue(value reflect.Value) (decimal.Decimal, bool) {
+	switch {
+	case value.Type() == decimalType:
+		return value.Interface().(decimal.Decimal), true
+	case value.Type() == numberType:
+		number, err := decimal.NewFromString(value.String())
+		return number, err == nil
+	case value.CanInt():
+		return decimal.NewFromInt(value.Int()), true
+	case value.CanUint():
+		return decimal.NewFromBigInt(new(big.Int).SetUint64(value.Uint()), 0), true
+	case value.CanFloat():
+		return decimal.NewFromFloat(value.Float()), true
+	}
+	return decimal.Zero, false
+}
+
+// magnitude returns the number of digits of d before its decimal point, which is zero or negative for
+// numbers below one.
+func magnitude(d decimal.Decimal) int64 {
+	coefficient := d.Coefficient()
+	return int64(len(coefficient.Abs(coefficient).String())) + int64(d.Exponent())
+}
+
+// compare compares a and b like decimal.Cmp. Numbers of different magnitudes are compared without
+// rescaling them, which would allocate in proportion to the difference of their exponents.
+func compare(a, b decimal.Decimal) int {
+	if a.Sign() != b.Sign() {
+		if a.Sign() < b.Sign() {
+			return -1
+		}
+		return 1
+	}
+	if a.Sign() == 0 {
+		return 0
+	}
+	magnitudeA, magnitudeB := magnitude(a), magnitude(b)
+	if magnitudeA != magnitudeB {
+		if (magnitudeA < magnitudeB) == (a.Sign() > 0) {
+			return -1
+		}
+		return 1
+	}
+	return a.Cmp(b)
+}
+
+// precision returns the number of digits of d, from its first integer digit or its decimal point down to
+// its last non-zero digit, and how many of them follow its decimal point. Numbers are not rescaled, so
+// extreme exponents are measured without allocating in proportion to them.
+func precision(d decimal.Decimal) (digits, scale int64) {
+	coefficient := d.Coefficient()
+	text := coefficient.Abs(coefficient).String()
+	significant := strings.TrimRight(text, "0")
+	if significant == "" {
+		return 0, 0
+	}
+	exponent := int64(d.Exponent()) + int64(len(text)-len(significant))
+	if exponent < 0 {
+		scale = -exponent
+	}
+	integerDigits := int64(len(significant)) + exponent
+	if integerDigits < 0 {
+		integerDigits = 0
+	}
+	return integerDigits + scale, scale
+}
+
+// isInteger reports whether d has no fractional part, without rescaling numbers whose exponent is far
+// below their digits.
+func isInteger(d decimal.Decimal) bool {
+	if d.Exponent() >= 0 || d.Sign() == 0 {
+		return true
+	}
+	if magnitude(d) <= 0 {
+		return false
+	}
+	return d.IsInteger()
+}
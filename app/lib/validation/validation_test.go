@@ -0,0 +1,157 @@
+package validation_test
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+
+	"transaction_system/app/lib/apperror"
+	"transaction_system/app/lib/validation"
+)
+
+type payload struct {
+	Amount   *decimal.Decimal `json:"amount" validate:"required,finite"`
+	Type     *string          `json:"type" validate:"required,notblank,max=5"`
+	ParentID *json.Number     `json:"parent_id" validate:"integer,min=0"`
+	Currency *string          `json:"currency" validate:"notnull,currency"`
+	At       *time.Time       `json:"at"`
+}
+
+// fieldErrors returns the field errors carried by err.
+func fieldErrors(t *testing.T, err error) []validation.FieldError {
+	var appErr *apperror.Error
+	if !errors.As(err, &appErr) {
+		t.Fatalf("expected an apperror.Error, got %v", err)
+	}
+	assert.ErrorIs(t, err, validation.ErrValidation)
+	return appErr.Details["errors"].([]validation.FieldError)
+}
+
+func TestDecode_Valid(t *testing.T) {
+	var request payload
+	fields, err := validation.Decode(strings.NewReader(`{"amount": 0.10, "type": "food", "parent_id": null, "currency": "eur"}`), &request)
+
+	// Assert the body is decoded and null fields are reported as present
+	assert.NoError(t, err)
+	assert.Equal(t, "0.1", request.Amount.String())
+	assert.Equal(t, "food", *request.Type)
+	assert.Nil(t, request.ParentID)
+	assert.Equal(t, validation.Fields{"amount": true, "type": true, "parent_id": true, "currency": true}, fields)
+}
+
+func TestDecode_ReportsEveryField(t *testing.T) {
+	var request payload
+	_, err := validation.Decode(strings.NewReader(`{"amount": 1e400, "type": "groceries", "parent_id": -1.5, "currency": null, "note": "x"}`), &request)
+
+	// Assert each invalid field is reported once, by its first failing rule, in field order
+	assert.Equal(t, []validation.FieldError{
+		{Field: "amount", Rule: "finite", Message: "must be a finite number"},
+		{Field: "currency", Rule: "notnull", Message: "must not be null"},
+		{Field: "note", Rule: "unknown", Message: "is not a known field"},
+		{Field: "parent_id", Rule: "integer", Message: "must be an integer"},
+		{Field: "type", Rule: "max", Message: "must contain at most 5 characters"},
+	}, fieldErrors(t, err))
+	assert.EqualError(t, err, "amount must be a finite number; currency must not be null; note is not a known field; "+
+		"parent_id must be an integer; type must contain at most 5 characters")
+}
+
+func TestDecode_Types(t *testing.T) {
+	var request payload
+	_, err := validation.Decode(strings.NewReader(`{"amount": "10", "type": 7, "parent_id": true, "at": "2024-02-30"}`), &request)
+
+	// Assert quoted numbers, mismatched types and invalid timestamps are rejected
+	assert.Equal(t, []validation.FieldError{
+		{Field: "amount", Rule: "type", Message: "must be a number"},
+		{Field: "at", Rule: "type", Message: "must be an RFC 3339 timestamp"},
+		{Field: "parent_id", Rule: "type", Message: "must be a number"},
+		{Field: "type", Rule: "type", Message: "must be a string"},
+	}, fieldErrors(t, err))
+}
+
+func TestDecode_Required(t *testing.T) {
+	var request payload
+	_, err := validation.Decode(strings.NewReader(`{"amount": null, "type": "  ", "parent_id": 1e-1000000000}`), &request)
+
+	// Assert null required fields, blank strings and tiny fractions are rejected
+	assert.Equal(t, []validation.FieldError{
+		{Field: "amount", Rule: "required", Message: "is required"},
+		{Field: "parent_id", Rule: "integer", Message: "must be an integer"},
+		{Field: "type", Rule: "notblank", Message: "must not be blank"},
+	}, fieldErrors(t, err))
+}
+
+func TestDecode_Bounds(t *testing.T) {
+	var request payload
+	_, err := validation.Decode(strings.NewReader(`{"amount": -1e308, "type": "", "parent_id": -1}`), &request)
+
+	// Assert numbers, including json.Number strings, are bounded by value and strings by length
+	assert.Equal(t, []validation.FieldError{
+		{Field: "parent_id", Rule: "min", Message: "must be at least 0"},
+		{Field: "type", Rule: "notblank", Message: "must not be blank"},
+	}, fieldErrors(t, err))
+}
+
+func TestDecode_Precision(t *testing.T) {
+	type amountPayload struct {
+		Amount *decimal.Decimal `json:"amount" validate:"finite,digits=6,scale=2"`
+	}
+
+	for _, tc := range []struct {
+		amount  string
+		message string
+	}{
+		{"1234.50", ""},
+		{"0.01", ""},
+		{"-120000e-2", ""},
+		{"0", ""},
+		{"1e-100000000", "must have at most 6 digits"},
+		{"-1e-2147483647", "must have at most 6 digits"},
+		{"1e100000000", "must be a finite number"},
+		{"1e300", "must have at most 6 digits"},
+		{"12345.67", "must have at most 6 digits"},
+		{"0.001", "must have at most 2 decimal places"},
+	} {
+		var request amountPayload
+		_, err := validation.Decode(strings.NewReader(`{"amount": `+tc.amount+`}`), &request)
+
+		// Assert extreme exponents are rejected without rescaling the amount
+		if tc.message == "" {
+			assert.NoError(t, err, tc.amount)
+			continue
+		}
+		errs := fieldErrors(t, err)
+		if assert.Len(t, errs, 1, tc.amount) {
+			assert.Equal(t, tc.message, errs[0].Message, tc.amount)
+		}
+	}
+}
+
+func TestDecode_NotAnObject(t *testing.T) {
+	for _, body := range []string{`[]`, `null`, `{"amount": `} {
+		var request payload
+		_, err := validation.Decode(strings.NewReader(body), &request)
+
+		// Assert bodies that are not JSON objects are rejected as a whole
+		assert.ErrorIs(t, err, apperror.ErrInvalidRequest, body)
+	}
+}
+
+func TestStruct(t *testing.T) {
+	amount := decimal.RequireFromString("-5")
+	transactionType := "cars"
+	parentID := json.Number("2")
+
+	// Assert valid structs pass, including negative amounts
+	assert.NoError(t, validation.Struct(&payload{Amount: &amount, Type: &transactionType, ParentID: &parentID}))
+
+	// Assert missing required fields are reported
+	assert.Equal(t, []validation.FieldError{
+		{Field: "amount", Rule: "required", Message: "is required"},
+		{Field: "type", Rule: "required", Message: "is required"},
+	}, fieldErrors(t, validation.Struct(&payload{})))
+}
@@ -1,13 +1,57 @@
 package models
 
-// Transaction represents the transactions table schema.
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func init() {
+	// Render amounts as JSON numbers rather than quoted strings so responses keep
+	// their numeric shape while still carrying every significant digit.
+	decimal.MarshalJSONWithoutQuotes = true
+}
+
+// Transaction represents the transactions table schema. IDs are validated against the range of the BIGINT
+// columns they are stored in, and amounts are bounded to 38 digits, 18 of them decimal places, so that
+// writing and comparing them never rescales a huge exponent.
+//
+// OccurredAt is the business time of the transaction, supplied by the client. The audit fields CreatedAt,
+// UpdatedAt and CreatedBy are set by the repository when the transaction is written, so they are nil until
+// it is stored, and CreatedBy stays nil when it was created without an actor.
 type Transaction struct {
-	Id       uint    `json:"id" gorm:"primarykey"`
-	Amount   float64 `json:"amount" validate:"notblank"`
-	Type     string  `json:"type" validate:"notblank" gorm:"varchar(50)"`
-	ParentID *uint   `json:"parent_id"`
+	Id         uint            `json:"id" validate:"max=9223372036854775807" gorm:"primarykey"`
+	Amount     decimal.Decimal `json:"amount" validate:"finite,digits=38,scale=18" gorm:"type:numeric"`
+	Type       string          `json:"type" validate:"notblank,max=50" gorm:"varchar(50)"`
+	ParentID   *uint           `json:"parent_id" validate:"max=9223372036854775807"`
+	Currency   string          `json:"currency" gorm:"type:char(3)"`
+	OccurredAt *time.Time      `json:"occurred_at,omitempty"`
+	CreatedAt  *time.Time      `json:"created_at,omitempty" gorm:"autoCreateTime:false"`
+	UpdatedAt  *time.Time      `json:"updated_at,omitempty" gorm:"autoUpdateTime:false"`
+	CreatedBy  *string         `json:"created_by,omitempty" gorm:"type:varchar(255)"`
 }
 
 func (Transaction) TableName() string {
 	return "transactions"
 }
+
+// TransactionUpdate holds the fields of a partial transaction update; nil fields are left unchanged.
+type TransactionUpdate struct {
+	Amount   *decimal.Decimal
+	Type     *string
+	Currency *string
+	// OccurredAt sets the business time of the transaction.
+	OccurredAt *time.Time
+	// ParentID is only applied when SetParentID is true, in which case a nil ParentID makes the transaction a root.
+	ParentID    *uint
+	SetParentID bool
+}
+
+// TransactionNode is a transaction positioned within a transaction tree.
+type TransactionNode struct {
+	Transaction
+	// Depth is the distance from the root of the tree, the root having depth 0.
+	Depth int `json:"depth"`
+	// Path holds the IDs from the root of the tree down to and including this transaction.
+	Path []uint `json:"path"`
+}
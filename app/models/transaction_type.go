@@ -0,0 +1,10 @@
+package models
+
+// TransactionType represents the transaction_types table schema, the registry of allowed transaction types.
+type TransactionType struct {
+	Name string `json:"name" gorm:"primarykey;type:varchar(50)"`
+}
+
+func (TransactionType) TableName() string {
+	return "transaction_types"
+}
@@ -0,0 +1,404 @@
+package repositories
+
+import (
+	"context"
+	"transaction_system/app/models"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// closureTransactionRepository keeps, next to the parent_id links, a row of the transaction_closure table
+// for every pair of a transaction and one of its descendants, each transaction also being paired with
+// itself at depth 0. Hierarchy reads are then single indexed joins rather than recursive queries.
+//
+// The closure rows cannot represent a cycle, so Update refuses to move a transaction under itself or one of
+// its descendants. Tree reads therefore never report one, is_cycle being 0 on every row they return.
+type closureTransactionRepository struct {
+	*transactionRepository
+}
+
+// bind returns a closure repository running its queries on tx.
+func (t *closureTransactionRepository) bind(tx *gorm.DB) *closureTransactionRepository {
+	return &closureTransactionRepository{transactionRepository: t.transactionRepository.bind(tx)}
+}
+
+// Create inserts a new transaction into the database together with its closure rows.
+func (t *closureTransactionRepository) Create(ctx context.Context, transaction *models.Transaction) error {
+	return t.Db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := t.transactionRepository.bind(tx).Create(ctx, transaction); err != nil {
+			return err
+		}
+
+		if err := tx.Exec(`INSERT INTO transaction_closure (ancestor_id, descendant_id, depth) VALUES (?, ?, 0)`,
+			transaction.Id, transaction.Id).Error; err != nil {
+			return err
+		}
+		if transaction.ParentID == nil {
+			return nil
+		}
+		return tx.Exec(`
+			INSERT INTO transaction_closure (ancestor_id, descendant_id, depth)
+			SELECT ancestor_id, CAST(? AS BIGINT), depth + 1
+			FROM transaction_closure
+			WHERE descendant_id = ?
+		`, transaction.Id, *transaction.ParentID).Error
+	})
+}
+
+// Update saves every field of an existing transaction to the database. When the parent changes, the closure
+// rows linking the subtree of the transaction to its former ancestors are replaced by rows linking it to its
+// new ones. Moving the transaction under itself or one of its descendants fails with ErrCycleDetected.
+func (t *closureTransactionRepository) Update(ctx context.Context, transaction *models.Transaction) error {
+	return t.Db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var stored models.Transaction
+		if err := tx.Where("id = ?", transaction.Id).First(&stored).Error; err != nil {
+			return err
+		}
+		if transaction.ParentID != nil && !equalIDs(stored.ParentID, transaction.ParentID) {
+			var descendants int64
+			err := tx.Raw(`SELECT COUNT(*) FROM transaction_closure WHERE ancestor_id = ? AND descendant_id = ?`,
+				transaction.Id, *transaction.ParentID).Scan(&descendants).Error
+			if err != nil {
+				return err
+			}
+			if descendants > 0 {
+				return ErrCycleDetected
+			}
+		}
+		if err := t.transactionRepository.bind(tx).Update(ctx, transaction); err != nil {
+			return err
+		}
+		if equalIDs(stored.ParentID, transaction.ParentID) {
+			return nil
+		}
+
+		err := tx.Exec(`
+			DELETE FROM transaction_closure
+			WHERE descendant_id IN (SELECT descendant_id FROM transaction_closure WHERE ancestor_id = ?)
+				AND ancestor_id IN (SELECT ancestor_id FROM transaction_closure WHERE descendant_id = ? AND depth > 0)
+		`, transaction.Id, transaction.Id).Error
+		if err != nil {
+			return err
+		}
+		if transaction.ParentID == nil {
+			return nil
+		}
+		return tx.Exec(`
+			INSERT INTO transaction_closure (ancestor_id, descendant_id, depth)
+			SELECT a.ancestor_id, d.descendant_id, a.depth + d.depth + 1
+			FROM transaction_closure a, transaction_closure d
+			WHERE a.descendant_id = ? AND d.ancestor_id = ?
+		`, *transaction.ParentID, transaction.Id).Error
+	})
+}
+
+// Delete removes a transaction from the database together with its closure rows, handling its children
+// according to mode.
+func (t *closureTransactionRepository) Delete(ctx context.Context, transactionID uint, mode DeleteMode) error {
+	return t.Db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if t.MaterializedSums {
+			if err := t.removeFromSums(tx, transactionID, mode); err != nil {
+				return err
+			}
+		}
+
+		switch mode {
+		case DeleteModeCascade:
+			const subtree = `SELECT descendant_id FROM transaction_closure WHERE ancestor_id = ?`
+
+			// Refuse subtrees deeper than the other repositories can walk whole, as they do
+			var height int
+			if err := tx.Raw(`SELECT COALESCE(MAX(depth), 0) FROM transaction_closure WHERE ancestor_id = ?`, transactionID).Scan(&height).Error; err != nil {
+				return err
+			}
+			if height > t.MaxDepth {
+				return ErrMaxDepthExceeded
+			}
+			if t.MaterializedSums {
+				if err := tx.Exec(`DELETE FROM transaction_descendant_sums WHERE transaction_id IN (`+subtree+`)`, transactionID).Error; err != nil {
+					return err
+				}
+			}
+			if err := tx.Exec(`DELETE FROM transactions WHERE id IN (`+subtree+`)`, transactionID).Error; err != nil {
+				return err
+			}
+			// Foreign keys usually drop the closure rows with the transactions, but not on every database
+			return tx.Exec(`DELETE FROM transaction_closure WHERE descendant_id IN (`+subtree+`)`, transactionID).Error
+		case DeleteModeReparent:
+			var transaction models.Transaction
+			if err := tx.Where("id = ?", transactionID).First(&transaction).Error; err != nil {
+				return err
+			}
+			if err := reparentChildren(tx, transactionID, transaction.ParentID); err != nil {
+				return err
+			}
+
+			// The descendants move one level up relative to every ancestor of the deleted transaction
+			err := tx.Exec(`
+				UPDATE transaction_closure SET depth = depth - 1
+				WHERE descendant_id IN (SELECT descendant_id FROM transaction_closure WHERE ancestor_id = ? AND depth > 0)
+					AND ancestor_id IN (SELECT ancestor_id FROM transaction_closure WHERE descendant_id = ? AND depth > 0)
+			`, transactionID, transactionID).Error
+			if err != nil {
+				return err
+			}
+		default:
+			var children int64
+			if err := tx.Model(&models.Transaction{}).Where("parent_id = ?", transactionID).Count(&children).Error; err != nil {
+				return err
+			}
+			if children > 0 {
+				return ErrTransactionHasChildren
+			}
+		}
+
+		if t.MaterializedSums {
+			if err := tx.Exec("DELETE FROM transaction_descendant_sums WHERE transaction_id = ?", transactionID).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Exec("DELETE FROM transaction_closure WHERE ancestor_id = ? OR descendant_id = ?", transactionID, transactionID).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Transaction{}, transactionID).Error
+	})
+}
+
+// WithinTransaction runs fn with a repository bound to a database transaction, committing it when fn
+// returns nil and rolling it back otherwise. Nested calls are backed by savepoints.
+func (t *closureTransactionRepository) WithinTransaction(ctx context.Context, fn func(transactionRepo TransactionRepositoryI) error) error {
+	return t.Db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(t.bind(tx))
+	})
+}
+
+// GetDepth retrieves the number of ancestors of a transaction, a root transaction having depth 0.
+func (t *closureTransactionRepository) GetDepth(ctx context.Context, transactionID uint) (int, error) {
+	var depth int
+	err := t.Db.WithContext(ctx).
+		Raw(`SELECT COALESCE(MAX(depth), 0) FROM transaction_closure WHERE descendant_id = ?`, transactionID).
+		Scan(&depth).Error
+	if err != nil {
+		return 0, err
+	}
+	if depth > t.MaxDepth {
+		return 0, ErrMaxDepthExceeded
+	}
+	return depth, nil
+}
+
+// GetDepths retrieves the depths of the transactions with the given IDs with a single query, skipping IDs
+// that do not exist.
+func (t *closureTransactionRepository) GetDepths(ctx context.Context, transactionIDs []uint) (map[uint]int, error) {
+	depths := make(map[uint]int, len(transactionIDs))
+	if len(transactionIDs) == 0 {
+		return depths, nil
+	}
+
+	var rows []treeRow
+	err := t.Db.WithContext(ctx).Raw(`
+		SELECT descendant_id AS id, MAX(depth) AS depth
+		FROM transaction_closure
+		WHERE descendant_id IN ?
+		GROUP BY descendant_id
+	`, transactionIDs).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	if err := t.checkTreeRows(rows); err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		depths[row.Id] = row.Depth
+	}
+	return depths, nil
+}
+
+// GetAncestors retrieves the ancestors of a transaction ordered from its parent up to the root.
+func (t *closureTransactionRepository) GetAncestors(ctx context.Context, transactionID uint) ([]models.Transaction, error) {
+	var rows []treeRow
+	err := t.Db.WithContext(ctx).Raw(`
+		SELECT t.id, t.parent_id, t.amount, t.type, t.currency, t.occurred_at, t.created_at, t.updated_at, t.created_by, c.depth
+		FROM transaction_closure c
+		JOIN transactions t ON t.id = c.ancestor_id
+		WHERE c.descendant_id = ? AND c.depth > 0
+		ORDER BY c.depth
+	`, transactionID).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	if err := t.checkTreeRows(rows); err != nil {
+		return nil, err
+	}
+
+	ancestors := make([]models.Transaction, 0, len(rows))
+	for _, row := range rows {
+		ancestors = append(ancestors, row.Transaction)
+	}
+	return ancestors, nil
+}
+
+// GetDescendants retrieves a transaction and its descendants down to maxDepth levels below it,
+// ordered by depth and then by ID.
+func (t *closureTransactionRepository) GetDescendants(ctx context.Context, transactionID uint, maxDepth int) ([]models.TransactionNode, error) {
+	// Read one level past the configured maximum depth so that deeper trees are reported rather than truncated
+	if maxDepth > t.MaxDepth {
+		maxDepth = t.MaxDepth + 1
+	}
+
+	var rows []treeRow
+	err := t.Db.WithContext(ctx).Raw(`
+		SELECT t.id, t.parent_id, t.amount, t.type, t.currency, t.occurred_at, t.created_at, t.updated_at, t.created_by, c.depth
+		FROM transaction_closure c
+		JOIN transactions t ON t.id = c.descendant_id
+		WHERE c.ancestor_id = ? AND c.depth <= ?
+		ORDER BY c.depth, t.id
+	`, transactionID, maxDepth).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	if err := t.checkTreeRows(rows); err != nil {
+		return nil, err
+	}
+
+	// Rows come by depth, so the path of a parent is known before those of its children
+	paths := make(map[uint][]uint, len(rows))
+	nodes := make([]models.TransactionNode, 0, len(rows))
+	for _, row := range rows {
+		var path []uint
+		if row.Depth > 0 && row.ParentID != nil {
+			path = append(path, paths[*row.ParentID]...)
+		}
+		path = append(path, row.Id)
+		paths[row.Id] = path
+		nodes = append(nodes, models.TransactionNode{Transaction: row.Transaction, Depth: row.Depth, Path: path})
+	}
+	return nodes, nil
+}
+
+// GetTransitiveSum retrieves the sum of all transactions transitively linked by their parent_id to a given transaction ID,
+// keyed by currency.
+func (t *closureTransactionRepository) GetTransitiveSum(ctx context.Context, transactionID uint) (map[string]decimal.Decimal, error) {
+	if t.MaterializedSums {
+		return t.getMaterializedSum(ctx, transactionID)
+	}
+	if !exactSums(t.Db) {
+		var rows []treeRow
+		err := t.Db.WithContext(ctx).Raw(`
+			SELECT t.id, t.amount, t.currency, c.depth
+			FROM transaction_closure c
+			JOIN transactions t ON t.id = c.descendant_id
+			WHERE c.ancestor_id = ? AND c.depth > 0
+		`, transactionID).Scan(&rows).Error
+		if err != nil {
+			return nil, err
+		}
+		if err := t.checkTreeRows(rows); err != nil {
+			return nil, err
+		}
+		return addUp(rows), nil
+	}
+
+	rows, err := t.Db.WithContext(ctx).Raw(`
+		SELECT t.currency, SUM(t.amount) AS total_amount, MAX(c.depth) AS max_depth
+		FROM transaction_closure c
+		JOIN transactions t ON t.id = c.descendant_id
+		WHERE c.ancestor_id = ? AND c.depth > 0
+		GROUP BY t.currency
+	`, transactionID).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	subtotals := make(map[string]decimal.Decimal)
+	for rows.Next() {
+		var currency string
+		var totalAmount decimal.Decimal
+		var maxDepth int
+		if err := rows.Scan(&currency, &totalAmount, &maxDepth); err != nil {
+			return nil, err
+		}
+		if maxDepth > t.MaxDepth {
+			return nil, ErrMaxDepthExceeded
+		}
+		subtotals[currency] = totalAmount
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return subtotals, nil
+}
+
+// rebuildClosureCTE walks every transaction up to its ancestors, one row per pair with their distance, for
+// up to ? levels. Like treeWalk, every row carries the path of IDs it went through and an is_cycle flag set
+// on a row whose ancestor was already on the path, which also stops the walk there.
+const rebuildClosureCTE = `
+	WITH RECURSIVE Closure AS (
+		SELECT id AS ancestor_id, id AS descendant_id, 0 AS depth,
+			'/' || CAST(id AS TEXT) || '/' AS path, 0 AS is_cycle
+		FROM transactions
+
+		UNION ALL
+
+		SELECT t.parent_id, Closure.descendant_id, Closure.depth + 1,
+			Closure.path || CAST(t.parent_id AS TEXT) || '/',
+			CASE WHEN Closure.path LIKE '%/' || CAST(t.parent_id AS TEXT) || '/%' THEN 1 ELSE 0 END
+		FROM Closure
+		JOIN transactions t ON t.id = Closure.ancestor_id
+		WHERE t.parent_id IS NOT NULL AND Closure.is_cycle = 0 AND Closure.depth < ?
+	)
+`
+
+// RebuildClosure recomputes the transaction_closure table from the parent_id links, returning the number of
+// rows stored. It is needed before switching to closure mode a database written to in adjacency mode. The
+// table is left untouched and ErrCycleDetected returned when the parent_id links form a cycle.
+func RebuildClosure(ctx context.Context, db *gorm.DB, maxDepth int) (int64, error) {
+	var rows int64
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := checkAcyclic(tx, maxDepth); err != nil {
+			return err
+		}
+
+		if err := tx.Exec("DELETE FROM transaction_closure").Error; err != nil {
+			return err
+		}
+
+		result := tx.Exec(`
+			INSERT INTO transaction_closure (ancestor_id, descendant_id, depth)
+		`+rebuildClosureCTE+`
+			SELECT ancestor_id, descendant_id, MIN(depth)
+			FROM Closure
+			GROUP BY ancestor_id, descendant_id
+		`, maxDepth+1)
+		rows = result.RowsAffected
+		return result.Error
+	})
+	return rows, err
+}
+
+// checkAcyclic returns ErrCycleDetected, naming a transaction that leads into the cycle, when the parent_id
+// links form a cycle within maxDepth levels of a transaction.
+func checkAcyclic(tx *gorm.DB, maxDepth int) error {
+	var cycleIDs []uint
+	err := tx.Raw(rebuildClosureCTE+`
+		SELECT descendant_id FROM Closure WHERE is_cycle = 1 LIMIT 1
+	`, maxDepth+1).Scan(&cycleIDs).Error
+	if err != nil {
+		return err
+	}
+	if len(cycleIDs) > 0 {
+		return ErrCycleDetected.WithDetails(map[string]interface{}{"transaction_id": cycleIDs[0]})
+	}
+	return nil
+}
+
+// equalIDs reports whether two optional transaction IDs are both nil or both set to the same ID.
+func equalIDs(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
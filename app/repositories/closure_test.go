@@ -0,0 +1,415 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"transaction_system/app/lib/audit"
+	"transaction_system/app/lib/db"
+	"transaction_system/app/models"
+)
+
+// openTestDB opens an empty in-memory SQLite database holding the transaction tables.
+func openTestDB(t *testing.T) *gorm.DB {
+	sqliteDB, err := db.Open(db.SQLiteScheme + ":memory:")
+	require.NoError(t, err)
+
+	sqlDB, err := sqliteDB.DB()
+	require.NoError(t, err)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	return sqliteDB.Session(&gorm.Session{Logger: logger.Default.LogMode(logger.Silent)})
+}
+
+// testRepositories returns every repository implementation, each on its own database opened by openDB.
+func testRepositories(t *testing.T, openDB func(t *testing.T) *gorm.DB) map[string]TransactionRepositoryI {
+	const maxDepth = 100
+	return map[string]TransactionRepositoryI{
+		"adjacency":           &transactionRepository{Db: openDB(t), MaxDepth: maxDepth},
+		"adjacency with sums": &transactionRepository{Db: openDB(t), MaxDepth: maxDepth, MaterializedSums: true},
+		"closure":             &closureTransactionRepository{&transactionRepository{Db: openDB(t), MaxDepth: maxDepth}},
+		"closure with sums":   &closureTransactionRepository{&transactionRepository{Db: openDB(t), MaxDepth: maxDepth, MaterializedSums: true}},
+		"memory":              MakeMemoryTransactionRepository(maxDepth),
+	}
+}
+
+// snapshotNode is the part of a tree node compared across implementations.
+type snapshotNode struct {
+	ID       uint
+	ParentID *uint
+	Amount   string
+	Currency string
+	Depth    int
+	Path     []uint
+}
+
+// snapshot records everything the repository reports about the hierarchy of transactionIDs.
+func snapshot(t *testing.T, repo TransactionRepositoryI, transactionIDs ...uint) map[string]interface{} {
+	ctx := context.Background()
+	result := make(map[string]interface{})
+	for _, id := range transactionIDs {
+		ancestors, err := repo.GetAncestors(ctx, id)
+		require.NoError(t, err)
+		ancestorIDs := []uint{}
+		for _, ancestor := range ancestors {
+			ancestorIDs = append(ancestorIDs, ancestor.Id)
+		}
+		result[fmt.Sprintf("%d ancestors", id)] = ancestorIDs
+
+		depth, err := repo.GetDepth(ctx, id)
+		require.NoError(t, err)
+		result[fmt.Sprintf("%d depth", id)] = depth
+
+		childIDs, err := repo.GetChildIDs(ctx, id)
+		require.NoError(t, err)
+		result[fmt.Sprintf("%d children", id)] = childIDs
+
+		descendants, err := repo.GetDescendants(ctx, id, 100)
+		require.NoError(t, err)
+		nodes := []snapshotNode{}
+		for _, node := range descendants {
+			nodes = append(nodes, snapshotNode{
+				ID:       node.Id,
+				ParentID: node.ParentID,
+				Amount:   node.Amount.String(),
+				Currency: node.Currency,
+				Depth:    node.Depth,
+				Path:     node.Path,
+			})
+		}
+		result[fmt.Sprintf("%d descendants", id)] = nodes
+
+		subtotals, err := repo.GetTransitiveSum(ctx, id)
+		require.NoError(t, err)
+		sums := make(map[string]string)
+		for currency, amount := range subtotals {
+			sums[currency] = amount.String()
+		}
+		result[fmt.Sprintf("%d sum", id)] = sums
+	}
+
+	depths, err := repo.GetDepths(ctx, transactionIDs)
+	require.NoError(t, err)
+	result["depths"] = depths
+	return result
+}
+
+func TestRepositories_ReturnIdenticalResults(t *testing.T) {
+	testIdenticalResults(t, openTestDB)
+}
+
+// testIdenticalResults applies the same changes to every repository implementation, the databases being
+// opened by openDB, and checks that they agree on the resulting hierarchy.
+func testIdenticalResults(t *testing.T, openDB func(t *testing.T) *gorm.DB) {
+	ctx := context.Background()
+	repositories := testRepositories(t, openDB)
+	id := func(id uint) *uint { return &id }
+	transactionIDs := []uint{1, 2, 3, 4, 5, 6, 7, 8}
+
+	// Each step is applied to every implementation, which must then agree on the whole hierarchy
+	steps := []struct {
+		name  string
+		apply func(repo TransactionRepositoryI) error
+	}{
+		{"create", func(repo TransactionRepositoryI) error {
+			for _, transaction := range []models.Transaction{
+				{Id: 1, Amount: decimal.RequireFromString("10"), Type: "car", Currency: "USD"},
+				{Id: 2, Amount: decimal.RequireFromString("5.5"), Type: "car", ParentID: id(1), Currency: "EUR"},
+				{Id: 3, Amount: decimal.RequireFromString("2.25"), Type: "shopping", ParentID: id(2), Currency: "USD"},
+				{Id: 4, Amount: decimal.RequireFromString("1"), Type: "shopping", ParentID: id(1), Currency: "USD"},
+				{Id: 5, Amount: decimal.RequireFromString("7"), Type: "car", ParentID: id(3), Currency: "EUR"},
+				{Id: 6, Amount: decimal.RequireFromString("3"), Type: "car", Currency: "USD"},
+				{Id: 7, Amount: decimal.RequireFromString("4"), Type: "car", ParentID: id(6), Currency: "USD"},
+				{Id: 8, Amount: decimal.RequireFromString("1.5"), Type: "car", ParentID: id(2), Currency: "USD"},
+			} {
+				transaction := transaction
+				if err := repo.Create(ctx, &transaction); err != nil {
+					return err
+				}
+			}
+			return nil
+		}},
+		{"move a subtree and change its amount", func(repo TransactionRepositoryI) error {
+			return repo.Update(ctx, &models.Transaction{Id: 3, Amount: decimal.RequireFromString("3.5"), Type: "shopping", ParentID: id(6), Currency: "USD"})
+		}},
+		{"change a currency", func(repo TransactionRepositoryI) error {
+			return repo.Update(ctx, &models.Transaction{Id: 2, Amount: decimal.RequireFromString("5.5"), Type: "car", ParentID: id(1), Currency: "USD"})
+		}},
+		{"make a subtree a root", func(repo TransactionRepositoryI) error {
+			return repo.Update(ctx, &models.Transaction{Id: 3, Amount: decimal.RequireFromString("3.5"), Type: "shopping", Currency: "USD"})
+		}},
+		{"move a root under another tree", func(repo TransactionRepositoryI) error {
+			return repo.Update(ctx, &models.Transaction{Id: 3, Amount: decimal.RequireFromString("3.5"), Type: "shopping", ParentID: id(7), Currency: "USD"})
+		}},
+		{"delete reparenting children", func(repo TransactionRepositoryI) error {
+			return repo.Delete(ctx, 2, DeleteModeReparent)
+		}},
+		{"delete a subtree", func(repo TransactionRepositoryI) error {
+			return repo.Delete(ctx, 7, DeleteModeCascade)
+		}},
+		{"delete a leaf", func(repo TransactionRepositoryI) error {
+			return repo.Delete(ctx, 4, DeleteModeReject)
+		}},
+	}
+
+	for _, step := range steps {
+		var expected map[string]interface{}
+		for _, name := range []string{"adjacency", "adjacency with sums", "closure", "closure with sums", "memory"} {
+			repo := repositories[name]
+			require.NoError(t, step.apply(repo), "%s: %s", step.name, name)
+
+			actual := snapshot(t, repo, transactionIDs...)
+			if expected == nil {
+				expected = actual
+				continue
+			}
+			assert.Equal(t, expected, actual, "%s: %s", step.name, name)
+		}
+	}
+
+	// Assert a transaction with children is still refused in reject mode
+	for name, repo := range repositories {
+		assert.Equal(t, ErrTransactionHasChildren, repo.Delete(ctx, 1, DeleteModeReject), name)
+	}
+}
+
+func TestRepositories_RefuseCascadeBeyondMaxDepth(t *testing.T) {
+	testRefuseCascadeBeyondMaxDepth(t, openTestDB)
+}
+
+// testRefuseCascadeBeyondMaxDepth checks that no repository implementation, the databases being opened by
+// openDB, deletes part of a subtree deeper than the maximum depth.
+func testRefuseCascadeBeyondMaxDepth(t *testing.T, openDB func(t *testing.T) *gorm.DB) {
+	ctx := context.Background()
+	repositories := testRepositories(t, openDB)
+
+	for _, name := range []string{"adjacency", "adjacency with sums", "closure", "closure with sums", "memory"} {
+		repo := repositories[name]
+
+		// A chain two levels deeper than the maximum, as left behind by a lowered MAX_TREE_DEPTH
+		transactionIDs := []uint{}
+		for transactionID := uint(1); transactionID <= 103; transactionID++ {
+			transaction := models.Transaction{Id: transactionID, Amount: decimal.RequireFromString("1"), Type: "car", Currency: "USD"}
+			if transactionID > 1 {
+				parentID := transactionID - 1
+				transaction.ParentID = &parentID
+			}
+			require.NoError(t, repo.Create(ctx, &transaction), name)
+			transactionIDs = append(transactionIDs, transactionID)
+		}
+
+		// Assert deleting the chain from its root is refused rather than leaving its deepest transactions behind
+		assert.ErrorIs(t, repo.Delete(ctx, 1, DeleteModeCascade), ErrMaxDepthExceeded, name)
+		remaining, err := repo.GetByIDs(ctx, transactionIDs)
+		require.NoError(t, err, name)
+		assert.Len(t, remaining, len(transactionIDs), name)
+
+		// Assert a subtree within the maximum depth is still deleted
+		require.NoError(t, repo.Delete(ctx, 3, DeleteModeCascade), name)
+		remaining, err = repo.GetByIDs(ctx, transactionIDs)
+		require.NoError(t, err, name)
+		assert.Len(t, remaining, 2, name)
+	}
+}
+
+func TestRepositories_ListFromFirstTransaction(t *testing.T) {
+	ctx := context.Background()
+	id := func(id uint) *uint { return &id }
+
+	for name, repo := range testRepositories(t, openTestDB) {
+		// The database repositories take an ID of 0 as unset, so the first transaction is stored directly
+		var sqlDB *gorm.DB
+		switch r := repo.(type) {
+		case *transactionRepository:
+			sqlDB = r.Db
+		case *closureTransactionRepository:
+			sqlDB = r.Db
+		}
+		for _, transactionID := range []uint{0, 1, 2} {
+			transaction := models.Transaction{Id: transactionID, Amount: decimal.RequireFromString("10"), Type: "car", Currency: "USD"}
+			if sqlDB != nil {
+				require.NoError(t, sqlDB.Exec(`INSERT INTO transactions (id, amount, type, currency) VALUES (?, ?, ?, ?)`,
+					transaction.Id, transaction.Amount, transaction.Type, transaction.Currency).Error, name)
+				continue
+			}
+			require.NoError(t, repo.Create(ctx, &transaction), name)
+		}
+
+		// Assert the first page starts at ID 0 and the next ones after their cursor
+		for _, tc := range []struct {
+			afterID  *uint
+			expected []uint
+		}{
+			{nil, []uint{0, 1}},
+			{id(0), []uint{1, 2}},
+			{id(1), []uint{2}},
+		} {
+			transactions, err := repo.List(ctx, tc.afterID, 2)
+			require.NoError(t, err, name)
+			ids := []uint{}
+			for _, transaction := range transactions {
+				ids = append(ids, transaction.Id)
+			}
+			assert.Equal(t, tc.expected, ids, name)
+		}
+	}
+}
+
+func TestRepositories_AuditFields(t *testing.T) {
+	ctx := context.Background()
+	id := func(id uint) *uint { return &id }
+	occurredAt := time.Date(2024, 1, 2, 3, 4, 5, 123456000, time.UTC)
+
+	for name, repo := range testRepositories(t, openTestDB) {
+		root := models.Transaction{Id: 1, Amount: decimal.RequireFromString("10"), Type: "car", Currency: "USD", OccurredAt: &occurredAt}
+		require.NoError(t, repo.Create(audit.WithActor(ctx, "alice"), &root), name)
+		child := models.Transaction{Id: 2, Amount: decimal.RequireFromString("5"), Type: "car", ParentID: id(1), Currency: "USD"}
+		require.NoError(t, repo.Create(ctx, &child), name)
+
+		// Assert the creation is recorded with the actor of the context, if any
+		stored, err := repo.GetByID(ctx, 1)
+		require.NoError(t, err, name)
+		require.NotNil(t, stored.CreatedAt, name)
+		assert.WithinDuration(t, *root.CreatedAt, *stored.CreatedAt, 0, name)
+		assert.WithinDuration(t, *stored.CreatedAt, *stored.UpdatedAt, 0, name)
+		assert.WithinDuration(t, occurredAt, *stored.OccurredAt, 0, name)
+		assert.Equal(t, "alice", *stored.CreatedBy, name)
+
+		// Assert tree reads carry the audit fields too
+		descendants, err := repo.GetDescendants(ctx, 1, 100)
+		require.NoError(t, err, name)
+		require.Len(t, descendants, 2, name)
+		assert.WithinDuration(t, *root.CreatedAt, *descendants[0].CreatedAt, 0, name)
+		assert.Equal(t, "alice", *descendants[0].CreatedBy, name)
+		assert.WithinDuration(t, *child.CreatedAt, *descendants[1].CreatedAt, 0, name)
+		assert.Nil(t, descendants[1].CreatedBy, name)
+		ancestors, err := repo.GetAncestors(ctx, 2)
+		require.NoError(t, err, name)
+		require.Len(t, ancestors, 1, name)
+		assert.WithinDuration(t, occurredAt, *ancestors[0].OccurredAt, 0, name)
+
+		// Assert updates keep the creation fields and move updated_at forward
+		update := models.Transaction{Id: 1, Amount: decimal.RequireFromString("12"), Type: "car", Currency: "USD"}
+		require.NoError(t, repo.Update(ctx, &update), name)
+		stored, err = repo.GetByID(ctx, 1)
+		require.NoError(t, err, name)
+		assert.WithinDuration(t, *root.CreatedAt, *stored.CreatedAt, 0, name)
+		assert.False(t, stored.UpdatedAt.Before(*stored.CreatedAt), name)
+		assert.Equal(t, "alice", *stored.CreatedBy, name)
+		assert.Nil(t, stored.OccurredAt, name)
+	}
+}
+
+func TestRebuild_MatchesMaintainedTables(t *testing.T) {
+	testRebuildMatchesMaintainedTables(t, openTestDB)
+}
+
+// testRebuildMatchesMaintainedTables checks, on databases opened by openDB, that rebuilding the closure and
+// descendant sums tables yields the rows the closure repository maintains.
+func testRebuildMatchesMaintainedTables(t *testing.T, openDB func(t *testing.T) *gorm.DB) {
+	ctx := context.Background()
+	id := func(id uint) *uint { return &id }
+
+	maintained := &closureTransactionRepository{&transactionRepository{Db: openDB(t), MaxDepth: 100, MaterializedSums: true}}
+	rebuilt := &transactionRepository{Db: openDB(t), MaxDepth: 100}
+	for _, repo := range []TransactionRepositoryI{maintained, rebuilt} {
+		for _, transaction := range []models.Transaction{
+			{Id: 1, Amount: decimal.RequireFromString("10"), Type: "car", Currency: "USD"},
+			{Id: 2, Amount: decimal.RequireFromString("5.5"), Type: "car", ParentID: id(1), Currency: "EUR"},
+			{Id: 3, Amount: decimal.RequireFromString("2"), Type: "car", ParentID: id(2), Currency: "USD"},
+			{Id: 4, Amount: decimal.RequireFromString("1"), Type: "car", ParentID: id(1), Currency: "USD"},
+		} {
+			transaction := transaction
+			require.NoError(t, repo.Create(ctx, &transaction))
+		}
+		require.NoError(t, repo.Update(ctx, &models.Transaction{Id: 3, Amount: decimal.RequireFromString("2"), Type: "car", ParentID: id(4), Currency: "USD"}))
+	}
+
+	// Rebuild the tables of the database written without maintaining them
+	_, err := RebuildClosure(ctx, rebuilt.Db, rebuilt.MaxDepth)
+	require.NoError(t, err)
+	_, err = rebuilt.RebuildDescendantSums(ctx)
+	require.NoError(t, err)
+
+	// Assert the rebuilt tables hold the same rows as the maintained ones
+	for _, query := range []string{
+		`SELECT ancestor_id || ':' || descendant_id || ':' || depth FROM transaction_closure ORDER BY ancestor_id, descendant_id`,
+		`SELECT transaction_id || ':' || currency || ':' || amount || ':' || transaction_count FROM transaction_descendant_sums ORDER BY transaction_id, currency`,
+	} {
+		var expected, actual []string
+		require.NoError(t, maintained.Db.Raw(query).Scan(&expected).Error)
+		require.NoError(t, rebuilt.Db.Raw(query).Scan(&actual).Error)
+		assert.NotEmpty(t, expected)
+		assert.Equal(t, expected, actual)
+	}
+}
+
+func TestClosure_RejectsCycles(t *testing.T) {
+	ctx := context.Background()
+	id := func(id uint) *uint { return &id }
+
+	repo := &closureTransactionRepository{&transactionRepository{Db: openTestDB(t), MaxDepth: 100}}
+	for _, transaction := range []models.Transaction{
+		{Id: 1, Amount: decimal.RequireFromString("10"), Type: "car", Currency: "USD"},
+		{Id: 2, Amount: decimal.RequireFromString("5"), Type: "car", ParentID: id(1), Currency: "USD"},
+		{Id: 3, Amount: decimal.RequireFromString("2"), Type: "car", ParentID: id(2), Currency: "USD"},
+	} {
+		transaction := transaction
+		require.NoError(t, repo.Create(ctx, &transaction))
+	}
+	before := snapshot(t, repo, 1, 2, 3)
+
+	// Assert moving a transaction under itself or one of its descendants is refused and changes nothing
+	for _, parentID := range []uint{1, 2, 3} {
+		err := repo.Update(ctx, &models.Transaction{Id: 1, Amount: decimal.RequireFromString("10"), Type: "car", ParentID: id(parentID), Currency: "USD"})
+		assert.Equal(t, ErrCycleDetected, err, "parent %d", parentID)
+	}
+	assert.Equal(t, before, snapshot(t, repo, 1, 2, 3))
+}
+
+func TestRebuild_RejectsCycles(t *testing.T) {
+	testRebuildRejectsCycles(t, openTestDB)
+}
+
+// testRebuildRejectsCycles checks, on a database opened by openDB, that rebuilds refuse cyclic trees.
+func testRebuildRejectsCycles(t *testing.T, openDB func(t *testing.T) *gorm.DB) {
+	ctx := context.Background()
+	id := func(id uint) *uint { return &id }
+
+	repo := &transactionRepository{Db: openDB(t), MaxDepth: 100}
+	for _, transaction := range []models.Transaction{
+		{Id: 1, Amount: decimal.RequireFromString("10"), Type: "car", Currency: "USD"},
+		{Id: 2, Amount: decimal.RequireFromString("5"), Type: "car", ParentID: id(1), Currency: "USD"},
+		{Id: 3, Amount: decimal.RequireFromString("2"), Type: "car", ParentID: id(2), Currency: "USD"},
+		{Id: 4, Amount: decimal.RequireFromString("1"), Type: "car", ParentID: id(3), Currency: "USD"},
+	} {
+		transaction := transaction
+		require.NoError(t, repo.Create(ctx, &transaction))
+	}
+	closureRows, err := RebuildClosure(ctx, repo.Db, repo.MaxDepth)
+	require.NoError(t, err)
+	sumRows, err := repo.RebuildDescendantSums(ctx)
+	require.NoError(t, err)
+
+	// Link the root under one of its descendants behind the back of the repository
+	require.NoError(t, repo.Db.Exec(`UPDATE transactions SET parent_id = 3 WHERE id = 1`).Error)
+
+	// Assert the rebuilds are refused and leave the tables as they were
+	_, err = RebuildClosure(ctx, repo.Db, repo.MaxDepth)
+	assert.ErrorIs(t, err, ErrCycleDetected)
+	_, err = repo.RebuildDescendantSums(ctx)
+	assert.ErrorIs(t, err, ErrCycleDetected)
+	for table, rows := range map[string]int64{"transaction_closure": closureRows, "transaction_descendant_sums": sumRows} {
+		var count int64
+		require.NoError(t, repo.Db.Table(table).Count(&count).Error)
+		assert.Equal(t, rows, count, table)
+	}
+}
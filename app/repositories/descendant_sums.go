@@ -0,0 +1,239 @@
+package repositories
+
+import (
+	"context"
+	"transaction_system/app/models"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// descendantSumsTable holds, for every transaction with descendants, the sum and count of its descendants'
+// amounts per currency. It is maintained by Create, Update and Delete when materialized sums are enabled.
+const descendantSumsTable = "transaction_descendant_sums"
+
+// sumDelta is a change to the descendant sums of a chain of transactions in one currency.
+type sumDelta struct {
+	Currency string
+	Amount   decimal.Decimal
+	Count    int64
+}
+
+// negate returns the delta that undoes d.
+func (d sumDelta) negate() sumDelta {
+	return sumDelta{Currency: d.Currency, Amount: d.Amount.Neg(), Count: -d.Count}
+}
+
+// ownDelta is the contribution of transaction itself to the descendant sums of its ancestors.
+func ownDelta(transaction *models.Transaction) sumDelta {
+	return sumDelta{Currency: transaction.Currency, Amount: transaction.Amount, Count: 1}
+}
+
+// descendantDeltas returns the descendant sums stored for a transaction as deltas, so that they can be
+// moved together with it.
+func (t *transactionRepository) descendantDeltas(tx *gorm.DB, transactionID uint) ([]sumDelta, error) {
+	var deltas []sumDelta
+	err := tx.Table(descendantSumsTable).
+		Select("currency, amount, transaction_count AS count").
+		Where("transaction_id = ?", transactionID).
+		Scan(&deltas).Error
+	return deltas, err
+}
+
+// shiftSums applies deltas to the descendant sums of startID and of each of its ancestors, dropping the
+// sums left without any transaction. A nil startID, standing for the parent of a root, is a no-op.
+func (t *transactionRepository) shiftSums(tx *gorm.DB, startID *uint, deltas ...sumDelta) error {
+	if startID == nil {
+		return nil
+	}
+
+	chain := treeWalk{
+		Direction: walkAncestors,
+		StartID:   *startID,
+		MaxDepth:  t.MaxDepth + 1,
+	}
+	if !exactSums(tx) {
+		return t.shiftSumsInGo(tx, chain, deltas)
+	}
+
+	for _, delta := range deltas {
+		if delta.Amount.IsZero() && delta.Count == 0 {
+			continue
+		}
+		// The values are cast as PostgreSQL types the parameters of an INSERT ... SELECT as text
+		query, args := chain.Build(`
+			INSERT INTO transaction_descendant_sums (transaction_id, currency, amount, transaction_count)
+			SELECT id, CAST(? AS CHAR(3)), CAST(? AS NUMERIC), CAST(? AS BIGINT) FROM TreeCTE WHERE is_cycle = 0
+			ON CONFLICT (transaction_id, currency) DO UPDATE SET
+				amount = transaction_descendant_sums.amount + EXCLUDED.amount,
+				transaction_count = transaction_descendant_sums.transaction_count + EXCLUDED.transaction_count
+		`, delta.Currency, delta.Amount, delta.Count)
+		if err := tx.Exec(query, args...).Error; err != nil {
+			return err
+		}
+	}
+
+	query, args := chain.Build(`
+		DELETE FROM transaction_descendant_sums
+		WHERE transaction_count <= 0 AND transaction_id IN (SELECT id FROM TreeCTE)
+	`)
+	return tx.Exec(query, args...).Error
+}
+
+// shiftSumsInGo applies deltas to the descendant sums of the transactions of chain, adding amounts up in Go
+// for databases that cannot add them exactly.
+func (t *transactionRepository) shiftSumsInGo(tx *gorm.DB, chain treeWalk, deltas []sumDelta) error {
+	query, args := chain.Build(`SELECT id FROM TreeCTE WHERE is_cycle = 0`)
+	var transactionIDs []uint
+	if err := tx.Raw(query, args...).Scan(&transactionIDs).Error; err != nil {
+		return err
+	}
+
+	for _, delta := range deltas {
+		var stored []struct {
+			TransactionID uint
+			Amount        decimal.Decimal
+			Count         int64
+		}
+		err := tx.Table(descendantSumsTable).
+			Select("transaction_id, amount, transaction_count AS count").
+			Where("currency = ? AND transaction_id IN ?", delta.Currency, transactionIDs).
+			Scan(&stored).Error
+		if err != nil {
+			return err
+		}
+		sums := make(map[uint]sumDelta, len(transactionIDs))
+		for _, row := range stored {
+			sums[row.TransactionID] = sumDelta{Currency: delta.Currency, Amount: row.Amount, Count: row.Count}
+		}
+
+		for _, transactionID := range transactionIDs {
+			sum := sums[transactionID]
+			sum.Amount = sum.Amount.Add(delta.Amount)
+			sum.Count += delta.Count
+			if sum.Count <= 0 {
+				err = tx.Exec(`DELETE FROM transaction_descendant_sums WHERE transaction_id = ? AND currency = ?`, transactionID, delta.Currency).Error
+			} else {
+				err = tx.Exec(`
+					INSERT INTO transaction_descendant_sums (transaction_id, currency, amount, transaction_count)
+					VALUES (?, ?, ?, ?)
+					ON CONFLICT (transaction_id, currency) DO UPDATE SET
+						amount = EXCLUDED.amount,
+						transaction_count = EXCLUDED.transaction_count
+				`, transactionID, delta.Currency, sum.Amount, sum.Count).Error
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// getMaterializedSum reads the descendant sums of a transaction maintained in descendantSumsTable.
+func (t *transactionRepository) getMaterializedSum(ctx context.Context, transactionID uint) (map[string]decimal.Decimal, error) {
+	var deltas []sumDelta
+	err := t.Db.WithContext(ctx).Table(descendantSumsTable).
+		Select("currency, amount, transaction_count AS count").
+		Where("transaction_id = ? AND transaction_count > 0", transactionID).
+		Scan(&deltas).Error
+	if err != nil {
+		return nil, err
+	}
+
+	subtotals := make(map[string]decimal.Decimal, len(deltas))
+	for _, delta := range deltas {
+		subtotals[delta.Currency] = delta.Amount
+	}
+	return subtotals, nil
+}
+
+// RebuildDescendantSums recomputes every materialized descendant sum from the transactions table, returning
+// the number of sums stored. It repairs sums that drifted, e.g. while materialized sums were disabled. The
+// sums are left untouched and ErrCycleDetected returned when the parent_id links form a cycle, which would
+// add transactions to their own sums over and over.
+func (t *transactionRepository) RebuildDescendantSums(ctx context.Context) (int64, error) {
+	var rows int64
+	err := t.Db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := checkAcyclic(tx, t.MaxDepth); err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM transaction_descendant_sums").Error; err != nil {
+			return err
+		}
+		if !exactSums(tx) {
+			var err error
+			rows, err = t.rebuildSumsInGo(tx)
+			return err
+		}
+
+		result := tx.Exec(`
+			INSERT INTO transaction_descendant_sums (transaction_id, currency, amount, transaction_count)
+			WITH RECURSIVE Closure AS (
+				SELECT parent_id AS ancestor_id, id AS descendant_id, 1 AS depth
+				FROM transactions
+				WHERE parent_id IS NOT NULL
+
+				UNION ALL
+
+				SELECT t.parent_id, Closure.descendant_id, Closure.depth + 1
+				FROM Closure
+				JOIN transactions t ON t.id = Closure.ancestor_id
+				WHERE t.parent_id IS NOT NULL AND Closure.depth < ?
+			)
+			SELECT Closure.ancestor_id, d.currency, SUM(d.amount), COUNT(*)
+			FROM Closure
+			JOIN transactions d ON d.id = Closure.descendant_id
+			GROUP BY Closure.ancestor_id, d.currency
+		`, t.MaxDepth+1)
+		rows = result.RowsAffected
+		return result.Error
+	})
+	return rows, err
+}
+
+// rebuildSumsInGo stores the descendant sums of every transaction, adding amounts up in Go for databases
+// that cannot add them exactly. It expects the descendant sums table to be empty and the parent_id links to
+// be free of cycles.
+func (t *transactionRepository) rebuildSumsInGo(tx *gorm.DB) (int64, error) {
+	var transactions []models.Transaction
+	if err := tx.Select("id, amount, parent_id, currency").Find(&transactions).Error; err != nil {
+		return 0, err
+	}
+	byID := make(map[uint]*models.Transaction, len(transactions))
+	for i := range transactions {
+		byID[transactions[i].Id] = &transactions[i]
+	}
+
+	// Add every transaction to each of its ancestors, up to the same depth as the SQL rebuild
+	type sumKey struct {
+		transactionID uint
+		currency      string
+	}
+	sums := make(map[sumKey]sumDelta)
+	for _, transaction := range transactions {
+		parentID := transaction.ParentID
+		for depth := 1; parentID != nil && depth <= t.MaxDepth+1; depth++ {
+			parent, exists := byID[*parentID]
+			if !exists {
+				break
+			}
+			key := sumKey{transactionID: parent.Id, currency: transaction.Currency}
+			sum := sums[key]
+			sum.Currency = transaction.Currency
+			sum.Amount = sum.Amount.Add(transaction.Amount)
+			sum.Count++
+			sums[key] = sum
+			parentID = parent.ParentID
+		}
+	}
+
+	for key, sum := range sums {
+		err := tx.Exec(`INSERT INTO transaction_descendant_sums (transaction_id, currency, amount, transaction_count) VALUES (?, ?, ?, ?)`,
+			key.transactionID, sum.Currency, sum.Amount, sum.Count).Error
+		if err != nil {
+			return 0, err
+		}
+	}
+	return int64(len(sums)), nil
+}
@@ -0,0 +1,37 @@
+package repositories
+
+import (
+	"errors"
+
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+)
+
+// sqliteDialect is the name of the SQLite dialector.
+const sqliteDialect = "sqlite"
+
+// isDuplicateKeyError reports whether err is a unique constraint violation raised by the database of tx.
+func isDuplicateKeyError(tx *gorm.DB, err error) bool {
+	if pgErr, ok := err.(*pq.Error); ok {
+		return pgErr.Code == DuplicateKeyViolationCode
+	}
+	if translator, ok := tx.Dialector.(gorm.ErrorTranslator); ok {
+		return errors.Is(translator.Translate(err), gorm.ErrDuplicatedKey)
+	}
+	return false
+}
+
+// exactSums reports whether the database of tx adds amounts exactly. SQLite keeps amounts as text, so that
+// no digit is lost, but would add them up as floating point numbers, so sums are computed in Go there.
+func exactSums(tx *gorm.DB) bool {
+	return tx.Dialector.Name() != sqliteDialect
+}
+
+// amountCondition returns a condition comparing the amount column with a bound amount using operator.
+// Amounts stored as text are compared as numbers.
+func amountCondition(tx *gorm.DB, operator string) string {
+	if exactSums(tx) {
+		return "amount " + operator + " ?"
+	}
+	return "CAST(amount AS REAL) " + operator + " CAST(? AS REAL)"
+}
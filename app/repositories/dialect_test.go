@@ -0,0 +1,63 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"transaction_system/app/models"
+)
+
+func TestSQLite_DuplicateTransaction(t *testing.T) {
+	testDuplicateTransaction(t, openTestDB)
+}
+
+// testDuplicateTransaction checks that every repository implementation, the databases being opened by
+// openDB, recognizes a duplicate transaction.
+func testDuplicateTransaction(t *testing.T, openDB func(t *testing.T) *gorm.DB) {
+	ctx := context.Background()
+	for name, repo := range testRepositories(t, openDB) {
+		transaction := models.Transaction{Id: 1, Amount: decimal.RequireFromString("10"), Type: "car", Currency: "USD"}
+		require.NoError(t, repo.Create(ctx, &transaction), name)
+
+		// Assert the unique violation is reported as a duplicate rather than a database error
+		duplicate := models.Transaction{Id: 1, Amount: decimal.RequireFromString("20"), Type: "car", Currency: "USD"}
+		assert.Equal(t, ErrTransactionAlreadyExist, repo.Create(ctx, &duplicate), name)
+	}
+}
+
+func TestSQLite_ExactAmounts(t *testing.T) {
+	testExactAmounts(t, openTestDB)
+}
+
+// testExactAmounts checks that every repository implementation, the databases being opened by openDB,
+// stores and adds up amounts exactly.
+func testExactAmounts(t *testing.T, openDB func(t *testing.T) *gorm.DB) {
+	ctx := context.Background()
+	id := func(id uint) *uint { return &id }
+	for name, repo := range testRepositories(t, openDB) {
+		for _, transaction := range []models.Transaction{
+			{Id: 1, Amount: decimal.RequireFromString("1"), Type: "car", Currency: "USD"},
+			{Id: 2, Amount: decimal.RequireFromString("0.1"), Type: "car", ParentID: id(1), Currency: "USD"},
+			{Id: 3, Amount: decimal.RequireFromString("0.2"), Type: "car", ParentID: id(2), Currency: "USD"},
+			{Id: 4, Amount: decimal.RequireFromString("12345678901234567.89"), Type: "car", ParentID: id(1), Currency: "EUR"},
+		} {
+			transaction := transaction
+			require.NoError(t, repo.Create(ctx, &transaction), name)
+		}
+
+		// Assert amounts are stored and added up without floating point rounding
+		stored, err := repo.GetByID(ctx, 4)
+		require.NoError(t, err, name)
+		assert.Equal(t, "12345678901234567.89", stored.Amount.String(), name)
+
+		sums, err := repo.GetTransitiveSum(ctx, 1)
+		require.NoError(t, err, name)
+		assert.Equal(t, "0.3", sums["USD"].String(), name)
+		assert.Equal(t, "12345678901234567.89", sums["EUR"].String(), name)
+	}
+}
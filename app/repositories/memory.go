@@ -0,0 +1,571 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"transaction_system/app/lib/config"
+	"transaction_system/app/models"
+
+	"github.com/shopspring/decimal"
+)
+
+// memoryState holds the transactions of an in-memory repository together with the indexes it reads from.
+type memoryState struct {
+	transactions map[uint]models.Transaction
+	// ids, children and byType hold transaction IDs in ascending order.
+	ids      []uint
+	children map[uint][]uint
+	byType   map[string][]uint
+	// savepoints counts the open savepoints, and undo holds a function reverting each change made while
+	// one is open, most recent last.
+	savepoints int
+	undo       []func()
+}
+
+func newMemoryState() *memoryState {
+	return &memoryState{
+		transactions: make(map[uint]models.Transaction),
+		children:     make(map[uint][]uint),
+		byType:       make(map[string][]uint),
+	}
+}
+
+// clone returns a copy of the state that can be modified without affecting s.
+func (s *memoryState) clone() *memoryState {
+	clone := &memoryState{
+		transactions: make(map[uint]models.Transaction, len(s.transactions)),
+		ids:          append([]uint(nil), s.ids...),
+		children:     make(map[uint][]uint, len(s.children)),
+		byType:       make(map[string][]uint, len(s.byType)),
+	}
+	for id, transaction := range s.transactions {
+		clone.transactions[id] = transaction
+	}
+	for id, childIDs := range s.children {
+		clone.children[id] = append([]uint(nil), childIDs...)
+	}
+	for transactionType, ids := range s.byType {
+		clone.byType[transactionType] = append([]uint(nil), ids...)
+	}
+	return clone
+}
+
+// savepoint opens a savepoint, returning the mark to release it with.
+func (s *memoryState) savepoint() int {
+	s.savepoints++
+	return len(s.undo)
+}
+
+// release closes the savepoint opened at mark, first reverting the changes made since when rollback is set.
+func (s *memoryState) release(mark int, rollback bool) {
+	if rollback {
+		undo := s.undo[mark:]
+		s.undo = s.undo[:mark]
+
+		// Reverting a change is itself a change that must not be recorded
+		open := s.savepoints
+		s.savepoints = 0
+		for i := len(undo) - 1; i >= 0; i-- {
+			undo[i]()
+		}
+		s.savepoints = open
+	}
+	s.savepoints--
+	if s.savepoints == 0 {
+		s.undo = nil
+	}
+}
+
+// record adds revert to the changes reverted when an open savepoint is rolled back.
+func (s *memoryState) record(revert func()) {
+	if s.savepoints > 0 {
+		s.undo = append(s.undo, revert)
+	}
+}
+
+// put stores transaction, replacing any stored transaction with the same ID.
+func (s *memoryState) put(transaction models.Transaction) {
+	if stored, exists := s.transactions[transaction.Id]; exists {
+		s.remove(stored.Id)
+	}
+	s.record(func() { s.remove(transaction.Id) })
+	s.transactions[transaction.Id] = transaction
+	s.ids = insertID(s.ids, transaction.Id)
+	s.byType[transaction.Type] = insertID(s.byType[transaction.Type], transaction.Id)
+	if transaction.ParentID != nil {
+		s.children[*transaction.ParentID] = insertID(s.children[*transaction.ParentID], transaction.Id)
+	}
+}
+
+// remove deletes the transaction with the given ID, leaving the index of its own children untouched.
+func (s *memoryState) remove(id uint) {
+	transaction, exists := s.transactions[id]
+	if !exists {
+		return
+	}
+	s.record(func() { s.put(transaction) })
+	delete(s.transactions, id)
+	s.ids = removeID(s.ids, id)
+	s.byType[transaction.Type] = removeID(s.byType[transaction.Type], id)
+	if len(s.byType[transaction.Type]) == 0 {
+		delete(s.byType, transaction.Type)
+	}
+	if transaction.ParentID != nil {
+		s.children[*transaction.ParentID] = removeID(s.children[*transaction.ParentID], id)
+		if len(s.children[*transaction.ParentID]) == 0 {
+			delete(s.children, *transaction.ParentID)
+		}
+	}
+}
+
+// forgetChildren drops the index of the children of the transaction with the given ID.
+func (s *memoryState) forgetChildren(id uint) {
+	childIDs, exists := s.children[id]
+	if !exists {
+		return
+	}
+	s.record(func() { s.children[id] = childIDs })
+	delete(s.children, id)
+}
+
+// insertID adds id to the sorted ids unless it is already there.
+func insertID(ids []uint, id uint) []uint {
+	i := sort.Search(len(ids), func(i int) bool { return ids[i] >= id })
+	if i < len(ids) && ids[i] == id {
+		return ids
+	}
+	ids = append(ids, 0)
+	copy(ids[i+1:], ids[i:])
+	ids[i] = id
+	return ids
+}
+
+// removeID removes id from the sorted ids.
+func removeID(ids []uint, id uint) []uint {
+	i := sort.Search(len(ids), func(i int) bool { return ids[i] >= id })
+	if i == len(ids) || ids[i] != id {
+		return ids
+	}
+	return append(ids[:i], ids[i+1:]...)
+}
+
+// memoryTransactionRepository keeps transactions in process memory, for running the service without a
+// database. It is safe for concurrent use. Writes, whether single or grouped with WithinTransaction, are
+// serialized, and a transaction works on a copy of the state that replaces it on commit. Nested transactions
+// are savepoints recording how to revert their changes rather than further copies.
+type memoryTransactionRepository struct {
+	// mu guards state; writeMu, nil within a transaction, serializes writers.
+	mu      *sync.RWMutex
+	writeMu *sync.Mutex
+	state   *memoryState
+	// MaxDepth bounds how far tree walks go before failing with ErrMaxDepthExceeded.
+	MaxDepth int
+}
+
+var (
+	memoryRepository     TransactionRepositoryI
+	memoryRepositoryOnce sync.Once
+)
+
+// sharedMemoryRepository returns the in-memory repository shared by the whole process.
+func sharedMemoryRepository() TransactionRepositoryI {
+	memoryRepositoryOnce.Do(func() {
+		memoryRepository = MakeMemoryTransactionRepository(config.Get().MaxTreeDepth)
+	})
+	return memoryRepository
+}
+
+// MakeMemoryTransactionRepository returns an empty in-memory repository.
+func MakeMemoryTransactionRepository(maxDepth int) TransactionRepositoryI {
+	return &memoryTransactionRepository{
+		mu:       &sync.RWMutex{},
+		writeMu:  &sync.Mutex{},
+		state:    newMemoryState(),
+		MaxDepth: maxDepth,
+	}
+}
+
+// read runs fn with the state locked for reading.
+func (r *memoryTransactionRepository) read(ctx context.Context, fn func(state *memoryState) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return fn(r.state)
+}
+
+// write runs fn with the state locked for writing. fn must check everything that may fail before it
+// modifies the state.
+func (r *memoryTransactionRepository) write(ctx context.Context, fn func(state *memoryState) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if r.writeMu != nil {
+		r.writeMu.Lock()
+		defer r.writeMu.Unlock()
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return fn(r.state)
+}
+
+// Create stores a new transaction.
+func (r *memoryTransactionRepository) Create(ctx context.Context, transaction *models.Transaction) error {
+	return r.write(ctx, func(state *memoryState) error {
+		if _, exists := state.transactions[transaction.Id]; exists {
+			return ErrTransactionAlreadyExist
+		}
+		if transaction.ParentID != nil {
+			if _, exists := state.transactions[*transaction.ParentID]; !exists {
+				return fmt.Errorf("parent transaction %d does not exist", *transaction.ParentID)
+			}
+		}
+		stampCreated(ctx, transaction)
+		state.put(*transaction)
+		return nil
+	})
+}
+
+// Update replaces a stored transaction with transaction, keeping the fields recording its creation.
+func (r *memoryTransactionRepository) Update(ctx context.Context, transaction *models.Transaction) error {
+	return r.write(ctx, func(state *memoryState) error {
+		if transaction.ParentID != nil {
+			if _, exists := state.transactions[*transaction.ParentID]; !exists {
+				return fmt.Errorf("parent transaction %d does not exist", *transaction.ParentID)
+			}
+		}
+		if stored, exists := state.transactions[transaction.Id]; exists {
+			transaction.CreatedAt = stored.CreatedAt
+			transaction.CreatedBy = stored.CreatedBy
+		}
+		stampUpdated(transaction)
+		state.put(*transaction)
+		return nil
+	})
+}
+
+// Delete removes a transaction, handling its children according to mode.
+func (r *memoryTransactionRepository) Delete(ctx context.Context, transactionID uint, mode DeleteMode) error {
+	return r.write(ctx, func(state *memoryState) error {
+		transaction, exists := state.transactions[transactionID]
+		if !exists {
+			return nil
+		}
+
+		switch mode {
+		case DeleteModeCascade:
+			// Deleting a truncated subtree would leave its deepest transactions behind
+			nodes, err := r.descendants(state, transactionID, r.MaxDepth+1)
+			if err != nil {
+				return err
+			}
+			for _, node := range nodes {
+				state.forgetChildren(node.Id)
+				state.remove(node.Id)
+			}
+			return nil
+		case DeleteModeReparent:
+			for _, childID := range append([]uint(nil), state.children[transactionID]...) {
+				child := state.transactions[childID]
+				child.ParentID = transaction.ParentID
+				stampUpdated(&child)
+				state.put(child)
+			}
+		default:
+			if len(state.children[transactionID]) > 0 {
+				return ErrTransactionHasChildren
+			}
+		}
+		state.remove(transactionID)
+		return nil
+	})
+}
+
+// WithinTransaction runs fn with a repository working on a copy of the state, which replaces the state
+// when fn returns nil and is discarded otherwise. Nested calls run fn on the enclosing transaction behind a
+// savepoint, reverting only the changes fn made when it fails.
+func (r *memoryTransactionRepository) WithinTransaction(ctx context.Context, fn func(transactionRepo TransactionRepositoryI) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if r.writeMu == nil {
+		r.mu.Lock()
+		mark := r.state.savepoint()
+		r.mu.Unlock()
+
+		err := fn(r)
+
+		r.mu.Lock()
+		r.state.release(mark, err != nil)
+		r.mu.Unlock()
+		return err
+	}
+
+	r.writeMu.Lock()
+	defer r.writeMu.Unlock()
+
+	r.mu.RLock()
+	tx := &memoryTransactionRepository{mu: &sync.RWMutex{}, state: r.state.clone(), MaxDepth: r.MaxDepth}
+	r.mu.RUnlock()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.state = tx.state
+	r.mu.Unlock()
+	return nil
+}
+
+// LockByID does nothing, as writers are already serialized for the whole of WithinTransaction.
+func (r *memoryTransactionRepository) LockByID(ctx context.Context, transactionID uint) error {
+	return ctx.Err()
+}
+
+// GetByID retrieves a transaction by its ID, returning nil when it does not exist.
+func (r *memoryTransactionRepository) GetByID(ctx context.Context, id uint) (*models.Transaction, error) {
+	var found *models.Transaction
+	err := r.read(ctx, func(state *memoryState) error {
+		if transaction, exists := state.transactions[id]; exists {
+			found = &transaction
+		}
+		return nil
+	})
+	return found, err
+}
+
+// GetByIDs retrieves the transactions with the given IDs, skipping IDs that do not exist.
+func (r *memoryTransactionRepository) GetByIDs(ctx context.Context, transactionIDs []uint) ([]models.Transaction, error) {
+	transactions := []models.Transaction{}
+	err := r.read(ctx, func(state *memoryState) error {
+		for _, id := range transactionIDs {
+			if transaction, exists := state.transactions[id]; exists {
+				transactions = append(transactions, transaction)
+			}
+		}
+		return nil
+	})
+	return transactions, err
+}
+
+// List retrieves a page of up to limit transactions with IDs greater than afterID, ordered by ID. A nil afterID
+// starts the page at the first transaction.
+func (r *memoryTransactionRepository) List(ctx context.Context, afterID *uint, limit int) ([]models.Transaction, error) {
+	var transactions []models.Transaction
+	err := r.read(ctx, func(state *memoryState) error {
+		start := 0
+		if afterID != nil {
+			start = sort.Search(len(state.ids), func(i int) bool { return state.ids[i] > *afterID })
+		}
+		for _, id := range state.ids[start:] {
+			if limit > 0 && len(transactions) == limit {
+				break
+			}
+			transactions = append(transactions, state.transactions[id])
+		}
+		return nil
+	})
+	return transactions, err
+}
+
+// GetByType retrieves a page of transactions of the given type, ordered by ID.
+func (r *memoryTransactionRepository) GetByType(ctx context.Context, query TypeQuery) ([]models.Transaction, error) {
+	var transactions []models.Transaction
+	err := r.read(ctx, func(state *memoryState) error {
+		ids := state.byType[query.Type]
+		for i := range ids {
+			id := ids[i]
+			if query.Descending {
+				id = ids[len(ids)-1-i]
+			}
+			if query.Limit > 0 && len(transactions) == query.Limit {
+				break
+			}
+			if query.AfterID != nil && ((!query.Descending && id <= *query.AfterID) || (query.Descending && id >= *query.AfterID)) {
+				continue
+			}
+
+			transaction := state.transactions[id]
+			if query.MinAmount != nil && transaction.Amount.LessThan(*query.MinAmount) {
+				continue
+			}
+			if query.MaxAmount != nil && transaction.Amount.GreaterThan(*query.MaxAmount) {
+				continue
+			}
+			if query.ParentID != nil && (transaction.ParentID == nil || *transaction.ParentID != *query.ParentID) {
+				continue
+			}
+			transactions = append(transactions, transaction)
+		}
+		return nil
+	})
+	return transactions, err
+}
+
+// GetChildIDs retrieves the IDs of the direct children of a transaction in ascending order.
+func (r *memoryTransactionRepository) GetChildIDs(ctx context.Context, transactionID uint) ([]uint, error) {
+	childIDs := []uint{}
+	err := r.read(ctx, func(state *memoryState) error {
+		childIDs = append(childIDs, state.children[transactionID]...)
+		return nil
+	})
+	return childIDs, err
+}
+
+// GetDepth retrieves the number of ancestors of a transaction, a root transaction having depth 0.
+func (r *memoryTransactionRepository) GetDepth(ctx context.Context, transactionID uint) (int, error) {
+	ancestors, err := r.GetAncestors(ctx, transactionID)
+	if err != nil {
+		return 0, err
+	}
+	return len(ancestors), nil
+}
+
+// GetDepths retrieves the depths of the transactions with the given IDs, skipping IDs that do not exist.
+func (r *memoryTransactionRepository) GetDepths(ctx context.Context, transactionIDs []uint) (map[uint]int, error) {
+	depths := make(map[uint]int, len(transactionIDs))
+	err := r.read(ctx, func(state *memoryState) error {
+		for _, transactionID := range transactionIDs {
+			if _, exists := state.transactions[transactionID]; !exists {
+				continue
+			}
+			ancestors, err := r.ancestors(state, transactionID)
+			if err != nil {
+				return err
+			}
+			depths[transactionID] = len(ancestors)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return depths, nil
+}
+
+// GetAncestors retrieves the ancestors of a transaction ordered from its parent up to the root.
+func (r *memoryTransactionRepository) GetAncestors(ctx context.Context, transactionID uint) ([]models.Transaction, error) {
+	var ancestors []models.Transaction
+	err := r.read(ctx, func(state *memoryState) error {
+		var err error
+		ancestors, err = r.ancestors(state, transactionID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ancestors, nil
+}
+
+// ancestors walks state from a transaction up to its root, returning its ancestors from its parent up.
+func (r *memoryTransactionRepository) ancestors(state *memoryState, transactionID uint) ([]models.Transaction, error) {
+	ancestors := []models.Transaction{}
+	transaction, exists := state.transactions[transactionID]
+	if !exists {
+		return ancestors, nil
+	}
+
+	visited := map[uint]bool{transactionID: true}
+	for transaction.ParentID != nil {
+		parent, exists := state.transactions[*transaction.ParentID]
+		if !exists {
+			break
+		}
+		if visited[parent.Id] {
+			return nil, ErrCycleDetected
+		}
+		if len(ancestors) == r.MaxDepth {
+			return nil, ErrMaxDepthExceeded
+		}
+		visited[parent.Id] = true
+		ancestors = append(ancestors, parent)
+		transaction = parent
+	}
+	return ancestors, nil
+}
+
+// descendants walks the subtree of a transaction breadth first down to maxDepth levels below it, returning
+// the nodes ordered by depth and then by ID. It fails with ErrMaxDepthExceeded, along with the nodes walked,
+// when the subtree goes deeper than MaxDepth.
+func (r *memoryTransactionRepository) descendants(state *memoryState, transactionID uint, maxDepth int) ([]models.TransactionNode, error) {
+	transaction, exists := state.transactions[transactionID]
+	if !exists {
+		return []models.TransactionNode{}, nil
+	}
+
+	nodes := []models.TransactionNode{{Transaction: transaction, Path: []uint{transactionID}}}
+	visited := map[uint]bool{transactionID: true}
+	for i := 0; i < len(nodes); i++ {
+		node := nodes[i]
+		if node.Depth == maxDepth {
+			continue
+		}
+		for _, childID := range state.children[node.Id] {
+			if visited[childID] {
+				return nil, ErrCycleDetected
+			}
+			visited[childID] = true
+			path := append(append([]uint(nil), node.Path...), childID)
+			nodes = append(nodes, models.TransactionNode{Transaction: state.transactions[childID], Depth: node.Depth + 1, Path: path})
+		}
+	}
+
+	for _, node := range nodes {
+		if node.Depth > r.MaxDepth {
+			return nodes, ErrMaxDepthExceeded
+		}
+	}
+	return nodes, nil
+}
+
+// GetDescendants retrieves a transaction and its descendants down to maxDepth levels below it,
+// ordered by depth and then by ID.
+func (r *memoryTransactionRepository) GetDescendants(ctx context.Context, transactionID uint, maxDepth int) ([]models.TransactionNode, error) {
+	// Walk one level past the configured maximum depth so that deeper trees are reported rather than truncated
+	if maxDepth > r.MaxDepth {
+		maxDepth = r.MaxDepth + 1
+	}
+
+	var nodes []models.TransactionNode
+	err := r.read(ctx, func(state *memoryState) error {
+		var err error
+		nodes, err = r.descendants(state, transactionID, maxDepth)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return nodes, nil
+}
+
+// GetTransitiveSum retrieves the sum of all transactions transitively linked by their parent_id to a given transaction ID,
+// keyed by currency.
+func (r *memoryTransactionRepository) GetTransitiveSum(ctx context.Context, transactionID uint) (map[string]decimal.Decimal, error) {
+	subtotals := make(map[string]decimal.Decimal)
+	err := r.read(ctx, func(state *memoryState) error {
+		nodes, err := r.descendants(state, transactionID, r.MaxDepth+1)
+		if err != nil {
+			return err
+		}
+		for _, node := range nodes {
+			if node.Id == transactionID {
+				continue
+			}
+			subtotals[node.Currency] = subtotals[node.Currency].Add(node.Amount)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return subtotals, nil
+}
+
+// RebuildDescendantSums has nothing to rebuild, as the in-memory repository computes sums on demand.
+func (r *memoryTransactionRepository) RebuildDescendantSums(ctx context.Context) (int64, error) {
+	return 0, nil
+}
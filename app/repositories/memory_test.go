@@ -0,0 +1,168 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"transaction_system/app/models"
+)
+
+func TestMemoryRepository_DuplicateKey(t *testing.T) {
+	ctx := context.Background()
+	repo := MakeMemoryTransactionRepository(10)
+
+	require.NoError(t, repo.Create(ctx, &models.Transaction{Id: 1, Amount: decimal.NewFromInt(10), Type: "car"}))
+
+	// Assert a second transaction with the same ID is refused like a duplicate key
+	err := repo.Create(ctx, &models.Transaction{Id: 1, Amount: decimal.NewFromInt(20), Type: "car"})
+	assert.Equal(t, ErrTransactionAlreadyExist, err)
+}
+
+func TestMemoryRepository_WithinTransaction(t *testing.T) {
+	ctx := context.Background()
+	repo := MakeMemoryTransactionRepository(10)
+	parentID := uint(1)
+
+	err := repo.WithinTransaction(ctx, func(txRepo TransactionRepositoryI) error {
+		require.NoError(t, txRepo.Create(ctx, &models.Transaction{Id: 1, Amount: decimal.NewFromInt(10), Type: "car"}))
+
+		// A failed nested transaction only rolls back its own changes
+		nestedErr := txRepo.WithinTransaction(ctx, func(nestedRepo TransactionRepositoryI) error {
+			require.NoError(t, nestedRepo.Create(ctx, &models.Transaction{Id: 2, Amount: decimal.NewFromInt(5), Type: "car", ParentID: &parentID}))
+			return errors.New("rolled back")
+		})
+		assert.Error(t, nestedErr)
+		return txRepo.Create(ctx, &models.Transaction{Id: 3, Amount: decimal.NewFromInt(1), Type: "car", ParentID: &parentID})
+	})
+	require.NoError(t, err)
+
+	// Assert the committed transaction holds everything but the rolled back change
+	childIDs, err := repo.GetChildIDs(ctx, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []uint{3}, childIDs)
+
+	// Assert a failed transaction leaves nothing behind
+	err = repo.WithinTransaction(ctx, func(txRepo TransactionRepositoryI) error {
+		require.NoError(t, txRepo.Delete(ctx, 1, DeleteModeCascade))
+		return errors.New("rolled back")
+	})
+	assert.Error(t, err)
+	transaction, err := repo.GetByID(ctx, 1)
+	assert.NoError(t, err)
+	assert.NotNil(t, transaction)
+}
+
+func TestMemoryRepository_SavepointRollsBackEveryChange(t *testing.T) {
+	ctx := context.Background()
+	repo := MakeMemoryTransactionRepository(10)
+	id := func(id uint) *uint { return &id }
+	for _, transaction := range []models.Transaction{
+		{Id: 1, Amount: decimal.NewFromInt(10), Type: "car", Currency: "USD"},
+		{Id: 2, Amount: decimal.NewFromInt(5), Type: "car", ParentID: id(1), Currency: "USD"},
+		{Id: 3, Amount: decimal.NewFromInt(2), Type: "shopping", ParentID: id(2), Currency: "USD"},
+		{Id: 4, Amount: decimal.NewFromInt(1), Type: "shopping", ParentID: id(1), Currency: "EUR"},
+		{Id: 5, Amount: decimal.NewFromInt(3), Type: "car", Currency: "USD"},
+	} {
+		transaction := transaction
+		require.NoError(t, repo.Create(ctx, &transaction))
+	}
+	transactionIDs := []uint{1, 2, 3, 4, 5, 6}
+
+	// state also records the indexes of every transaction and of transactions by type
+	state := func(repo TransactionRepositoryI) map[string]interface{} {
+		result := snapshot(t, repo, transactionIDs...)
+		for _, transactionType := range []string{"car", "shopping"} {
+			transactions, err := repo.GetByType(ctx, TypeQuery{Type: transactionType})
+			require.NoError(t, err)
+			result[transactionType] = transactions
+		}
+		transactions, err := repo.List(ctx, nil, 0)
+		require.NoError(t, err)
+		result["all"] = transactions
+		return result
+	}
+
+	err := repo.WithinTransaction(ctx, func(txRepo TransactionRepositoryI) error {
+		before := state(txRepo)
+
+		// A savepoint that fails after a successful nested one rolls back the changes of both
+		nestedErr := txRepo.WithinTransaction(ctx, func(nestedRepo TransactionRepositoryI) error {
+			require.NoError(t, nestedRepo.Update(ctx, &models.Transaction{Id: 3, Amount: decimal.NewFromInt(7), Type: "car", ParentID: id(5), Currency: "EUR"}))
+			require.NoError(t, nestedRepo.Delete(ctx, 2, DeleteModeReparent))
+			require.NoError(t, nestedRepo.WithinTransaction(ctx, func(innerRepo TransactionRepositoryI) error {
+				require.NoError(t, innerRepo.Delete(ctx, 1, DeleteModeCascade))
+				return innerRepo.Create(ctx, &models.Transaction{Id: 6, Amount: decimal.NewFromInt(4), Type: "car", ParentID: id(3), Currency: "USD"})
+			}))
+			return errors.New("rolled back")
+		})
+		assert.Error(t, nestedErr)
+
+		// Assert the transaction is back to the state it had before the savepoint
+		assert.Equal(t, before, state(txRepo))
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func TestMemoryRepository_GetByType(t *testing.T) {
+	ctx := context.Background()
+	repo := MakeMemoryTransactionRepository(10)
+	for _, id := range []uint{5, 1, 4, 2, 3} {
+		transactionType := "car"
+		if id == 4 {
+			transactionType = "shopping"
+		}
+		require.NoError(t, repo.Create(ctx, &models.Transaction{Id: id, Amount: decimal.NewFromInt(int64(id)), Type: transactionType}))
+	}
+
+	afterID := uint(5)
+	minAmount := decimal.NewFromInt(2)
+	transactions, err := repo.GetByType(ctx, TypeQuery{Type: "car", AfterID: &afterID, Descending: true, Limit: 2, MinAmount: &minAmount})
+
+	// Assert the type index is read in the requested order and filtered
+	assert.NoError(t, err)
+	assert.Len(t, transactions, 2)
+	assert.Equal(t, uint(3), transactions[0].Id)
+	assert.Equal(t, uint(2), transactions[1].Id)
+}
+
+func TestMemoryRepository_ConcurrentWrites(t *testing.T) {
+	ctx := context.Background()
+	repo := MakeMemoryTransactionRepository(10)
+	require.NoError(t, repo.Create(ctx, &models.Transaction{Id: 1, Amount: decimal.NewFromInt(0), Type: "car", Currency: "USD"}))
+
+	// Writers within and outside of transactions, and readers, run side by side
+	parentID := uint(1)
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(3)
+		id := uint(i + 2)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, repo.Create(ctx, &models.Transaction{Id: id, Amount: decimal.NewFromInt(1), Type: "car", ParentID: &parentID, Currency: "USD"}))
+		}()
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, repo.WithinTransaction(ctx, func(txRepo TransactionRepositoryI) error {
+				return txRepo.Create(ctx, &models.Transaction{Id: id + 1000, Amount: decimal.NewFromInt(1), Type: "car", ParentID: &parentID, Currency: "USD"})
+			}))
+		}()
+		go func() {
+			defer wg.Done()
+			_, err := repo.GetTransitiveSum(ctx, 1)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	// Assert no write was lost
+	subtotals, err := repo.GetTransitiveSum(ctx, 1)
+	assert.NoError(t, err)
+	assert.True(t, decimal.NewFromInt(100).Equal(subtotals["USD"]))
+}
@@ -5,10 +5,13 @@
 package mock_repositories
 
 import (
+	context "context"
 	reflect "reflect"
 	models "transaction_system/app/models"
+	repositories "transaction_system/app/repositories"
 
 	gomock "github.com/golang/mock/gomock"
+	decimal "github.com/shopspring/decimal"
 )
 
 // MockTransactionRepositoryI is a mock of TransactionRepositoryI interface.
@@ -35,60 +38,236 @@ func (m *MockTransactionRepositoryI) EXPECT() *MockTransactionRepositoryIMockRec
 }
 
 // Create mocks base method.
-func (m *MockTransactionRepositoryI) Create(transaction *models.Transaction) error {
+func (m *MockTransactionRepositoryI) Create(ctx context.Context, transaction *models.Transaction) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Create", transaction)
+	ret := m.ctrl.Call(m, "Create", ctx, transaction)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Create indicates an expected call of Create.
-func (mr *MockTransactionRepositoryIMockRecorder) Create(transaction interface{}) *gomock.Call {
+func (mr *MockTransactionRepositoryIMockRecorder) Create(ctx, transaction interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockTransactionRepositoryI)(nil).Create), transaction)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockTransactionRepositoryI)(nil).Create), ctx, transaction)
+}
+
+// Delete mocks base method.
+func (m *MockTransactionRepositoryI) Delete(ctx context.Context, transactionID uint, mode repositories.DeleteMode) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", ctx, transactionID, mode)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockTransactionRepositoryIMockRecorder) Delete(ctx, transactionID, mode interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockTransactionRepositoryI)(nil).Delete), ctx, transactionID, mode)
+}
+
+// GetAncestors mocks base method.
+func (m *MockTransactionRepositoryI) GetAncestors(ctx context.Context, transactionID uint) ([]models.Transaction, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAncestors", ctx, transactionID)
+	ret0, _ := ret[0].([]models.Transaction)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAncestors indicates an expected call of GetAncestors.
+func (mr *MockTransactionRepositoryIMockRecorder) GetAncestors(ctx, transactionID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAncestors", reflect.TypeOf((*MockTransactionRepositoryI)(nil).GetAncestors), ctx, transactionID)
 }
 
 // GetByID mocks base method.
-func (m *MockTransactionRepositoryI) GetByID(transactionID uint) (*models.Transaction, error) {
+func (m *MockTransactionRepositoryI) GetByID(ctx context.Context, transactionID uint) (*models.Transaction, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetByID", transactionID)
+	ret := m.ctrl.Call(m, "GetByID", ctx, transactionID)
 	ret0, _ := ret[0].(*models.Transaction)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetByID indicates an expected call of GetByID.
-func (mr *MockTransactionRepositoryIMockRecorder) GetByID(transactionID interface{}) *gomock.Call {
+func (mr *MockTransactionRepositoryIMockRecorder) GetByID(ctx, transactionID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByID", reflect.TypeOf((*MockTransactionRepositoryI)(nil).GetByID), ctx, transactionID)
+}
+
+// GetByIDs mocks base method.
+func (m *MockTransactionRepositoryI) GetByIDs(ctx context.Context, transactionIDs []uint) ([]models.Transaction, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByIDs", ctx, transactionIDs)
+	ret0, _ := ret[0].([]models.Transaction)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByIDs indicates an expected call of GetByIDs.
+func (mr *MockTransactionRepositoryIMockRecorder) GetByIDs(ctx, transactionIDs interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByID", reflect.TypeOf((*MockTransactionRepositoryI)(nil).GetByID), transactionID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByIDs", reflect.TypeOf((*MockTransactionRepositoryI)(nil).GetByIDs), ctx, transactionIDs)
 }
 
 // GetByType mocks base method.
-func (m *MockTransactionRepositoryI) GetByType(transactionType string) ([]models.Transaction, error) {
+func (m *MockTransactionRepositoryI) GetByType(ctx context.Context, query repositories.TypeQuery) ([]models.Transaction, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetByType", transactionType)
+	ret := m.ctrl.Call(m, "GetByType", ctx, query)
 	ret0, _ := ret[0].([]models.Transaction)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetByType indicates an expected call of GetByType.
-func (mr *MockTransactionRepositoryIMockRecorder) GetByType(transactionType interface{}) *gomock.Call {
+func (mr *MockTransactionRepositoryIMockRecorder) GetByType(ctx, query interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByType", reflect.TypeOf((*MockTransactionRepositoryI)(nil).GetByType), ctx, query)
+}
+
+// GetChildIDs mocks base method.
+func (m *MockTransactionRepositoryI) GetChildIDs(ctx context.Context, transactionID uint) ([]uint, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetChildIDs", ctx, transactionID)
+	ret0, _ := ret[0].([]uint)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetChildIDs indicates an expected call of GetChildIDs.
+func (mr *MockTransactionRepositoryIMockRecorder) GetChildIDs(ctx, transactionID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetChildIDs", reflect.TypeOf((*MockTransactionRepositoryI)(nil).GetChildIDs), ctx, transactionID)
+}
+
+// GetDepth mocks base method.
+func (m *MockTransactionRepositoryI) GetDepth(ctx context.Context, transactionID uint) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDepth", ctx, transactionID)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDepth indicates an expected call of GetDepth.
+func (mr *MockTransactionRepositoryIMockRecorder) GetDepth(ctx, transactionID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDepth", reflect.TypeOf((*MockTransactionRepositoryI)(nil).GetDepth), ctx, transactionID)
+}
+
+// GetDepths mocks base method.
+func (m *MockTransactionRepositoryI) GetDepths(ctx context.Context, transactionIDs []uint) (map[uint]int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDepths", ctx, transactionIDs)
+	ret0, _ := ret[0].(map[uint]int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDepths indicates an expected call of GetDepths.
+func (mr *MockTransactionRepositoryIMockRecorder) GetDepths(ctx, transactionIDs interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByType", reflect.TypeOf((*MockTransactionRepositoryI)(nil).GetByType), transactionType)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDepths", reflect.TypeOf((*MockTransactionRepositoryI)(nil).GetDepths), ctx, transactionIDs)
+}
+
+// GetDescendants mocks base method.
+func (m *MockTransactionRepositoryI) GetDescendants(ctx context.Context, transactionID uint, maxDepth int) ([]models.TransactionNode, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDescendants", ctx, transactionID, maxDepth)
+	ret0, _ := ret[0].([]models.TransactionNode)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDescendants indicates an expected call of GetDescendants.
+func (mr *MockTransactionRepositoryIMockRecorder) GetDescendants(ctx, transactionID, maxDepth interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDescendants", reflect.TypeOf((*MockTransactionRepositoryI)(nil).GetDescendants), ctx, transactionID, maxDepth)
 }
 
 // GetTransitiveSum mocks base method.
-func (m *MockTransactionRepositoryI) GetTransitiveSum(transactionID uint) (float64, error) {
+func (m *MockTransactionRepositoryI) GetTransitiveSum(ctx context.Context, transactionID uint) (map[string]decimal.Decimal, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetTransitiveSum", transactionID)
-	ret0, _ := ret[0].(float64)
+	ret := m.ctrl.Call(m, "GetTransitiveSum", ctx, transactionID)
+	ret0, _ := ret[0].(map[string]decimal.Decimal)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetTransitiveSum indicates an expected call of GetTransitiveSum.
-func (mr *MockTransactionRepositoryIMockRecorder) GetTransitiveSum(transactionID interface{}) *gomock.Call {
+func (mr *MockTransactionRepositoryIMockRecorder) GetTransitiveSum(ctx, transactionID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTransitiveSum", reflect.TypeOf((*MockTransactionRepositoryI)(nil).GetTransitiveSum), ctx, transactionID)
+}
+
+// List mocks base method.
+func (m *MockTransactionRepositoryI) List(ctx context.Context, afterID *uint, limit int) ([]models.Transaction, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", ctx, afterID, limit)
+	ret0, _ := ret[0].([]models.Transaction)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockTransactionRepositoryIMockRecorder) List(ctx, afterID, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockTransactionRepositoryI)(nil).List), ctx, afterID, limit)
+}
+
+// LockByID mocks base method.
+func (m *MockTransactionRepositoryI) LockByID(ctx context.Context, transactionID uint) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LockByID", ctx, transactionID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// LockByID indicates an expected call of LockByID.
+func (mr *MockTransactionRepositoryIMockRecorder) LockByID(ctx, transactionID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LockByID", reflect.TypeOf((*MockTransactionRepositoryI)(nil).LockByID), ctx, transactionID)
+}
+
+// RebuildDescendantSums mocks base method.
+func (m *MockTransactionRepositoryI) RebuildDescendantSums(ctx context.Context) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RebuildDescendantSums", ctx)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RebuildDescendantSums indicates an expected call of RebuildDescendantSums.
+func (mr *MockTransactionRepositoryIMockRecorder) RebuildDescendantSums(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RebuildDescendantSums", reflect.TypeOf((*MockTransactionRepositoryI)(nil).RebuildDescendantSums), ctx)
+}
+
+// Update mocks base method.
+func (m *MockTransactionRepositoryI) Update(ctx context.Context, transaction *models.Transaction) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", ctx, transaction)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockTransactionRepositoryIMockRecorder) Update(ctx, transaction interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockTransactionRepositoryI)(nil).Update), ctx, transaction)
+}
+
+// WithinTransaction mocks base method.
+func (m *MockTransactionRepositoryI) WithinTransaction(ctx context.Context, fn func(repositories.TransactionRepositoryI) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WithinTransaction", ctx, fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WithinTransaction indicates an expected call of WithinTransaction.
+func (mr *MockTransactionRepositoryIMockRecorder) WithinTransaction(ctx, fn interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTransitiveSum", reflect.TypeOf((*MockTransactionRepositoryI)(nil).GetTransitiveSum), transactionID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WithinTransaction", reflect.TypeOf((*MockTransactionRepositoryI)(nil).WithinTransaction), ctx, fn)
 }
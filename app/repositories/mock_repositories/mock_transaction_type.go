@@ -0,0 +1,80 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./transaction_type.go
+
+// Package mock_repositories is a generated GoMock package.
+package mock_repositories
+
+import (
+	context "context"
+	reflect "reflect"
+	models "transaction_system/app/models"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockTransactionTypeRepositoryI is a mock of TransactionTypeRepositoryI interface.
+type MockTransactionTypeRepositoryI struct {
+	ctrl     *gomock.Controller
+	recorder *MockTransactionTypeRepositoryIMockRecorder
+}
+
+// MockTransactionTypeRepositoryIMockRecorder is the mock recorder for MockTransactionTypeRepositoryI.
+type MockTransactionTypeRepositoryIMockRecorder struct {
+	mock *MockTransactionTypeRepositoryI
+}
+
+// NewMockTransactionTypeRepositoryI creates a new mock instance.
+func NewMockTransactionTypeRepositoryI(ctrl *gomock.Controller) *MockTransactionTypeRepositoryI {
+	mock := &MockTransactionTypeRepositoryI{ctrl: ctrl}
+	mock.recorder = &MockTransactionTypeRepositoryIMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockTransactionTypeRepositoryI) EXPECT() *MockTransactionTypeRepositoryIMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m *MockTransactionTypeRepositoryI) Create(ctx context.Context, transactionType *models.TransactionType) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", ctx, transactionType)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockTransactionTypeRepositoryIMockRecorder) Create(ctx, transactionType interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockTransactionTypeRepositoryI)(nil).Create), ctx, transactionType)
+}
+
+// Exists mocks base method.
+func (m *MockTransactionTypeRepositoryI) Exists(ctx context.Context, name string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Exists", ctx, name)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Exists indicates an expected call of Exists.
+func (mr *MockTransactionTypeRepositoryIMockRecorder) Exists(ctx, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Exists", reflect.TypeOf((*MockTransactionTypeRepositoryI)(nil).Exists), ctx, name)
+}
+
+// List mocks base method.
+func (m *MockTransactionTypeRepositoryI) List(ctx context.Context) ([]models.TransactionType, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", ctx)
+	ret0, _ := ret[0].([]models.TransactionType)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockTransactionTypeRepositoryIMockRecorder) List(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockTransactionTypeRepositoryI)(nil).List), ctx)
+}
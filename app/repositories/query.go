@@ -0,0 +1,73 @@
+package repositories
+
+import (
+	"fmt"
+	"strings"
+)
+
+// walkDirection selects whether a tree walk follows children or parents.
+type walkDirection int
+
+const (
+	// walkDescendants follows parent_id links downwards, from a transaction to its children.
+	walkDescendants walkDirection = iota
+	// walkAncestors follows parent_id links upwards, from a transaction to its parent.
+	walkAncestors
+)
+
+// treeWalk describes a recursive walk of the transaction tree starting at one transaction.
+// Every row of the walk carries its depth, the path of IDs leading to it as "/1/2/3/", an
+// is_cycle flag set on a row whose ID was already visited, which also stops the walk there,
+// and the start_id of the transaction the walk started from.
+type treeWalk struct {
+	Direction walkDirection
+	StartID   uint
+	// StartIDs, when set, replaces StartID with several transactions walked from at once.
+	StartIDs []uint
+	// MaxDepth is the deepest level produced by the walk, the starting transaction being at depth 0.
+	MaxDepth int
+	// Columns lists the transactions columns carried by every row in addition to id and parent_id.
+	Columns []string
+}
+
+// Build returns a WITH RECURSIVE query that exposes the walk as TreeCTE to body, together with the
+// arguments to bind. Only column names are written into the query; every value is a bound parameter,
+// and args are the parameters referenced by body.
+func (w treeWalk) Build(body string, args ...interface{}) (string, []interface{}) {
+	columns := append([]string{"id", "parent_id"}, w.Columns...)
+
+	baseColumns := strings.Join(columns, ", ")
+	recursiveColumns := "t." + strings.Join(columns, ", t.")
+
+	join := "t.parent_id = TreeCTE.id"
+	if w.Direction == walkAncestors {
+		join = "t.id = TreeCTE.parent_id"
+	}
+
+	start, startArg := "id = ?", interface{}(w.StartID)
+	if len(w.StartIDs) > 0 {
+		start, startArg = "id IN ?", w.StartIDs
+	}
+
+	query := fmt.Sprintf(`
+		WITH RECURSIVE TreeCTE AS (
+			SELECT %s, 0 AS depth,
+				'/' || CAST(id AS TEXT) || '/' AS path, 0 AS is_cycle, id AS start_id
+			FROM transactions
+			WHERE %s
+
+			UNION ALL
+
+			SELECT %s, TreeCTE.depth + 1,
+				TreeCTE.path || CAST(t.id AS TEXT) || '/',
+				CASE WHEN TreeCTE.path LIKE '%%/' || CAST(t.id AS TEXT) || '/%%' THEN 1 ELSE 0 END,
+				TreeCTE.start_id
+			FROM transactions t
+			JOIN TreeCTE ON %s
+			WHERE TreeCTE.is_cycle = 0 AND TreeCTE.depth < ?
+		)
+		%s
+	`, baseColumns, start, recursiveColumns, join, body)
+
+	return query, append([]interface{}{startArg, w.MaxDepth}, args...)
+}
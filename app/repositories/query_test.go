@@ -0,0 +1,48 @@
+package repositories
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTreeWalkBuild_BindsValues(t *testing.T) {
+	query, args := treeWalk{
+		Direction: walkDescendants,
+		StartID:   4242,
+		MaxDepth:  17,
+		Columns:   []string{"amount"},
+	}.Build(`SELECT id FROM TreeCTE WHERE depth > ?`, 0)
+
+	// Assert values are passed as arguments rather than written into the query
+	assert.NotContains(t, query, "4242")
+	assert.NotContains(t, query, "17")
+	assert.Equal(t, 3, strings.Count(query, "?"))
+	assert.Equal(t, []interface{}{uint(4242), 17, 0}, args)
+	assert.Contains(t, query, "JOIN TreeCTE ON t.parent_id = TreeCTE.id")
+}
+
+func TestTreeWalkBuild_Ancestors(t *testing.T) {
+	query, _ := treeWalk{
+		Direction: walkAncestors,
+		StartID:   1,
+		MaxDepth:  1,
+	}.Build(`SELECT id FROM TreeCTE`)
+
+	// Assert the walk follows parent links upwards
+	assert.Contains(t, query, "JOIN TreeCTE ON t.id = TreeCTE.parent_id")
+	assert.Contains(t, query, "SELECT id, parent_id, 0 AS depth")
+}
+
+func TestTreeWalkBuild_StartIDs(t *testing.T) {
+	query, args := treeWalk{
+		Direction: walkAncestors,
+		StartIDs:  []uint{1, 2},
+		MaxDepth:  3,
+	}.Build(`SELECT start_id, MAX(depth) FROM TreeCTE GROUP BY start_id`)
+
+	// Assert the walk starts from every transaction, bound as a single list
+	assert.Contains(t, query, "WHERE id IN ?")
+	assert.Equal(t, []interface{}{[]uint{1, 2}, 3}, args)
+}
@@ -1,13 +1,22 @@
 package repositories
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"github.com/lib/pq"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"transaction_system/app/lib/apperror"
+	"transaction_system/app/lib/audit"
+	"transaction_system/app/lib/config"
 	"transaction_system/app/lib/db"
 	"transaction_system/app/models"
 
+	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 //go:generate mockgen -source=./transaction.go -destination=mock_repositories/mock_transaction.go -package=mock_repositories
@@ -17,38 +26,274 @@ const (
 	DuplicateKeyViolationCode = "23505"
 )
 
-var ErrTransactionAlreadyExist = errors.New("transaction with the same ID already exists")
+var ErrTransactionAlreadyExist = apperror.New("transaction_already_exists", http.StatusConflict, "transaction with the same ID already exists")
+var ErrTransactionHasChildren = apperror.New("transaction_has_children", http.StatusConflict, "transaction has child transactions")
+var ErrCycleDetected = apperror.New("cycle_detected", http.StatusUnprocessableEntity, "transaction hierarchy contains a cycle")
+var ErrMaxDepthExceeded = apperror.New("max_depth_exceeded", http.StatusUnprocessableEntity, "transaction hierarchy exceeds the maximum depth")
+
+// DeleteMode controls what happens to the children of a deleted transaction.
+type DeleteMode string
+
+const (
+	// DeleteModeReject refuses to delete a transaction that has children.
+	DeleteModeReject DeleteMode = "reject"
+	// DeleteModeCascade deletes the transaction together with all of its descendants.
+	DeleteModeCascade DeleteMode = "cascade"
+	// DeleteModeReparent moves the children of the transaction to its parent before deleting it.
+	DeleteModeReparent DeleteMode = "reparent"
+)
+
+// TypeQuery selects a page of transactions of one type using keyset pagination on the transaction ID.
+type TypeQuery struct {
+	Type string
+	// AfterID, when set, restricts the page to IDs that come after it in the requested order.
+	AfterID    *uint
+	Descending bool
+	// Limit is the maximum number of transactions returned, zero meaning no limit.
+	Limit     int
+	MinAmount *decimal.Decimal
+	MaxAmount *decimal.Decimal
+	ParentID  *uint
+}
 
 type TransactionRepositoryI interface {
-	Create(transaction *models.Transaction) error
-	GetByID(transactionID uint) (*models.Transaction, error)
-	GetByType(transactionType string) ([]models.Transaction, error)
-	GetTransitiveSum(transactionID uint) (float64, error)
+	Create(ctx context.Context, transaction *models.Transaction) error
+	Update(ctx context.Context, transaction *models.Transaction) error
+	Delete(ctx context.Context, transactionID uint, mode DeleteMode) error
+	WithinTransaction(ctx context.Context, fn func(transactionRepo TransactionRepositoryI) error) error
+	LockByID(ctx context.Context, transactionID uint) error
+	GetByID(ctx context.Context, transactionID uint) (*models.Transaction, error)
+	GetByIDs(ctx context.Context, transactionIDs []uint) ([]models.Transaction, error)
+	List(ctx context.Context, afterID *uint, limit int) ([]models.Transaction, error)
+	GetByType(ctx context.Context, query TypeQuery) ([]models.Transaction, error)
+	GetChildIDs(ctx context.Context, transactionID uint) ([]uint, error)
+	GetDepth(ctx context.Context, transactionID uint) (int, error)
+	GetDepths(ctx context.Context, transactionIDs []uint) (map[uint]int, error)
+	GetDescendants(ctx context.Context, transactionID uint, maxDepth int) ([]models.TransactionNode, error)
+	GetAncestors(ctx context.Context, transactionID uint) ([]models.Transaction, error)
+	GetTransitiveSum(ctx context.Context, transactionID uint) (map[string]decimal.Decimal, error)
+	RebuildDescendantSums(ctx context.Context) (int64, error)
 }
 
 type transactionRepository struct {
 	Db *gorm.DB
+	// MaxDepth bounds how far recursive queries walk up or down a tree before failing with ErrMaxDepthExceeded.
+	MaxDepth int
+	// MaterializedSums makes writes maintain the descendant sums table and GetTransitiveSum read from it.
+	MaterializedSums bool
 }
 
 func NewTransactionRepository() TransactionRepositoryI {
-	return &transactionRepository{Db: db.Get()}
+	if config.Get().RepositoryMode == config.RepositoryModeMemory {
+		return sharedMemoryRepository()
+	}
+
+	repository := &transactionRepository{
+		Db:               db.Get(),
+		MaxDepth:         config.Get().MaxTreeDepth,
+		MaterializedSums: config.Get().MaterializedSums,
+	}
+	if config.Get().RepositoryMode == config.RepositoryModeClosure {
+		return &closureTransactionRepository{transactionRepository: repository}
+	}
+	return repository
 }
 
-// Create inserts a new transaction into the database.
-func (t *transactionRepository) Create(transaction *models.Transaction) error {
-	if err := t.Db.Create(transaction).Error; err != nil {
-		if pgErr, ok := err.(*pq.Error); ok && pgErr.Code == DuplicateKeyViolationCode {
+// bind returns a repository with the same settings running its queries on tx.
+func (t *transactionRepository) bind(tx *gorm.DB) *transactionRepository {
+	return &transactionRepository{Db: tx, MaxDepth: t.MaxDepth, MaterializedSums: t.MaterializedSums}
+}
+
+// Create inserts a new transaction into the database, adding its amount to the descendant sums of its
+// ancestors in the same database transaction when materialized sums are enabled.
+func (t *transactionRepository) Create(ctx context.Context, transaction *models.Transaction) error {
+	stampCreated(ctx, transaction)
+	if !t.MaterializedSums {
+		return t.insert(t.Db.WithContext(ctx), transaction)
+	}
+	return t.Db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := t.insert(tx, transaction); err != nil {
+			return err
+		}
+		return t.shiftSums(tx, transaction.ParentID, ownDelta(transaction))
+	})
+}
+
+func (t *transactionRepository) insert(tx *gorm.DB, transaction *models.Transaction) error {
+	if err := tx.Create(transaction).Error; err != nil {
+		if isDuplicateKeyError(tx, err) {
 			return ErrTransactionAlreadyExist
 		}
-		return err
+		return fmt.Errorf("inserting transaction %d: %w", transaction.Id, err)
 	}
 	return nil
 }
 
+// save writes every field of an existing transaction except created_at and created_by, which never change.
+func save(tx *gorm.DB, transaction *models.Transaction) error {
+	return tx.Omit("created_at", "created_by").Save(transaction).Error
+}
+
+// reparentChildren moves the children of a transaction under parentID.
+func reparentChildren(tx *gorm.DB, transactionID uint, parentID *uint) error {
+	return tx.Model(&models.Transaction{}).
+		Where("parent_id = ?", transactionID).
+		Updates(map[string]interface{}{"parent_id": parentID, "updated_at": auditTime()}).Error
+}
+
+// auditTime returns the current time in UTC, at the microsecond precision of the timestamp columns.
+func auditTime() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
+
+// stampCreated sets the audit fields of a transaction about to be inserted: both timestamps to the current
+// time and its creator to the actor of ctx.
+func stampCreated(ctx context.Context, transaction *models.Transaction) {
+	now := auditTime()
+	transaction.CreatedAt = &now
+	transaction.UpdatedAt = &now
+	transaction.CreatedBy = nil
+	if actor := audit.Actor(ctx); actor != "" {
+		transaction.CreatedBy = &actor
+	}
+}
+
+// stampUpdated sets the updated_at timestamp of a transaction about to be saved to the current time.
+func stampUpdated(transaction *models.Transaction) {
+	now := auditTime()
+	transaction.UpdatedAt = &now
+}
+
+// Update saves every field of an existing transaction to the database, except those recording its creation.
+// When materialized sums are enabled, the transaction and its descendants are taken out of the sums of its
+// former ancestors and added to those of its new ones in the same database transaction.
+func (t *transactionRepository) Update(ctx context.Context, transaction *models.Transaction) error {
+	stampUpdated(transaction)
+	if !t.MaterializedSums {
+		return save(t.Db.WithContext(ctx), transaction)
+	}
+	return t.Db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var stored models.Transaction
+		if err := tx.Where("id = ?", transaction.Id).First(&stored).Error; err != nil {
+			return err
+		}
+		descendants, err := t.descendantDeltas(tx, transaction.Id)
+		if err != nil {
+			return err
+		}
+		if err := save(tx, transaction); err != nil {
+			return err
+		}
+
+		removed := []sumDelta{ownDelta(&stored).negate()}
+		for _, delta := range descendants {
+			removed = append(removed, delta.negate())
+		}
+		if err := t.shiftSums(tx, stored.ParentID, removed...); err != nil {
+			return err
+		}
+		return t.shiftSums(tx, transaction.ParentID, append(descendants, ownDelta(transaction))...)
+	})
+}
+
+// Delete removes a transaction from the database, handling its children according to mode.
+func (t *transactionRepository) Delete(ctx context.Context, transactionID uint, mode DeleteMode) error {
+	return t.Db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if t.MaterializedSums {
+			if err := t.removeFromSums(tx, transactionID, mode); err != nil {
+				return err
+			}
+		}
+
+		switch mode {
+		case DeleteModeCascade:
+			subtree := treeWalk{
+				Direction: walkDescendants,
+				StartID:   transactionID,
+				MaxDepth:  t.MaxDepth + 1,
+			}
+
+			// Deleting a truncated subtree would leave its deepest transactions behind
+			var rows []treeRow
+			query, args := subtree.Build(`SELECT id, depth, is_cycle FROM TreeCTE WHERE is_cycle = 1 OR depth > ?`, t.MaxDepth)
+			if err := tx.Raw(query, args...).Scan(&rows).Error; err != nil {
+				return err
+			}
+			if err := t.checkTreeRows(rows); err != nil {
+				return err
+			}
+
+			if t.MaterializedSums {
+				query, args := subtree.Build(`DELETE FROM transaction_descendant_sums WHERE transaction_id IN (SELECT id FROM TreeCTE WHERE is_cycle = 0)`)
+				if err := tx.Exec(query, args...).Error; err != nil {
+					return err
+				}
+			}
+			query, args = subtree.Build(`DELETE FROM transactions WHERE id IN (SELECT id FROM TreeCTE WHERE is_cycle = 0)`)
+			return tx.Exec(query, args...).Error
+		case DeleteModeReparent:
+			var transaction models.Transaction
+			if err := tx.Where("id = ?", transactionID).First(&transaction).Error; err != nil {
+				return err
+			}
+			if err := reparentChildren(tx, transactionID, transaction.ParentID); err != nil {
+				return err
+			}
+		default:
+			var children int64
+			if err := tx.Model(&models.Transaction{}).Where("parent_id = ?", transactionID).Count(&children).Error; err != nil {
+				return err
+			}
+			if children > 0 {
+				return ErrTransactionHasChildren
+			}
+		}
+		if t.MaterializedSums {
+			if err := tx.Exec("DELETE FROM transaction_descendant_sums WHERE transaction_id = ?", transactionID).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Delete(&models.Transaction{}, transactionID).Error
+	})
+}
+
+// removeFromSums takes what a deletion in mode removes out of the descendant sums of the ancestors of the
+// deleted transaction: the transaction itself, and its descendants too when they are deleted with it.
+func (t *transactionRepository) removeFromSums(tx *gorm.DB, transactionID uint, mode DeleteMode) error {
+	var transaction models.Transaction
+	if err := tx.Where("id = ?", transactionID).First(&transaction).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	removed := []sumDelta{ownDelta(&transaction).negate()}
+	if mode == DeleteModeCascade {
+		descendants, err := t.descendantDeltas(tx, transactionID)
+		if err != nil {
+			return err
+		}
+		for _, delta := range descendants {
+			removed = append(removed, delta.negate())
+		}
+	}
+	return t.shiftSums(tx, transaction.ParentID, removed...)
+}
+
+// WithinTransaction runs fn with a repository bound to a database transaction, committing it when fn
+// returns nil and rolling it back otherwise. Nested calls are backed by savepoints, so an inner failure
+// can be rolled back without aborting the enclosing transaction.
+func (t *transactionRepository) WithinTransaction(ctx context.Context, fn func(transactionRepo TransactionRepositoryI) error) error {
+	return t.Db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(t.bind(tx))
+	})
+}
+
 // GetByID retrieves a transaction by its ID from the database.
-func (t *transactionRepository) GetByID(id uint) (*models.Transaction, error) {
+func (t *transactionRepository) GetByID(ctx context.Context, id uint) (*models.Transaction, error) {
 	var transaction models.Transaction
-	result := t.Db.Where("id = ?", id).First(&transaction)
+	result := t.Db.WithContext(ctx).Where("id = ?", id).First(&transaction)
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 			// No record found
@@ -61,39 +306,326 @@ func (t *transactionRepository) GetByID(id uint) (*models.Transaction, error) {
 	return &transaction, nil
 }
 
-// GetByType retrieves transactions by type from the database.
-func (t *transactionRepository) GetByType(transactionType string) ([]models.Transaction, error) {
+// LockByID locks the row of a transaction until the end of the database transaction the repository is bound
+// to, so that concurrent writers checking the same transaction wait for each other. SQLite has no row locks,
+// but serializes writers, so that a transaction writing after a concurrent one committed fails rather than
+// acting on what it read.
+func (t *transactionRepository) LockByID(ctx context.Context, transactionID uint) error {
+	tx := t.Db.WithContext(ctx)
+	if tx.Dialector.Name() == sqliteDialect {
+		return nil
+	}
+	var ids []uint
+	return tx.Model(&models.Transaction{}).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ?", transactionID).
+		Pluck("id", &ids).Error
+}
+
+// GetByIDs retrieves the transactions with the given IDs from the database, skipping IDs that do not exist.
+func (t *transactionRepository) GetByIDs(ctx context.Context, transactionIDs []uint) ([]models.Transaction, error) {
+	transactions := []models.Transaction{}
+	if len(transactionIDs) == 0 {
+		return transactions, nil
+	}
+	result := t.Db.WithContext(ctx).Where("id IN ?", transactionIDs).Find(&transactions)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return transactions, nil
+}
+
+// List retrieves a page of up to limit transactions with IDs greater than afterID from the database, ordered by ID.
+// A nil afterID starts the page at the first transaction.
+func (t *transactionRepository) List(ctx context.Context, afterID *uint, limit int) ([]models.Transaction, error) {
+	var transactions []models.Transaction
+	query := t.Db.WithContext(ctx)
+	if afterID != nil {
+		query = query.Where("id > ?", *afterID)
+	}
+	result := query.Order("id").Limit(limit).Find(&transactions)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return transactions, nil
+}
+
+// GetByType retrieves a page of transactions of the given type from the database, ordered by ID.
+func (t *transactionRepository) GetByType(ctx context.Context, query TypeQuery) ([]models.Transaction, error) {
 	var transactions []models.Transaction
-	result := t.Db.Where("type = ?", transactionType).Find(&transactions)
+	db := t.Db.WithContext(ctx).Where("type = ?", query.Type)
+	if query.AfterID != nil {
+		if query.Descending {
+			db = db.Where("id < ?", *query.AfterID)
+		} else {
+			db = db.Where("id > ?", *query.AfterID)
+		}
+	}
+	if query.MinAmount != nil {
+		db = db.Where(amountCondition(db, ">="), *query.MinAmount)
+	}
+	if query.MaxAmount != nil {
+		db = db.Where(amountCondition(db, "<="), *query.MaxAmount)
+	}
+	if query.ParentID != nil {
+		db = db.Where("parent_id = ?", *query.ParentID)
+	}
+	if query.Descending {
+		db = db.Order("id DESC")
+	} else {
+		db = db.Order("id ASC")
+	}
+	if query.Limit > 0 {
+		db = db.Limit(query.Limit)
+	}
+
+	result := db.Find(&transactions)
 	if result.Error != nil {
 		return nil, result.Error
 	}
 	return transactions, nil
 }
 
-// GetTransitiveSum retrieves the sum of all transactions transitively linked by their parent_id to a given transaction ID.
-func (t *transactionRepository) GetTransitiveSum(transactionID uint) (float64, error) {
-	var totalAmount float64
-	query := fmt.Sprintf(`
-		WITH RECURSIVE TransactionsCTE AS (
-			SELECT id, amount
-			FROM transactions
-			WHERE id = %d
-	
-			UNION ALL
-	
-			SELECT t.id, t.amount
-			FROM transactions t
-			JOIN TransactionsCTE ON t.parent_id = TransactionsCTE.id
-		)
-		SELECT COALESCE(SUM(amount), 0) AS total_amount
-		FROM TransactionsCTE where id != %d;
-	`, transactionID, transactionID)
-
-	err := t.Db.Raw(query).Row().Scan(&totalAmount)
+// GetChildIDs retrieves the IDs of the direct children of a transaction in ascending order.
+func (t *transactionRepository) GetChildIDs(ctx context.Context, transactionID uint) ([]uint, error) {
+	childIDs := []uint{}
+	result := t.Db.WithContext(ctx).Model(&models.Transaction{}).Where("parent_id = ?", transactionID).Order("id").Pluck("id", &childIDs)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return childIDs, nil
+}
+
+// GetDepth retrieves the number of ancestors of a transaction, a root transaction having depth 0.
+func (t *transactionRepository) GetDepth(ctx context.Context, transactionID uint) (int, error) {
+	ancestors, err := t.GetAncestors(ctx, transactionID)
 	if err != nil {
 		return 0, err
 	}
+	return len(ancestors), nil
+}
+
+// GetDepths retrieves the depths of the transactions with the given IDs with a single query, skipping IDs
+// that do not exist.
+func (t *transactionRepository) GetDepths(ctx context.Context, transactionIDs []uint) (map[uint]int, error) {
+	depths := make(map[uint]int, len(transactionIDs))
+	if len(transactionIDs) == 0 {
+		return depths, nil
+	}
+
+	query, args := treeWalk{
+		Direction: walkAncestors,
+		StartIDs:  transactionIDs,
+		MaxDepth:  t.MaxDepth + 1,
+	}.Build(`
+		SELECT start_id AS id, MAX(depth) AS depth, MAX(is_cycle) AS is_cycle
+		FROM TreeCTE
+		GROUP BY start_id
+	`)
+
+	var rows []treeRow
+	if err := t.recursive(ctx).Raw(query, args...).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	if err := t.checkTreeRows(rows); err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		depths[row.Id] = row.Depth
+	}
+	return depths, nil
+}
+
+// transactionColumns lists the columns of a transaction other than id and parent_id, which tree walks read
+// whole transactions with.
+var transactionColumns = []string{"amount", "type", "currency", "occurred_at", "created_at", "updated_at", "created_by"}
+
+// treeRow is a row of a recursive tree query. Path holds the IDs visited so far as "/1/2/3/",
+// and IsCycle flags a row whose ID already appears in the path of the row it was reached from.
+type treeRow struct {
+	models.Transaction
+	Depth   int
+	Path    string
+	IsCycle int
+}
+
+// recursive returns a session that prepares its statements once and reuses them, which suits the
+// recursive queries whose text only depends on the shape of the walk.
+func (t *transactionRepository) recursive(ctx context.Context) *gorm.DB {
+	return t.Db.WithContext(ctx).Session(&gorm.Session{PrepareStmt: true})
+}
+
+// checkTreeRows returns ErrCycleDetected when the walk ran into a cycle and ErrMaxDepthExceeded when
+// it went further than MaxDepth levels away from its starting transaction.
+func (t *transactionRepository) checkTreeRows(rows []treeRow) error {
+	for _, row := range rows {
+		if row.IsCycle != 0 {
+			return ErrCycleDetected
+		}
+		if row.Depth > t.MaxDepth {
+			return ErrMaxDepthExceeded
+		}
+	}
+	return nil
+}
+
+// GetAncestors retrieves the ancestors of a transaction ordered from its parent up to the root.
+func (t *transactionRepository) GetAncestors(ctx context.Context, transactionID uint) ([]models.Transaction, error) {
+	query, args := treeWalk{
+		Direction: walkAncestors,
+		StartID:   transactionID,
+		MaxDepth:  t.MaxDepth + 1,
+		Columns:   transactionColumns,
+	}.Build(`
+		SELECT id, parent_id, amount, type, currency, occurred_at, created_at, updated_at, created_by, depth, path, is_cycle
+		FROM TreeCTE
+		WHERE depth > 0
+		ORDER BY depth
+	`)
+
+	var rows []treeRow
+	if err := t.recursive(ctx).Raw(query, args...).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	if err := t.checkTreeRows(rows); err != nil {
+		return nil, err
+	}
+
+	ancestors := make([]models.Transaction, 0, len(rows))
+	for _, row := range rows {
+		ancestors = append(ancestors, row.Transaction)
+	}
+	return ancestors, nil
+}
+
+// GetDescendants retrieves a transaction and its descendants down to maxDepth levels below it,
+// ordered by depth and then by ID.
+func (t *transactionRepository) GetDescendants(ctx context.Context, transactionID uint, maxDepth int) ([]models.TransactionNode, error) {
+	// Walk one level past the configured maximum depth so that deeper trees are reported rather than truncated
+	if maxDepth > t.MaxDepth {
+		maxDepth = t.MaxDepth + 1
+	}
+
+	query, args := treeWalk{
+		Direction: walkDescendants,
+		StartID:   transactionID,
+		MaxDepth:  maxDepth,
+		Columns:   transactionColumns,
+	}.Build(`
+		SELECT id, parent_id, amount, type, currency, occurred_at, created_at, updated_at, created_by, depth, path, is_cycle
+		FROM TreeCTE
+		ORDER BY depth, id
+	`)
+
+	var rows []treeRow
+	if err := t.recursive(ctx).Raw(query, args...).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	if err := t.checkTreeRows(rows); err != nil {
+		return nil, err
+	}
+
+	nodes := make([]models.TransactionNode, 0, len(rows))
+	for _, row := range rows {
+		path, err := parsePath(row.Path)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, models.TransactionNode{Transaction: row.Transaction, Depth: row.Depth, Path: path})
+	}
+	return nodes, nil
+}
+
+// addUp sums the amounts of rows by currency.
+func addUp(rows []treeRow) map[string]decimal.Decimal {
+	subtotals := make(map[string]decimal.Decimal)
+	for _, row := range rows {
+		subtotals[row.Currency] = subtotals[row.Currency].Add(row.Amount)
+	}
+	return subtotals
+}
+
+// parsePath decodes a slash separated list of transaction IDs built by a recursive query.
+func parsePath(path string) ([]uint, error) {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	ids := make([]uint, 0, len(parts))
+	for _, part := range parts {
+		id, err := strconv.ParseUint(part, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid transaction path %q: %w", path, err)
+		}
+		ids = append(ids, uint(id))
+	}
+	return ids, nil
+}
+
+// GetTransitiveSum retrieves the sum of all transactions transitively linked by their parent_id to a given transaction ID,
+// keyed by currency. The sums are computed by the database over NUMERIC amounts, so they are exact.
+func (t *transactionRepository) GetTransitiveSum(ctx context.Context, transactionID uint) (map[string]decimal.Decimal, error) {
+	if t.MaterializedSums {
+		return t.getMaterializedSum(ctx, transactionID)
+	}
+	if !exactSums(t.Db) {
+		query, args := treeWalk{
+			Direction: walkDescendants,
+			StartID:   transactionID,
+			MaxDepth:  t.MaxDepth + 1,
+			Columns:   []string{"amount", "currency"},
+		}.Build(`SELECT id, amount, currency, depth, is_cycle FROM TreeCTE WHERE depth > 0`)
+
+		var rows []treeRow
+		if err := t.recursive(ctx).Raw(query, args...).Scan(&rows).Error; err != nil {
+			return nil, err
+		}
+		if err := t.checkTreeRows(rows); err != nil {
+			return nil, err
+		}
+		return addUp(rows), nil
+	}
+
+	query, args := treeWalk{
+		Direction: walkDescendants,
+		StartID:   transactionID,
+		MaxDepth:  t.MaxDepth + 1,
+		Columns:   []string{"amount", "currency"},
+	}.Build(`
+		SELECT currency,
+			COALESCE(SUM(CASE WHEN depth > 0 AND is_cycle = 0 THEN amount END), 0) AS total_amount,
+			COUNT(CASE WHEN depth > 0 AND is_cycle = 0 THEN 1 END) AS descendants,
+			MAX(depth) AS max_depth,
+			MAX(is_cycle) AS is_cycle
+		FROM TreeCTE
+		GROUP BY currency
+	`)
+
+	rows, err := t.recursive(ctx).Raw(query, args...).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	subtotals := make(map[string]decimal.Decimal)
+	for rows.Next() {
+		var currency string
+		var totalAmount decimal.Decimal
+		var descendants, maxDepth, isCycle int
+		if err := rows.Scan(&currency, &totalAmount, &descendants, &maxDepth, &isCycle); err != nil {
+			return nil, err
+		}
+		if isCycle != 0 {
+			return nil, ErrCycleDetected
+		}
+		if maxDepth > t.MaxDepth {
+			return nil, ErrMaxDepthExceeded
+		}
+		if descendants > 0 {
+			subtotals[currency] = totalAmount
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 
-	return totalAmount, nil
+	return subtotals, nil
 }
@@ -0,0 +1,129 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"transaction_system/app/lib/apperror"
+	"transaction_system/app/lib/config"
+	"transaction_system/app/lib/db"
+	"transaction_system/app/models"
+
+	"gorm.io/gorm"
+)
+
+//go:generate mockgen -source=./transaction_type.go -destination=mock_repositories/mock_transaction_type.go -package=mock_repositories
+
+var ErrTransactionTypeAlreadyExist = apperror.New("transaction_type_already_exists", http.StatusConflict, "transaction type is already registered")
+
+type TransactionTypeRepositoryI interface {
+	Create(ctx context.Context, transactionType *models.TransactionType) error
+	Exists(ctx context.Context, name string) (bool, error)
+	List(ctx context.Context) ([]models.TransactionType, error)
+}
+
+type transactionTypeRepository struct {
+	Db *gorm.DB
+}
+
+func NewTransactionTypeRepository() TransactionTypeRepositoryI {
+	if config.Get().RepositoryMode == config.RepositoryModeMemory {
+		return sharedMemoryTransactionTypeRepository()
+	}
+	return &transactionTypeRepository{Db: db.Get()}
+}
+
+// Create registers a transaction type, returning ErrTransactionTypeAlreadyExist when it is already registered.
+func (t *transactionTypeRepository) Create(ctx context.Context, transactionType *models.TransactionType) error {
+	tx := t.Db.WithContext(ctx)
+	if err := tx.Create(transactionType).Error; err != nil {
+		if isDuplicateKeyError(tx, err) {
+			return ErrTransactionTypeAlreadyExist
+		}
+		return fmt.Errorf("inserting transaction type %q: %w", transactionType.Name, err)
+	}
+	return nil
+}
+
+// Exists reports whether a transaction type is registered.
+func (t *transactionTypeRepository) Exists(ctx context.Context, name string) (bool, error) {
+	var count int64
+	err := t.Db.WithContext(ctx).Model(&models.TransactionType{}).Where("name = ?", name).Count(&count).Error
+	return count > 0, err
+}
+
+// List returns every registered transaction type ordered by name.
+func (t *transactionTypeRepository) List(ctx context.Context) ([]models.TransactionType, error) {
+	transactionTypes := []models.TransactionType{}
+	err := t.Db.WithContext(ctx).Order("name").Find(&transactionTypes).Error
+	return transactionTypes, err
+}
+
+// memoryTransactionTypeRepository keeps the transaction type registry in process memory, for running the
+// service without a database. It is safe for concurrent use.
+type memoryTransactionTypeRepository struct {
+	mu    sync.RWMutex
+	names map[string]struct{}
+}
+
+var (
+	memoryTypeRepository     TransactionTypeRepositoryI
+	memoryTypeRepositoryOnce sync.Once
+)
+
+// sharedMemoryTransactionTypeRepository returns the in-memory type registry shared by the whole process.
+func sharedMemoryTransactionTypeRepository() TransactionTypeRepositoryI {
+	memoryTypeRepositoryOnce.Do(func() {
+		memoryTypeRepository = MakeMemoryTransactionTypeRepository()
+	})
+	return memoryTypeRepository
+}
+
+// MakeMemoryTransactionTypeRepository returns an empty in-memory type registry.
+func MakeMemoryTransactionTypeRepository() TransactionTypeRepositoryI {
+	return &memoryTransactionTypeRepository{names: make(map[string]struct{})}
+}
+
+// Create registers a transaction type.
+func (r *memoryTransactionTypeRepository) Create(ctx context.Context, transactionType *models.TransactionType) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.names[transactionType.Name]; exists {
+		return ErrTransactionTypeAlreadyExist
+	}
+	r.names[transactionType.Name] = struct{}{}
+	return nil
+}
+
+// Exists reports whether a transaction type is registered.
+func (r *memoryTransactionTypeRepository) Exists(ctx context.Context, name string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	_, exists := r.names[name]
+	return exists, nil
+}
+
+// List returns every registered transaction type ordered by name.
+func (r *memoryTransactionTypeRepository) List(ctx context.Context) ([]models.TransactionType, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	transactionTypes := make([]models.TransactionType, 0, len(r.names))
+	for name := range r.names {
+		transactionTypes = append(transactionTypes, models.TransactionType{Name: name})
+	}
+	sort.Slice(transactionTypes, func(i, j int) bool {
+		return transactionTypes[i].Name < transactionTypes[j].Name
+	})
+	return transactionTypes, nil
+}
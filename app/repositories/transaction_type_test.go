@@ -0,0 +1,40 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"transaction_system/app/models"
+)
+
+func TestTransactionTypeRepository(t *testing.T) {
+	ctx := context.Background()
+	for name, repo := range map[string]TransactionTypeRepositoryI{
+		"database": &transactionTypeRepository{Db: openTestDB(t)},
+		"memory":   MakeMemoryTransactionTypeRepository(),
+	} {
+		t.Run(name, func(t *testing.T) {
+			for _, typeName := range []string{"refund", "purchase"} {
+				require.NoError(t, repo.Create(ctx, &models.TransactionType{Name: typeName}))
+			}
+
+			// Assert registering a type twice is refused like a duplicate key
+			assert.Equal(t, ErrTransactionTypeAlreadyExist, repo.Create(ctx, &models.TransactionType{Name: "refund"}))
+
+			// Assert types are looked up by exact name and listed in order
+			exists, err := repo.Exists(ctx, "purchase")
+			assert.NoError(t, err)
+			assert.True(t, exists)
+			exists, err = repo.Exists(ctx, "Purchase")
+			assert.NoError(t, err)
+			assert.False(t, exists)
+
+			transactionTypes, err := repo.List(ctx)
+			assert.NoError(t, err)
+			assert.Equal(t, []models.TransactionType{{Name: "purchase"}, {Name: "refund"}}, transactionTypes)
+		})
+	}
+}
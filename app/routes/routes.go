@@ -1,10 +1,13 @@
 package routes
 
 import (
+	"context"
 	"fmt"
 	"github.com/julienschmidt/httprouter"
 	"net/http"
+	"time"
 	"transaction_system/app/controllers"
+	"transaction_system/app/lib/config"
 )
 
 func HomeHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
@@ -15,13 +18,44 @@ func HealthCheckHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Par
 	fmt.Fprintf(w, "Hi, I am transaction system. I am healthy")
 }
 
+// withTimeout bounds the request context of handler so that the queries it runs are canceled
+// once timeout has elapsed.
+func withTimeout(timeout time.Duration, handler httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+		handler(w, r.WithContext(ctx), params)
+	}
+}
+
 func InitRoutes(router *httprouter.Router) {
+	// Report unknown routes, unsupported methods and panics as problem details like every other error
+	router.NotFound = http.HandlerFunc(controllers.NotFoundHandler)
+	router.MethodNotAllowed = http.HandlerFunc(controllers.MethodNotAllowedHandler)
+	router.PanicHandler = controllers.PanicHandler
 
 	router.GET("/", HomeHandler)
 	router.GET("/health-check", HealthCheckHandler)
 
+	timeout := config.Get().RequestTimeout
 	transactionController := controllers.NewTransactionController()
-	router.PUT("/transactionservice/transaction/:transaction_id", transactionController.CreateTransaction)
-	router.GET("/transactionservice/types/:type", transactionController.GetTransactionsByType)
-	router.GET("/transactionservice/sum/:transaction_id", transactionController.GetTransitiveSum)
+	// Created transactions record the caller named by the X-User-ID header as their creator
+	router.PUT("/transactionservice/transaction/:transaction_id", controllers.WithActor(withTimeout(timeout, transactionController.CreateTransaction)))
+	router.POST("/transactionservice/transactions/batch", controllers.WithActor(withTimeout(timeout, transactionController.CreateTransactions)))
+	router.POST("/transactionservice/transactions/import", controllers.WithActor(withTimeout(timeout, transactionController.ImportTransactions)))
+	// Exports stream the whole table, so they are not bound by the request timeout
+	router.GET("/transactionservice/transactions/export", transactionController.ExportTransactions)
+	router.GET("/transactionservice/transaction/:transaction_id", withTimeout(timeout, transactionController.GetTransaction))
+	router.PATCH("/transactionservice/transaction/:transaction_id", withTimeout(timeout, transactionController.UpdateTransaction))
+	router.DELETE("/transactionservice/transaction/:transaction_id", withTimeout(timeout, transactionController.DeleteTransaction))
+	router.GET("/transactionservice/types/:type", withTimeout(timeout, transactionController.GetTransactionsByType))
+	router.GET("/transactionservice/sum/:transaction_id", withTimeout(timeout, transactionController.GetTransitiveSum))
+	router.GET("/transactionservice/tree/:transaction_id", withTimeout(timeout, transactionController.GetTransactionTree))
+	router.GET("/transactionservice/ancestors/:transaction_id", withTimeout(timeout, transactionController.GetAncestors))
+
+	// Administration of the registry of allowed transaction types, open to callers holding the admin token
+	adminToken := config.Get().AdminToken
+	transactionTypeController := controllers.NewTransactionTypeController()
+	router.GET("/transactionservice/admin/types", controllers.WithAdminToken(adminToken, withTimeout(timeout, transactionTypeController.ListTransactionTypes)))
+	router.POST("/transactionservice/admin/types", controllers.WithAdminToken(adminToken, withTimeout(timeout, transactionTypeController.CreateTransactionType)))
 }
@@ -5,8 +5,11 @@
 package mock_services
 
 import (
+	context "context"
 	reflect "reflect"
 	models "transaction_system/app/models"
+	repositories "transaction_system/app/repositories"
+	services "transaction_system/app/services"
 
 	gomock "github.com/golang/mock/gomock"
 )
@@ -35,46 +38,166 @@ func (m *MockTransactionServiceI) EXPECT() *MockTransactionServiceIMockRecorder
 }
 
 // CreateTransaction mocks base method.
-func (m *MockTransactionServiceI) CreateTransaction(transaction models.Transaction) (bool, error) {
+func (m *MockTransactionServiceI) CreateTransaction(ctx context.Context, transaction models.Transaction) (*models.Transaction, bool, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateTransaction", transaction)
-	ret0, _ := ret[0].(bool)
+	ret := m.ctrl.Call(m, "CreateTransaction", ctx, transaction)
+	ret0, _ := ret[0].(*models.Transaction)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// CreateTransaction indicates an expected call of CreateTransaction.
+func (mr *MockTransactionServiceIMockRecorder) CreateTransaction(ctx, transaction interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateTransaction", reflect.TypeOf((*MockTransactionServiceI)(nil).CreateTransaction), ctx, transaction)
+}
+
+// CreateTransactions mocks base method.
+func (m *MockTransactionServiceI) CreateTransactions(ctx context.Context, transactions []models.Transaction, mode services.BatchMode) (*services.BatchResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateTransactions", ctx, transactions, mode)
+	ret0, _ := ret[0].(*services.BatchResult)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// CreateTransaction indicates an expected call of CreateTransaction.
-func (mr *MockTransactionServiceIMockRecorder) CreateTransaction(transaction interface{}) *gomock.Call {
+// CreateTransactions indicates an expected call of CreateTransactions.
+func (mr *MockTransactionServiceIMockRecorder) CreateTransactions(ctx, transactions, mode interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateTransaction", reflect.TypeOf((*MockTransactionServiceI)(nil).CreateTransaction), transaction)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateTransactions", reflect.TypeOf((*MockTransactionServiceI)(nil).CreateTransactions), ctx, transactions, mode)
+}
+
+// DeleteTransaction mocks base method.
+func (m *MockTransactionServiceI) DeleteTransaction(ctx context.Context, transactionID uint, mode repositories.DeleteMode) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteTransaction", ctx, transactionID, mode)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteTransaction indicates an expected call of DeleteTransaction.
+func (mr *MockTransactionServiceIMockRecorder) DeleteTransaction(ctx, transactionID, mode interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteTransaction", reflect.TypeOf((*MockTransactionServiceI)(nil).DeleteTransaction), ctx, transactionID, mode)
+}
+
+// GetAncestors mocks base method.
+func (m *MockTransactionServiceI) GetAncestors(ctx context.Context, transactionID uint) (*services.AncestorChain, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAncestors", ctx, transactionID)
+	ret0, _ := ret[0].(*services.AncestorChain)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAncestors indicates an expected call of GetAncestors.
+func (mr *MockTransactionServiceIMockRecorder) GetAncestors(ctx, transactionID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAncestors", reflect.TypeOf((*MockTransactionServiceI)(nil).GetAncestors), ctx, transactionID)
+}
+
+// GetTransaction mocks base method.
+func (m *MockTransactionServiceI) GetTransaction(ctx context.Context, transactionID uint, opts services.GetTransactionOptions) (*services.TransactionDetails, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTransaction", ctx, transactionID, opts)
+	ret0, _ := ret[0].(*services.TransactionDetails)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTransaction indicates an expected call of GetTransaction.
+func (mr *MockTransactionServiceIMockRecorder) GetTransaction(ctx, transactionID, opts interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTransaction", reflect.TypeOf((*MockTransactionServiceI)(nil).GetTransaction), ctx, transactionID, opts)
 }
 
 // GetTransactionIDsByType mocks base method.
-func (m *MockTransactionServiceI) GetTransactionIDsByType(transactionType string) ([]uint, error) {
+func (m *MockTransactionServiceI) GetTransactionIDsByType(ctx context.Context, query repositories.TypeQuery) (*services.TransactionIDPage, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetTransactionIDsByType", transactionType)
-	ret0, _ := ret[0].([]uint)
+	ret := m.ctrl.Call(m, "GetTransactionIDsByType", ctx, query)
+	ret0, _ := ret[0].(*services.TransactionIDPage)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetTransactionIDsByType indicates an expected call of GetTransactionIDsByType.
-func (mr *MockTransactionServiceIMockRecorder) GetTransactionIDsByType(transactionType interface{}) *gomock.Call {
+func (mr *MockTransactionServiceIMockRecorder) GetTransactionIDsByType(ctx, query interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTransactionIDsByType", reflect.TypeOf((*MockTransactionServiceI)(nil).GetTransactionIDsByType), ctx, query)
+}
+
+// GetTransactionTree mocks base method.
+func (m *MockTransactionServiceI) GetTransactionTree(ctx context.Context, transactionID uint, opts services.TreeOptions) ([]models.TransactionNode, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTransactionTree", ctx, transactionID, opts)
+	ret0, _ := ret[0].([]models.TransactionNode)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTransactionTree indicates an expected call of GetTransactionTree.
+func (mr *MockTransactionServiceIMockRecorder) GetTransactionTree(ctx, transactionID, opts interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTransactionIDsByType", reflect.TypeOf((*MockTransactionServiceI)(nil).GetTransactionIDsByType), transactionType)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTransactionTree", reflect.TypeOf((*MockTransactionServiceI)(nil).GetTransactionTree), ctx, transactionID, opts)
 }
 
 // GetTransitiveSum mocks base method.
-func (m *MockTransactionServiceI) GetTransitiveSum(transactionID uint) (float64, error) {
+func (m *MockTransactionServiceI) GetTransitiveSum(ctx context.Context, transactionID uint, targetCurrency string) (*services.TransitiveSum, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetTransitiveSum", transactionID)
-	ret0, _ := ret[0].(float64)
+	ret := m.ctrl.Call(m, "GetTransitiveSum", ctx, transactionID, targetCurrency)
+	ret0, _ := ret[0].(*services.TransitiveSum)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetTransitiveSum indicates an expected call of GetTransitiveSum.
-func (mr *MockTransactionServiceIMockRecorder) GetTransitiveSum(transactionID interface{}) *gomock.Call {
+func (mr *MockTransactionServiceIMockRecorder) GetTransitiveSum(ctx, transactionID, targetCurrency interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTransitiveSum", reflect.TypeOf((*MockTransactionServiceI)(nil).GetTransitiveSum), ctx, transactionID, targetCurrency)
+}
+
+// ImportTransactions mocks base method.
+func (m *MockTransactionServiceI) ImportTransactions(ctx context.Context, transactions []models.Transaction, dryRun bool) (*services.BatchResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ImportTransactions", ctx, transactions, dryRun)
+	ret0, _ := ret[0].(*services.BatchResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ImportTransactions indicates an expected call of ImportTransactions.
+func (mr *MockTransactionServiceIMockRecorder) ImportTransactions(ctx, transactions, dryRun interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ImportTransactions", reflect.TypeOf((*MockTransactionServiceI)(nil).ImportTransactions), ctx, transactions, dryRun)
+}
+
+// ListTransactions mocks base method.
+func (m *MockTransactionServiceI) ListTransactions(ctx context.Context, afterID *uint, limit int) ([]models.Transaction, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListTransactions", ctx, afterID, limit)
+	ret0, _ := ret[0].([]models.Transaction)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListTransactions indicates an expected call of ListTransactions.
+func (mr *MockTransactionServiceIMockRecorder) ListTransactions(ctx, afterID, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListTransactions", reflect.TypeOf((*MockTransactionServiceI)(nil).ListTransactions), ctx, afterID, limit)
+}
+
+// UpdateTransaction mocks base method.
+func (m *MockTransactionServiceI) UpdateTransaction(ctx context.Context, transactionID uint, update models.TransactionUpdate) (*models.Transaction, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateTransaction", ctx, transactionID, update)
+	ret0, _ := ret[0].(*models.Transaction)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateTransaction indicates an expected call of UpdateTransaction.
+func (mr *MockTransactionServiceIMockRecorder) UpdateTransaction(ctx, transactionID, update interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTransitiveSum", reflect.TypeOf((*MockTransactionServiceI)(nil).GetTransitiveSum), transactionID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateTransaction", reflect.TypeOf((*MockTransactionServiceI)(nil).UpdateTransaction), ctx, transactionID, update)
 }
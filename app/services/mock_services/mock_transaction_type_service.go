@@ -0,0 +1,95 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./transaction_type_service.go
+
+// Package mock_services is a generated GoMock package.
+package mock_services
+
+import (
+	context "context"
+	reflect "reflect"
+	models "transaction_system/app/models"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockTransactionTypeServiceI is a mock of TransactionTypeServiceI interface.
+type MockTransactionTypeServiceI struct {
+	ctrl     *gomock.Controller
+	recorder *MockTransactionTypeServiceIMockRecorder
+}
+
+// MockTransactionTypeServiceIMockRecorder is the mock recorder for MockTransactionTypeServiceI.
+type MockTransactionTypeServiceIMockRecorder struct {
+	mock *MockTransactionTypeServiceI
+}
+
+// NewMockTransactionTypeServiceI creates a new mock instance.
+func NewMockTransactionTypeServiceI(ctrl *gomock.Controller) *MockTransactionTypeServiceI {
+	mock := &MockTransactionTypeServiceI{ctrl: ctrl}
+	mock.recorder = &MockTransactionTypeServiceIMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockTransactionTypeServiceI) EXPECT() *MockTransactionTypeServiceIMockRecorder {
+	return m.recorder
+}
+
+// AddTransactionType mocks base method.
+func (m *MockTransactionTypeServiceI) AddTransactionType(ctx context.Context, name string) (*models.TransactionType, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddTransactionType", ctx, name)
+	ret0, _ := ret[0].(*models.TransactionType)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// AddTransactionType indicates an expected call of AddTransactionType.
+func (mr *MockTransactionTypeServiceIMockRecorder) AddTransactionType(ctx, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddTransactionType", reflect.TypeOf((*MockTransactionTypeServiceI)(nil).AddTransactionType), ctx, name)
+}
+
+// CheckTransactionType mocks base method.
+func (m *MockTransactionTypeServiceI) CheckTransactionType(ctx context.Context, name string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckTransactionType", ctx, name)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CheckTransactionType indicates an expected call of CheckTransactionType.
+func (mr *MockTransactionTypeServiceIMockRecorder) CheckTransactionType(ctx, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckTransactionType", reflect.TypeOf((*MockTransactionTypeServiceI)(nil).CheckTransactionType), ctx, name)
+}
+
+// ListTransactionTypes mocks base method.
+func (m *MockTransactionTypeServiceI) ListTransactionTypes(ctx context.Context) ([]models.TransactionType, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListTransactionTypes", ctx)
+	ret0, _ := ret[0].([]models.TransactionType)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListTransactionTypes indicates an expected call of ListTransactionTypes.
+func (mr *MockTransactionTypeServiceIMockRecorder) ListTransactionTypes(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListTransactionTypes", reflect.TypeOf((*MockTransactionTypeServiceI)(nil).ListTransactionTypes), ctx)
+}
+
+// NormalizeTransactionType mocks base method.
+func (m *MockTransactionTypeServiceI) NormalizeTransactionType(name string) string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "NormalizeTransactionType", name)
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// NormalizeTransactionType indicates an expected call of NormalizeTransactionType.
+func (mr *MockTransactionTypeServiceIMockRecorder) NormalizeTransactionType(name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NormalizeTransactionType", reflect.TypeOf((*MockTransactionTypeServiceI)(nil).NormalizeTransactionType), name)
+}
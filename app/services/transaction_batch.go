@@ -0,0 +1,273 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"transaction_system/app/lib/apperror"
+	"transaction_system/app/lib/transfer"
+	"transaction_system/app/models"
+	"transaction_system/app/repositories"
+)
+
+// MaxBatchSize is the largest number of transactions accepted by CreateTransactions and ImportTransactions.
+const MaxBatchSize = 10000
+
+var ErrBatchTooLarge = apperror.New("batch_too_large", http.StatusBadRequest, "batch contains too many transactions")
+var ErrInvalidBatchMode = apperror.New("invalid_batch_mode", http.StatusBadRequest, "batch mode must be 'atomic' or 'best_effort'")
+
+// errBatchRolledBack aborts the database transaction of an atomic batch once an item has failed.
+var errBatchRolledBack = errors.New("batch rolled back")
+
+// BatchMode controls how CreateTransactions reacts to a transaction that cannot be created.
+type BatchMode string
+
+const (
+	// BatchModeAtomic creates every transaction of the batch or none of them.
+	BatchModeAtomic BatchMode = "atomic"
+	// BatchModeBestEffort creates every transaction it can and reports the others as failed.
+	BatchModeBestEffort BatchMode = "best_effort"
+)
+
+// BatchItemStatus is the outcome of one transaction of a batch.
+type BatchItemStatus string
+
+const (
+	// BatchItemCreated means the transaction was created.
+	BatchItemCreated BatchItemStatus = "created"
+	// BatchItemUnchanged means an identical transaction was already stored.
+	BatchItemUnchanged BatchItemStatus = "unchanged"
+	// BatchItemFailed means the transaction could not be created, Err holding the reason.
+	BatchItemFailed BatchItemStatus = "failed"
+	// BatchItemRolledBack means the transaction was valid but the atomic batch was rolled back.
+	BatchItemRolledBack BatchItemStatus = "rolled_back"
+	// BatchItemSkipped means the transaction was not attempted because the atomic batch had already failed.
+	BatchItemSkipped BatchItemStatus = "skipped"
+)
+
+// BatchItemResult is the outcome of one transaction of a batch.
+type BatchItemResult struct {
+	Index  int
+	ID     uint
+	Status BatchItemStatus
+	Err    error
+}
+
+// BatchResult is the outcome of CreateTransactions.
+type BatchResult struct {
+	// Committed reports whether the database transaction holding the batch was committed.
+	Committed bool
+	// DryRun reports that the batch was rolled back on purpose, Results telling what would have happened.
+	DryRun  bool
+	Results []BatchItemResult
+}
+
+// batchParent is a transaction that transactions later in the batch may use as their parent.
+type batchParent struct {
+	transaction *models.Transaction
+	depth       int
+}
+
+// CreateTransactions creates a batch of transactions in a single database transaction. A transaction may use
+// one that appears earlier in the same batch as its parent. In atomic mode the first failure rolls the whole
+// batch back; in best-effort mode failed transactions are rolled back individually and the rest are committed.
+func (t *transactionService) CreateTransactions(ctx context.Context, transactions []models.Transaction, mode BatchMode) (*BatchResult, error) {
+	if mode != BatchModeAtomic && mode != BatchModeBestEffort {
+		return nil, ErrInvalidBatchMode
+	}
+	if len(transactions) > MaxBatchSize {
+		return nil, ErrBatchTooLarge
+	}
+	return t.createBatch(ctx, transactions, mode, false)
+}
+
+// ImportTransactions creates every transaction of an import in a single database transaction, inserting
+// parents before their children whatever their order in transactions. Results are reported in input order.
+// With dryRun set every transaction is validated and created, then the database transaction is rolled back.
+func (t *transactionService) ImportTransactions(ctx context.Context, transactions []models.Transaction, dryRun bool) (*BatchResult, error) {
+	if len(transactions) > MaxBatchSize {
+		return nil, ErrBatchTooLarge
+	}
+	order, err := transfer.Order(transactions)
+	if err != nil {
+		var cycleErr *transfer.CycleError
+		if errors.As(err, &cycleErr) {
+			return nil, repositories.ErrCycleDetected.WithDetails(map[string]interface{}{
+				"transaction_ids": cycleErr.TransactionIDs,
+			}).Wrap(err)
+		}
+		return nil, err
+	}
+	ordered := make([]models.Transaction, len(transactions))
+	for i, index := range order {
+		ordered[i] = transactions[index]
+	}
+
+	// A dry run reports every invalid transaction rather than stopping at the first one
+	mode := BatchModeAtomic
+	if dryRun {
+		mode = BatchModeBestEffort
+	}
+	result, err := t.createBatch(ctx, ordered, mode, dryRun)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]BatchItemResult, len(result.Results))
+	for i, itemResult := range result.Results {
+		itemResult.Index = order[i]
+		results[order[i]] = itemResult
+	}
+	result.Results = results
+	return result, nil
+}
+
+// ListTransactions retrieves a page of up to limit transactions with IDs greater than afterID, ordered by ID.
+// A nil afterID starts the page at the first transaction.
+func (t *transactionService) ListTransactions(ctx context.Context, afterID *uint, limit int) ([]models.Transaction, error) {
+	if limit <= 0 || limit > MaxPageLimit {
+		limit = MaxPageLimit
+	}
+	return t.transactionRepo.List(ctx, afterID, limit)
+}
+
+// createBatch creates transactions in order within a single database transaction, rolling it back once
+// done when dryRun is set.
+func (t *transactionService) createBatch(ctx context.Context, transactions []models.Transaction, mode BatchMode, dryRun bool) (*BatchResult, error) {
+	// Look up every parent that is not part of the batch, and their depths, with one query each
+	parents, err := t.loadBatchParents(ctx, transactions)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]BatchItemResult, len(transactions))
+	for i := range transactions {
+		results[i] = BatchItemResult{Index: i, ID: transactions[i].Id, Status: BatchItemSkipped}
+	}
+
+	err = t.transactionRepo.WithinTransaction(ctx, func(txRepo repositories.TransactionRepositoryI) error {
+		for i := range transactions {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			status, err := t.createBatchItem(ctx, txRepo, transactions[i], parents)
+			results[i].Status = status
+			results[i].Err = err
+			if err != nil && mode == BatchModeAtomic {
+				return errBatchRolledBack
+			}
+		}
+		if dryRun {
+			return errBatchRolledBack
+		}
+		return nil
+	})
+	if err != nil && err != errBatchRolledBack {
+		return nil, fmt.Errorf("creating batch: %w", err)
+	}
+
+	result := &BatchResult{Committed: err == nil, DryRun: dryRun, Results: results}
+	if !result.Committed && !dryRun {
+		for i := range results {
+			if results[i].Status == BatchItemCreated {
+				results[i].Status = BatchItemRolledBack
+			}
+		}
+	}
+	return result, nil
+}
+
+// loadBatchParents fetches the parents referenced by the batch that are not created by the batch itself.
+func (t *transactionService) loadBatchParents(ctx context.Context, transactions []models.Transaction) (map[uint]batchParent, error) {
+	batchIDs := make(map[uint]bool, len(transactions))
+	for _, transaction := range transactions {
+		batchIDs[transaction.Id] = true
+	}
+
+	var parentIDs []uint
+	seen := make(map[uint]bool)
+	for _, transaction := range transactions {
+		if transaction.ParentID == nil || batchIDs[*transaction.ParentID] || seen[*transaction.ParentID] {
+			continue
+		}
+		seen[*transaction.ParentID] = true
+		parentIDs = append(parentIDs, *transaction.ParentID)
+	}
+
+	parents := make(map[uint]batchParent, len(parentIDs)+len(transactions))
+	if len(parentIDs) == 0 {
+		return parents, nil
+	}
+
+	storedParents, err := t.transactionRepo.GetByIDs(ctx, parentIDs)
+	if err != nil {
+		return nil, fmt.Errorf("getting parent transactions: %w", err)
+	}
+
+	if len(storedParents) == 0 {
+		return parents, nil
+	}
+
+	depths, err := t.transactionRepo.GetDepths(ctx, parentIDs)
+	if err != nil {
+		return nil, fmt.Errorf("getting depths of parent transactions: %w", err)
+	}
+	for i := range storedParents {
+		parents[storedParents[i].Id] = batchParent{transaction: &storedParents[i], depth: depths[storedParents[i].Id]}
+	}
+	return parents, nil
+}
+
+// createBatchItem creates one transaction of a batch behind a savepoint, recording it in parents so that
+// later transactions of the batch can use it as their parent.
+func (t *transactionService) createBatchItem(ctx context.Context, txRepo repositories.TransactionRepositoryI, transaction models.Transaction, parents map[uint]batchParent) (BatchItemStatus, error) {
+	var err error
+	transaction.Type, err = t.resolveType(ctx, transaction.Type)
+	if err != nil {
+		return BatchItemFailed, err
+	}
+
+	var parent *models.Transaction
+	parentDepth := 0
+	if transaction.ParentID != nil {
+		batchParent, ok := parents[*transaction.ParentID]
+		if !ok {
+			return BatchItemFailed, ErrParentTransactionNotFound
+		}
+		parent = batchParent.transaction
+		parentDepth = batchParent.depth
+	}
+
+	if err := t.checkNewTransaction(&transaction, parent, parentDepth); err != nil {
+		return BatchItemFailed, err
+	}
+
+	// A replayed transaction has the same parent as the requested one, so both sit at the same depth
+	depth := 0
+	if parent != nil {
+		depth = parentDepth + 1
+	}
+
+	err = txRepo.WithinTransaction(ctx, func(itemRepo repositories.TransactionRepositoryI) error {
+		return t.writeChecked(ctx, itemRepo, transaction, parent, func(transactionRepo repositories.TransactionRepositoryI) error {
+			return transactionRepo.Create(ctx, &transaction)
+		})
+	})
+	if errors.Is(err, repositories.ErrTransactionAlreadyExist) {
+		// The savepoint was rolled back, so the stored transaction can be read within the batch transaction
+		storedTransaction, _, err := replayTransaction(ctx, txRepo, transaction)
+		if err != nil {
+			return BatchItemFailed, err
+		}
+		parents[storedTransaction.Id] = batchParent{transaction: storedTransaction, depth: depth}
+		return BatchItemUnchanged, nil
+	}
+	if err != nil {
+		return BatchItemFailed, err
+	}
+
+	parents[transaction.Id] = batchParent{transaction: &transaction, depth: depth}
+	return BatchItemCreated, nil
+}
@@ -0,0 +1,211 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+	"transaction_system/app/lib/cache"
+	"transaction_system/app/lib/config"
+	"transaction_system/app/models"
+	"transaction_system/app/repositories"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// allSumsGenerationKey is bumped to invalidate every cached transitive sum.
+	allSumsGenerationKey = "transactions:sum-generation"
+	// allTypesGenerationKey is bumped to invalidate every cached type lookup.
+	allTypesGenerationKey = "transactions:type-generation"
+)
+
+var (
+	memoryCacheStore     cache.Store
+	memoryCacheStoreOnce sync.Once
+)
+
+// sumGenerationKey is bumped to invalidate the cached transitive sums of a transaction.
+func sumGenerationKey(transactionID uint) string {
+	return fmt.Sprintf("%s:%d", allSumsGenerationKey, transactionID)
+}
+
+// typeGenerationKey is bumped to invalidate the cached lookups of a transaction type. Types share the key
+// of their canonical form, so that lookups normalized by the type registry are invalidated by writes of
+// any spelling of their type.
+func typeGenerationKey(transactionType string) string {
+	return allTypesGenerationKey + ":" + CanonicalTransactionType(transactionType)
+}
+
+// cachedTransactionService caches the transitive sums and type lookups of a TransactionServiceI, passing
+// every other call through. Creating a transaction only invalidates the sums of its ancestors and the
+// lookups of its type; any other write invalidates every cached result.
+type cachedTransactionService struct {
+	TransactionServiceI
+	store cache.Store
+	ttl   time.Duration
+}
+
+// newCacheStore returns the Redis store when Redis is configured, and the in-process store otherwise.
+func newCacheStore() cache.Store {
+	cfg := config.Get()
+	if cfg.RedisHost != "" {
+		return cache.NewRedisStore(redis.NewClient(&redis.Options{Addr: cfg.RedisHost, DB: cfg.RedisDB}))
+	}
+
+	// Share the in-process store so that a write through any service invalidates it
+	memoryCacheStoreOnce.Do(func() {
+		memoryCacheStore = cache.NewMemoryStore(cache.DefaultMaxEntries)
+	})
+	return memoryCacheStore
+}
+
+// MakeCachedTransactionService wraps service with a cache of its transitive sums and type lookups kept in
+// store, each result being served for at most ttl.
+func MakeCachedTransactionService(service TransactionServiceI, store cache.Store, ttl time.Duration) TransactionServiceI {
+	return &cachedTransactionService{
+		TransactionServiceI: service,
+		store:               store,
+		ttl:                 ttl,
+	}
+}
+
+// CreateTransaction creates a transaction, invalidating the transitive sums of its ancestors and the
+// lookups of its type when it was created.
+func (s *cachedTransactionService) CreateTransaction(ctx context.Context, transaction models.Transaction) (*models.Transaction, bool, error) {
+	created, isCreated, err := s.TransactionServiceI.CreateTransaction(ctx, transaction)
+	if err != nil || !isCreated {
+		return created, isCreated, err
+	}
+
+	generationKeys := []string{typeGenerationKey(created.Type)}
+	chain, err := s.TransactionServiceI.GetAncestors(ctx, created.Id)
+	if err != nil {
+		log.Printf("Error reading ancestors of transaction %d, invalidating every cached sum: %v", created.Id, err)
+		generationKeys = append(generationKeys, allSumsGenerationKey)
+	} else {
+		for _, ancestor := range chain.Ancestors {
+			generationKeys = append(generationKeys, sumGenerationKey(ancestor.Id))
+		}
+	}
+	s.invalidate(ctx, generationKeys...)
+
+	return created, true, nil
+}
+
+// CreateTransactions creates a batch of transactions, invalidating every cached result when it was committed.
+func (s *cachedTransactionService) CreateTransactions(ctx context.Context, transactions []models.Transaction, mode BatchMode) (*BatchResult, error) {
+	result, err := s.TransactionServiceI.CreateTransactions(ctx, transactions, mode)
+	if result != nil && result.Committed {
+		s.invalidate(ctx, allSumsGenerationKey, allTypesGenerationKey)
+	}
+	return result, err
+}
+
+// ImportTransactions imports transactions, invalidating every cached result when they were committed.
+func (s *cachedTransactionService) ImportTransactions(ctx context.Context, transactions []models.Transaction, dryRun bool) (*BatchResult, error) {
+	result, err := s.TransactionServiceI.ImportTransactions(ctx, transactions, dryRun)
+	if result != nil && result.Committed {
+		s.invalidate(ctx, allSumsGenerationKey, allTypesGenerationKey)
+	}
+	return result, err
+}
+
+// UpdateTransaction updates a transaction, invalidating every cached result.
+func (s *cachedTransactionService) UpdateTransaction(ctx context.Context, transactionID uint, update models.TransactionUpdate) (*models.Transaction, error) {
+	updated, err := s.TransactionServiceI.UpdateTransaction(ctx, transactionID, update)
+	if err == nil {
+		s.invalidate(ctx, allSumsGenerationKey, allTypesGenerationKey)
+	}
+	return updated, err
+}
+
+// DeleteTransaction deletes a transaction, invalidating every cached result.
+func (s *cachedTransactionService) DeleteTransaction(ctx context.Context, transactionID uint, mode repositories.DeleteMode) error {
+	err := s.TransactionServiceI.DeleteTransaction(ctx, transactionID, mode)
+	if err == nil {
+		s.invalidate(ctx, allSumsGenerationKey, allTypesGenerationKey)
+	}
+	return err
+}
+
+// GetTransactionIDsByType returns the cached page of transaction IDs matching query, looking it up on a miss.
+func (s *cachedTransactionService) GetTransactionIDsByType(ctx context.Context, query repositories.TypeQuery) (*TransactionIDPage, error) {
+	encoded, err := json.Marshal(query)
+	if err != nil {
+		return nil, err
+	}
+	digest := sha256.Sum256(encoded)
+
+	var page *TransactionIDPage
+	err = s.cached(ctx, "transactions:type:"+hex.EncodeToString(digest[:]),
+		[]string{typeGenerationKey(query.Type), allTypesGenerationKey}, &page, func() (err error) {
+			page, err = s.TransactionServiceI.GetTransactionIDsByType(ctx, query)
+			return err
+		})
+	return page, err
+}
+
+// GetTransitiveSum returns the cached transitive sum of a transaction, computing it on a miss.
+func (s *cachedTransactionService) GetTransitiveSum(ctx context.Context, transactionID uint, targetCurrency string) (*TransitiveSum, error) {
+	var sum *TransitiveSum
+	err := s.cached(ctx, fmt.Sprintf("transactions:sum:%d:%s", transactionID, targetCurrency),
+		[]string{sumGenerationKey(transactionID), allSumsGenerationKey}, &sum, func() (err error) {
+			sum, err = s.TransactionServiceI.GetTransitiveSum(ctx, transactionID, targetCurrency)
+			return err
+		})
+	return sum, err
+}
+
+// cached decodes into result the value cached under name for the current generations of generationKeys.
+// On a miss it calls load, which sets result, and caches the result unless load failed. A failing store
+// only costs the cache: load is called as if the value was missing.
+func (s *cachedTransactionService) cached(ctx context.Context, name string, generationKeys []string, result interface{}, load func() error) error {
+	generations, err := s.store.Generations(ctx, generationKeys...)
+	if err != nil {
+		log.Printf("Error reading cache generations: %v", err)
+		return load()
+	}
+
+	// Reading the generations before loading means a value loaded while a write invalidates it is cached
+	// under a key that is never read again
+	key := name
+	for _, generation := range generations {
+		key += fmt.Sprintf(":%d", generation)
+	}
+
+	value, found, err := s.store.Get(ctx, key)
+	if err != nil {
+		log.Printf("Error reading cache key %s: %v", key, err)
+	} else if found {
+		if err = json.Unmarshal(value, result); err == nil {
+			return nil
+		}
+		log.Printf("Error decoding cache key %s: %v", key, err)
+	}
+
+	if err := load(); err != nil {
+		return err
+	}
+
+	value, err = json.Marshal(result)
+	if err == nil {
+		err = s.store.Set(ctx, key, value, s.ttl)
+	}
+	if err != nil {
+		log.Printf("Error writing cache key %s: %v", key, err)
+	}
+	return nil
+}
+
+// invalidate bumps the generations of generationKeys, so that results cached under them are no longer served.
+func (s *cachedTransactionService) invalidate(ctx context.Context, generationKeys ...string) {
+	if err := s.store.Bump(ctx, generationKeys...); err != nil {
+		log.Printf("Error invalidating cache keys %s: %v", strings.Join(generationKeys, ", "), err)
+	}
+}
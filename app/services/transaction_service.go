@@ -1,85 +1,821 @@
 package services
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"time"
+	"transaction_system/app/lib/apperror"
+	"transaction_system/app/lib/config"
+	"transaction_system/app/lib/currency"
+	"transaction_system/app/lib/rates"
+	"transaction_system/app/lib/rules"
 	"transaction_system/app/models"
 	"transaction_system/app/repositories"
+
+	"github.com/shopspring/decimal"
 )
 
 //go:generate mockgen -source=./transaction_service.go -destination=mock_services/mock_transaction_service.go -package=mock_services
 
-var ErrParentTransactionNotFound = errors.New("parent transaction does not exist")
-var ErrTransactionNotFound = errors.New("transaction does not exist for given transaction ID")
+var ErrParentTransactionNotFound = apperror.New("parent_not_found", http.StatusBadRequest, "parent transaction does not exist")
+var ErrTransactionNotFound = apperror.New("transaction_not_found", http.StatusNotFound, "transaction does not exist for given transaction ID")
+var ErrInvalidCurrency = apperror.New("invalid_currency", http.StatusBadRequest, "currency is not a valid ISO 4217 code")
+var ErrCurrencyConversionUnavailable = apperror.New("conversion_unavailable", http.StatusUnprocessableEntity, "currency conversion is not configured")
+var ErrInvalidParent = apperror.New("invalid_parent", http.StatusBadRequest, "transaction cannot be its own parent")
+var ErrTransactionConflict = apperror.New("transaction_conflict", http.StatusConflict, "transaction with the same ID already exists with different values")
+
+// FieldDiff describes a field whose stored value differs from the requested one.
+type FieldDiff struct {
+	Stored    interface{} `json:"stored"`
+	Requested interface{} `json:"requested"`
+}
+
+// TransactionConflictError is returned when a transaction is re-submitted with values that differ
+// from the stored transaction. It matches ErrTransactionConflict with errors.Is, and unwraps to a copy
+// of it carrying the diff as details.
+type TransactionConflictError struct {
+	Diff map[string]FieldDiff
+}
+
+func (e *TransactionConflictError) Error() string {
+	return ErrTransactionConflict.Error()
+}
+
+func (e *TransactionConflictError) Unwrap() error {
+	return ErrTransactionConflict.WithDetails(map[string]interface{}{"diff": e.Diff})
+}
 
 type TransactionServiceI interface {
-	CreateTransaction(transaction models.Transaction) (bool, error)
-	GetTransactionIDsByType(transactionType string) ([]uint, error)
-	GetTransitiveSum(transactionID uint) (float64, error)
+	CreateTransaction(ctx context.Context, transaction models.Transaction) (*models.Transaction, bool, error)
+	CreateTransactions(ctx context.Context, transactions []models.Transaction, mode BatchMode) (*BatchResult, error)
+	ImportTransactions(ctx context.Context, transactions []models.Transaction, dryRun bool) (*BatchResult, error)
+	ListTransactions(ctx context.Context, afterID *uint, limit int) ([]models.Transaction, error)
+	UpdateTransaction(ctx context.Context, transactionID uint, update models.TransactionUpdate) (*models.Transaction, error)
+	DeleteTransaction(ctx context.Context, transactionID uint, mode repositories.DeleteMode) error
+	GetTransaction(ctx context.Context, transactionID uint, opts GetTransactionOptions) (*TransactionDetails, error)
+	GetTransactionIDsByType(ctx context.Context, query repositories.TypeQuery) (*TransactionIDPage, error)
+	GetTransactionTree(ctx context.Context, transactionID uint, opts TreeOptions) ([]models.TransactionNode, error)
+	GetAncestors(ctx context.Context, transactionID uint) (*AncestorChain, error)
+	GetTransitiveSum(ctx context.Context, transactionID uint, targetCurrency string) (*TransitiveSum, error)
+}
+
+const (
+	// DefaultPageLimit is the page size used when a query does not set a limit.
+	DefaultPageLimit = 100
+	// MaxPageLimit is the largest page size a query may request.
+	MaxPageLimit = 1000
+)
+
+// TransactionIDPage is one page of transaction IDs.
+type TransactionIDPage struct {
+	TransactionIDs []uint
+	// NextAfterID is the AfterID of the next page, nil when this is the last page.
+	NextAfterID *uint
+}
+
+// GetTransactionOptions selects the optional details returned by GetTransaction.
+type GetTransactionOptions struct {
+	IncludeChildren bool
+	IncludeDepth    bool
+}
+
+// TransactionDetails is a transaction together with the optional details requested from GetTransaction.
+type TransactionDetails struct {
+	*models.Transaction
+	// ChildIDs holds the IDs of the direct children, when requested.
+	ChildIDs *[]uint `json:"children,omitempty"`
+	// Depth is the number of ancestors, when requested.
+	Depth *int `json:"depth,omitempty"`
+}
+
+// TreeOptions filters the nodes returned by GetTransactionTree.
+type TreeOptions struct {
+	// MaxDepth limits how many levels below the root are returned, zero meaning no limit.
+	MaxDepth int
+	// Type, when set, only keeps descendants of that type. The root is always kept.
+	Type string
+}
+
+// TransactionTreeNode is a transaction with its descendants nested below it.
+type TransactionTreeNode struct {
+	models.Transaction
+	Depth    int                    `json:"depth"`
+	Children []*TransactionTreeNode `json:"children"`
+}
+
+// AncestorChain lists the ancestors of a transaction from its parent up to the root.
+type AncestorChain struct {
+	TransactionID uint `json:"transaction_id"`
+	// RootID is the ID of the root of the tree, the transaction itself when it has no parent.
+	RootID    uint                 `json:"root_id"`
+	Ancestors []models.Transaction `json:"ancestors"`
+}
+
+// TransitiveSum is the sum of the descendants of a transaction.
+type TransitiveSum struct {
+	// Sum is the total in Currency. It is nil when the descendants use several
+	// currencies and no target currency was requested.
+	Sum *decimal.Decimal `json:"sum,omitempty"`
+	// Currency is the currency of Sum.
+	Currency string `json:"currency,omitempty"`
+	// Subtotals holds the unconverted sum for every currency found among the descendants.
+	Subtotals map[string]decimal.Decimal `json:"subtotals"`
+}
+
+// Option configures optional dependencies of the transaction service.
+type Option func(*transactionService)
+
+// WithRatesProvider enables conversion of transitive sums into a single currency.
+func WithRatesProvider(provider rates.Provider) Option {
+	return func(t *transactionService) {
+		t.ratesProvider = provider
+	}
+}
+
+// WithRules enforces the rules of ruleSet on created transactions.
+func WithRules(ruleSet *rules.RuleSet) Option {
+	return func(t *transactionService) {
+		t.rules = ruleSet
+	}
+}
+
+// WithTransactionTypeService normalizes the types of written transactions and type lookups with the type
+// registry, which also rejects unregistered types in strict mode.
+func WithTransactionTypeService(typeService TransactionTypeServiceI) Option {
+	return func(t *transactionService) {
+		t.typeService = typeService
+	}
+}
+
+// WithMaxTreeDepth overrides the maximum number of ancestors a transaction may have.
+func WithMaxTreeDepth(maxTreeDepth int) Option {
+	return func(t *transactionService) {
+		t.maxTreeDepth = maxTreeDepth
+	}
 }
 
 type transactionService struct {
 	transactionRepo repositories.TransactionRepositoryI
+	ratesProvider   rates.Provider
+	rules           *rules.RuleSet
+	typeService     TransactionTypeServiceI
+	maxTreeDepth    int
 }
 
 func NewTransactionService() TransactionServiceI {
-	return &transactionService{
-		transactionRepo: repositories.NewTransactionRepository(),
+	if defaultCurrency := config.Get().DefaultCurrency; !currency.IsValid(defaultCurrency) {
+		log.Fatalf("Invalid DEFAULT_CURRENCY %q, must be an ISO 4217 code", defaultCurrency)
+	}
+	opts := []Option{WithTransactionTypeService(NewTransactionTypeService())}
+	if ratesFile := config.Get().RatesFile; ratesFile != "" {
+		provider, err := rates.LoadStaticProvider(ratesFile)
+		if err != nil {
+			log.Fatalf("Error loading rates file: %v", err)
+		}
+		opts = append(opts, WithRatesProvider(provider))
+	}
+	if rulesFile := config.Get().RulesFile; rulesFile != "" {
+		ruleSet, err := rules.Load(rulesFile)
+		if err != nil {
+			log.Fatalf("Error loading rules file: %v", err)
+		}
+		opts = append(opts, WithRules(ruleSet))
+	}
+	service := MakeTransactionService(repositories.NewTransactionRepository(), opts...)
+	if config.Get().CacheEnabled {
+		service = MakeCachedTransactionService(service, newCacheStore(), config.Get().CacheTTL)
 	}
+	return service
 }
 
-func MakeTransactionService(transactionRepo repositories.TransactionRepositoryI) TransactionServiceI {
-	return &transactionService{
+func MakeTransactionService(transactionRepo repositories.TransactionRepositoryI, opts ...Option) TransactionServiceI {
+	service := &transactionService{
 		transactionRepo: transactionRepo,
+		maxTreeDepth:    config.Get().MaxTreeDepth,
 	}
+	for _, opt := range opts {
+		opt(service)
+	}
+	return service
 }
 
-// CreateTransaction creates a new transaction using the provided transaction data.
-func (t *transactionService) CreateTransaction(transaction models.Transaction) (bool, error) {
+// CreateTransaction creates a new transaction using the provided transaction data and reports whether it was created.
+// Re-submitting a transaction identical to the stored one is not an error: the stored transaction is returned
+// with created set to false. Re-submitting it with different values returns a *TransactionConflictError.
+func (t *transactionService) CreateTransaction(ctx context.Context, transaction models.Transaction) (*models.Transaction, bool, error) {
+	var err error
+	transaction.Type, err = t.resolveType(ctx, transaction.Type)
+	if err != nil {
+		return nil, false, err
+	}
 
+	var parentTransaction *models.Transaction
+	parentDepth := 0
 	if transaction.ParentID != nil {
-		parentTransaction, err := t.transactionRepo.GetByID(*transaction.ParentID)
+		parentTransaction, err = t.transactionRepo.GetByID(ctx, *transaction.ParentID)
 		if err != nil {
-			return false, err
+			return nil, false, fmt.Errorf("getting parent transaction %d: %w", *transaction.ParentID, err)
 		}
 
 		if parentTransaction == nil {
-			return false, ErrParentTransactionNotFound
+			return nil, false, ErrParentTransactionNotFound
+		}
+
+		parentDepth, err = t.transactionRepo.GetDepth(ctx, *transaction.ParentID)
+		if err != nil {
+			return nil, false, fmt.Errorf("getting depth of parent transaction %d: %w", *transaction.ParentID, err)
 		}
 	}
 
-	err := t.transactionRepo.Create(&transaction)
+	if err := t.checkNewTransaction(&transaction, parentTransaction, parentDepth); err != nil {
+		return nil, false, err
+	}
+
+	err = t.writeChecked(ctx, t.transactionRepo, transaction, parentTransaction, func(transactionRepo repositories.TransactionRepositoryI) error {
+		if err := transactionRepo.Create(ctx, &transaction); err != nil {
+			return fmt.Errorf("creating transaction %d: %w", transaction.Id, err)
+		}
+		return nil
+	})
+	if errors.Is(err, repositories.ErrTransactionAlreadyExist) {
+		return replayTransaction(ctx, t.transactionRepo, transaction)
+	}
 	if err != nil {
-		return false, err
+		return nil, false, err
 	}
-	return true, nil
+	return &transaction, true, nil
 }
 
-// GetTransactionIDsByType retrieves a list of transaction IDs that match the given transactionType.
-func (t *transactionService) GetTransactionIDsByType(transactionType string) ([]uint, error) {
-	var transactionIDs []uint
+// checkNewTransaction applies defaults to a transaction about to be created and validates it against its
+// parent, which is nil for a root transaction and otherwise sits parentDepth levels below its root.
+func (t *transactionService) checkNewTransaction(transaction *models.Transaction, parent *models.Transaction, parentDepth int) error {
+	if transaction.Currency == "" {
+		transaction.Currency = config.Get().DefaultCurrency
+	}
+	if !currency.IsValid(transaction.Currency) {
+		return ErrInvalidCurrency
+	}
+	transaction.OccurredAt = storedTime(transaction.OccurredAt)
+
+	if parent != nil && parentDepth+1 > t.maxTreeDepth {
+		return repositories.ErrMaxDepthExceeded
+	}
+	return nil
+}
 
-	transactions, err := t.transactionRepo.GetByType(transactionType)
+// writeChecked checks a transaction about to be written under parent, nil for a root transaction, against
+// the rules of the service before running write with transactionRepo. When the type of parent bounds its
+// children, the check and write run in a database transaction holding a lock on parent, so that concurrent
+// writes under parent cannot all see room for one more child.
+func (t *transactionService) writeChecked(ctx context.Context, transactionRepo repositories.TransactionRepositoryI, transaction models.Transaction, parent *models.Transaction, write func(transactionRepo repositories.TransactionRepositoryI) error) error {
+	bounded := false
+	if t.rules != nil && parent != nil {
+		_, bounded = t.rules.MaxChildren(parent.Type)
+	}
+	if !bounded {
+		if err := t.checkRules(ctx, transactionRepo, transaction, parent); err != nil {
+			return err
+		}
+		return write(transactionRepo)
+	}
+
+	return transactionRepo.WithinTransaction(ctx, func(txRepo repositories.TransactionRepositoryI) error {
+		if err := txRepo.LockByID(ctx, parent.Id); err != nil {
+			return fmt.Errorf("locking parent transaction %d: %w", parent.Id, err)
+		}
+		if err := t.checkRules(ctx, txRepo, transaction, parent); err != nil {
+			return err
+		}
+		return write(txRepo)
+	})
+}
+
+// checkRules checks a transaction about to be created or updated under parent, nil for a root transaction,
+// against the rules of the service, counting the children of parent in transactionRepo when its type bounds
+// them. The transaction itself is never counted, so replays and updates that keep their parent pass.
+func (t *transactionService) checkRules(ctx context.Context, transactionRepo repositories.TransactionRepositoryI, transaction models.Transaction, parent *models.Transaction) error {
+	if t.rules == nil {
+		return nil
+	}
+
+	parentChildren := 0
+	if parent != nil {
+		if _, bounded := t.rules.MaxChildren(parent.Type); bounded {
+			childIDs, err := transactionRepo.GetChildIDs(ctx, parent.Id)
+			if err != nil {
+				return fmt.Errorf("getting children of transaction %d: %w", parent.Id, err)
+			}
+			// A replayed or updated transaction may already be one of the children
+			for _, childID := range childIDs {
+				if childID != transaction.Id {
+					parentChildren++
+				}
+			}
+		}
+	}
+	return t.rules.Check(transaction, parent, parentChildren)
+}
+
+// resolveType returns the normalized form of a transaction type about to be written, or an error when the
+// type registry rejects it. Types are left unchanged without a type registry.
+func (t *transactionService) resolveType(ctx context.Context, transactionType string) (string, error) {
+	if t.typeService == nil {
+		return transactionType, nil
+	}
+	transactionType = t.typeService.NormalizeTransactionType(transactionType)
+	if err := t.typeService.CheckTransactionType(ctx, transactionType); err != nil {
+		return "", err
+	}
+	return transactionType, nil
+}
+
+// replayTransaction compares a re-submitted transaction with the one stored in transactionRepo.
+func replayTransaction(ctx context.Context, transactionRepo repositories.TransactionRepositoryI, transaction models.Transaction) (*models.Transaction, bool, error) {
+	storedTransaction, err := transactionRepo.GetByID(ctx, transaction.Id)
+	if err != nil {
+		return nil, false, fmt.Errorf("getting stored transaction %d: %w", transaction.Id, err)
+	}
+	if storedTransaction == nil {
+		return nil, false, repositories.ErrTransactionAlreadyExist
+	}
+
+	if diff := diffTransactions(storedTransaction, &transaction); len(diff) > 0 {
+		return nil, false, &TransactionConflictError{Diff: diff}
+	}
+	return storedTransaction, false, nil
+}
+
+// diffTransactions returns the client-supplied fields that differ between the stored and requested transaction.
+func diffTransactions(stored, requested *models.Transaction) map[string]FieldDiff {
+	diff := make(map[string]FieldDiff)
+	if !stored.Amount.Equal(requested.Amount) {
+		diff["amount"] = FieldDiff{Stored: stored.Amount, Requested: requested.Amount}
+	}
+	if stored.Type != requested.Type {
+		diff["type"] = FieldDiff{Stored: stored.Type, Requested: requested.Type}
+	}
+	if !equalParentIDs(stored.ParentID, requested.ParentID) {
+		diff["parent_id"] = FieldDiff{Stored: stored.ParentID, Requested: requested.ParentID}
+	}
+	if stored.Currency != requested.Currency {
+		diff["currency"] = FieldDiff{Stored: stored.Currency, Requested: requested.Currency}
+	}
+	if !equalTimes(stored.OccurredAt, requested.OccurredAt) {
+		diff["occurred_at"] = FieldDiff{Stored: stored.OccurredAt, Requested: requested.OccurredAt}
+	}
+	return diff
+}
+
+func equalParentIDs(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func equalTimes(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+// storedTime returns a client-supplied time as it is stored: in UTC, at the microsecond precision of the
+// timestamp columns.
+func storedTime(value *time.Time) *time.Time {
+	if value == nil {
+		return nil
+	}
+	stored := value.UTC().Truncate(time.Microsecond)
+	return &stored
+}
+
+// UpdateTransaction applies a partial update to an existing transaction and returns the updated transaction.
+// The transaction, and the parent it moves under, stay locked from being read to the update being written,
+// so that concurrent updates neither overwrite each other nor pass their checks against a stale tree.
+func (t *transactionService) UpdateTransaction(ctx context.Context, transactionID uint, update models.TransactionUpdate) (*models.Transaction, error) {
+	// The supplied fields that do not depend on the stored transaction are checked before locking it
+	var transactionType string
+	if update.Type != nil {
+		var err error
+		transactionType, err = t.resolveType(ctx, *update.Type)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if update.Currency != nil && !currency.IsValid(*update.Currency) {
+		return nil, ErrInvalidCurrency
+	}
+
+	var transaction *models.Transaction
+	err := t.transactionRepo.WithinTransaction(ctx, func(txRepo repositories.TransactionRepositoryI) error {
+		if err := txRepo.LockByID(ctx, transactionID); err != nil {
+			return fmt.Errorf("locking transaction %d: %w", transactionID, err)
+		}
+		var err error
+		transaction, err = txRepo.GetByID(ctx, transactionID)
+		if err != nil {
+			return fmt.Errorf("getting transaction %d: %w", transactionID, err)
+		}
+
+		if transaction == nil {
+			return ErrTransactionNotFound
+		}
+
+		if update.Amount != nil {
+			transaction.Amount = *update.Amount
+		}
+		typeChanged := false
+		if update.Type != nil {
+			typeChanged = transactionType != transaction.Type
+			transaction.Type = transactionType
+		}
+		if update.Currency != nil {
+			transaction.Currency = *update.Currency
+		}
+		if update.OccurredAt != nil {
+			transaction.OccurredAt = storedTime(update.OccurredAt)
+		}
+		var parentTransaction *models.Transaction
+		if update.SetParentID {
+			if update.ParentID != nil {
+				if *update.ParentID == transactionID {
+					return ErrInvalidParent
+				}
+
+				if err := txRepo.LockByID(ctx, *update.ParentID); err != nil {
+					return fmt.Errorf("locking parent transaction %d: %w", *update.ParentID, err)
+				}
+				parentTransaction, err = txRepo.GetByID(ctx, *update.ParentID)
+				if err != nil {
+					return fmt.Errorf("getting parent transaction %d: %w", *update.ParentID, err)
+				}
+
+				if parentTransaction == nil {
+					return ErrParentTransactionNotFound
+				}
+
+				if err := t.checkReparent(ctx, txRepo, transactionID, *update.ParentID); err != nil {
+					return err
+				}
+			}
+			transaction.ParentID = update.ParentID
+		}
+
+		// The updated transaction must keep to the rules it was created under, as if it were created now
+		if t.rules != nil && parentTransaction == nil && transaction.ParentID != nil {
+			parentTransaction, err = txRepo.GetByID(ctx, *transaction.ParentID)
+			if err != nil {
+				return fmt.Errorf("getting parent transaction %d: %w", *transaction.ParentID, err)
+			}
+		}
+
+		return t.writeChecked(ctx, txRepo, *transaction, parentTransaction, func(transactionRepo repositories.TransactionRepositoryI) error {
+			// The children must keep to the rules under the new type of their parent
+			if t.rules != nil && typeChanged {
+				childIDs, err := transactionRepo.GetChildIDs(ctx, transactionID)
+				if err != nil {
+					return fmt.Errorf("getting children of transaction %d: %w", transactionID, err)
+				}
+				children, err := transactionRepo.GetByIDs(ctx, childIDs)
+				if err != nil {
+					return fmt.Errorf("getting children of transaction %d: %w", transactionID, err)
+				}
+				if err := t.checkChildren(children, transaction, 0); err != nil {
+					return err
+				}
+			}
+			if err := transactionRepo.Update(ctx, transaction); err != nil {
+				return fmt.Errorf("updating transaction %d: %w", transactionID, err)
+			}
+			return nil
+		})
+	})
 	if err != nil {
 		return nil, err
 	}
+	return transaction, nil
+}
+
+// checkReparent verifies with transactionRepo that moving a transaction under parentID neither creates a
+// cycle nor pushes any of its descendants beyond the maximum tree depth.
+func (t *transactionService) checkReparent(ctx context.Context, transactionRepo repositories.TransactionRepositoryI, transactionID, parentID uint) error {
+	parentAncestors, err := transactionRepo.GetAncestors(ctx, parentID)
+	if err != nil {
+		return fmt.Errorf("getting ancestors of parent transaction %d: %w", parentID, err)
+	}
+
+	for _, ancestor := range parentAncestors {
+		if ancestor.Id == transactionID {
+			return repositories.ErrCycleDetected
+		}
+	}
+
+	subtree, err := transactionRepo.GetDescendants(ctx, transactionID, t.maxTreeDepth)
+	if err != nil {
+		return fmt.Errorf("getting descendants of transaction %d: %w", transactionID, err)
+	}
+
+	subtreeHeight := 0
+	for _, node := range subtree {
+		if node.Depth > subtreeHeight {
+			subtreeHeight = node.Depth
+		}
+	}
+
+	if len(parentAncestors)+1+subtreeHeight > t.maxTreeDepth {
+		return repositories.ErrMaxDepthExceeded
+	}
+	return nil
+}
+
+// DeleteTransaction deletes a transaction, handling its children according to mode.
+func (t *transactionService) DeleteTransaction(ctx context.Context, transactionID uint, mode repositories.DeleteMode) error {
+	transaction, err := t.transactionRepo.GetByID(ctx, transactionID)
+	if err != nil {
+		return fmt.Errorf("getting transaction %d: %w", transactionID, err)
+	}
+
+	if transaction == nil {
+		return ErrTransactionNotFound
+	}
+
+	if mode != repositories.DeleteModeReparent || t.rules == nil {
+		if err := t.transactionRepo.Delete(ctx, transactionID, mode); err != nil {
+			return fmt.Errorf("deleting transaction %d: %w", transactionID, err)
+		}
+		return nil
+	}
+
+	// The children move to the parent of the transaction, or become roots, and must keep to the rules there
+	return t.transactionRepo.WithinTransaction(ctx, func(txRepo repositories.TransactionRepositoryI) error {
+		if err := txRepo.LockByID(ctx, transactionID); err != nil {
+			return fmt.Errorf("locking transaction %d: %w", transactionID, err)
+		}
+		transaction, err := txRepo.GetByID(ctx, transactionID)
+		if err != nil {
+			return fmt.Errorf("getting transaction %d: %w", transactionID, err)
+		}
+		if transaction == nil {
+			return ErrTransactionNotFound
+		}
+
+		var newParent *models.Transaction
+		newParentChildren := 0
+		if transaction.ParentID != nil {
+			parentID := *transaction.ParentID
+			if err := txRepo.LockByID(ctx, parentID); err != nil {
+				return fmt.Errorf("locking parent transaction %d: %w", parentID, err)
+			}
+			if newParent, err = txRepo.GetByID(ctx, parentID); err != nil {
+				return fmt.Errorf("getting parent transaction %d: %w", parentID, err)
+			}
+			siblingIDs, err := txRepo.GetChildIDs(ctx, parentID)
+			if err != nil {
+				return fmt.Errorf("getting children of transaction %d: %w", parentID, err)
+			}
+			// The deleted transaction leaves room for one of its children
+			for _, siblingID := range siblingIDs {
+				if siblingID != transactionID {
+					newParentChildren++
+				}
+			}
+		}
+
+		childIDs, err := txRepo.GetChildIDs(ctx, transactionID)
+		if err != nil {
+			return fmt.Errorf("getting children of transaction %d: %w", transactionID, err)
+		}
+		children, err := txRepo.GetByIDs(ctx, childIDs)
+		if err != nil {
+			return fmt.Errorf("getting children of transaction %d: %w", transactionID, err)
+		}
+		if err := t.checkChildren(children, newParent, newParentChildren); err != nil {
+			return err
+		}
+
+		if err := txRepo.Delete(ctx, transactionID, mode); err != nil {
+			return fmt.Errorf("deleting transaction %d: %w", transactionID, err)
+		}
+		return nil
+	})
+}
+
+// checkChildren checks children about to sit under parent, nil when they become roots, against the rules
+// of the service, parent already having otherChildren other children. Each child counts against the ones
+// checked after it.
+func (t *transactionService) checkChildren(children []models.Transaction, parent *models.Transaction, otherChildren int) error {
+	for i, child := range children {
+		if err := t.rules.Check(child, parent, otherChildren+i); err != nil {
+			return apperror.From(err).WithDetails(map[string]interface{}{"transaction_id": child.Id})
+		}
+	}
+	return nil
+}
+
+// GetTransaction retrieves a transaction by its ID along with the details selected by opts.
+func (t *transactionService) GetTransaction(ctx context.Context, transactionID uint, opts GetTransactionOptions) (*TransactionDetails, error) {
+	transaction, err := t.transactionRepo.GetByID(ctx, transactionID)
+	if err != nil {
+		return nil, fmt.Errorf("getting transaction %d: %w", transactionID, err)
+	}
+
+	if transaction == nil {
+		return nil, ErrTransactionNotFound
+	}
+
+	details := &TransactionDetails{Transaction: transaction}
+	if opts.IncludeChildren {
+		childIDs, err := t.transactionRepo.GetChildIDs(ctx, transactionID)
+		if err != nil {
+			return nil, fmt.Errorf("getting children of transaction %d: %w", transactionID, err)
+		}
+		details.ChildIDs = &childIDs
+	}
+	if opts.IncludeDepth {
+		depth, err := t.transactionRepo.GetDepth(ctx, transactionID)
+		if err != nil {
+			return nil, fmt.Errorf("getting depth of transaction %d: %w", transactionID, err)
+		}
+		details.Depth = &depth
+	}
+
+	return details, nil
+}
+
+// GetTransactionIDsByType retrieves a page of transaction IDs that match the given query.
+func (t *transactionService) GetTransactionIDsByType(ctx context.Context, query repositories.TypeQuery) (*TransactionIDPage, error) {
+	if query.Limit <= 0 {
+		query.Limit = DefaultPageLimit
+	}
+	if query.Limit > MaxPageLimit {
+		query.Limit = MaxPageLimit
+	}
+	limit := query.Limit
+	if t.typeService != nil {
+		query.Type = t.typeService.NormalizeTransactionType(query.Type)
+	}
+
+	// Fetch one extra transaction to find out whether there is a next page
+	query.Limit++
+	transactions, err := t.transactionRepo.GetByType(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("getting transactions of type %q: %w", query.Type, err)
+	}
+
+	page := &TransactionIDPage{TransactionIDs: []uint{}}
+	if len(transactions) > limit {
+		transactions = transactions[:limit]
+		nextAfterID := transactions[limit-1].Id
+		page.NextAfterID = &nextAfterID
+	}
 
 	// Extract transaction IDs from the fetched transactions
 	for _, transaction := range transactions {
-		transactionIDs = append(transactionIDs, transaction.Id)
+		page.TransactionIDs = append(page.TransactionIDs, transaction.Id)
+	}
+
+	return page, nil
+}
+
+// GetTransactionTree retrieves a transaction and its descendants as a flat list ordered by depth,
+// each node carrying its depth and the path of IDs leading to it from the requested transaction.
+func (t *transactionService) GetTransactionTree(ctx context.Context, transactionID uint, opts TreeOptions) ([]models.TransactionNode, error) {
+	maxDepth := opts.MaxDepth
+	if maxDepth <= 0 {
+		maxDepth = math.MaxInt32
+	}
+
+	nodes, err := t.transactionRepo.GetDescendants(ctx, transactionID, maxDepth)
+	if err != nil {
+		return nil, fmt.Errorf("getting descendants of transaction %d: %w", transactionID, err)
+	}
+
+	if len(nodes) == 0 {
+		return nil, ErrTransactionNotFound
+	}
+
+	if opts.Type == "" {
+		return nodes, nil
 	}
 
-	return transactionIDs, nil
+	filtered := nodes[:1]
+	for _, node := range nodes[1:] {
+		if node.Type == opts.Type {
+			filtered = append(filtered, node)
+		}
+	}
+	return filtered, nil
+}
+
+// NestTransactionTree nests a flat list of nodes returned by GetTransactionTree under its first node.
+// Nodes whose parent was filtered out are attached to their closest ancestor present in the list.
+func NestTransactionTree(nodes []models.TransactionNode) *TransactionTreeNode {
+	if len(nodes) == 0 {
+		return nil
+	}
+
+	treeNodes := make(map[uint]*TransactionTreeNode, len(nodes))
+	root := &TransactionTreeNode{Transaction: nodes[0].Transaction, Depth: nodes[0].Depth, Children: []*TransactionTreeNode{}}
+	treeNodes[root.Id] = root
+
+	for _, node := range nodes[1:] {
+		treeNode := &TransactionTreeNode{Transaction: node.Transaction, Depth: node.Depth, Children: []*TransactionTreeNode{}}
+		treeNodes[node.Id] = treeNode
+
+		// Walk up the path to the closest ancestor that is part of the tree
+		for i := len(node.Path) - 2; i >= 0; i-- {
+			if parent, ok := treeNodes[node.Path[i]]; ok {
+				parent.Children = append(parent.Children, treeNode)
+				break
+			}
+		}
+	}
+	return root
+}
+
+// GetAncestors retrieves the chain of ancestors of a transaction up to the root of its tree.
+func (t *transactionService) GetAncestors(ctx context.Context, transactionID uint) (*AncestorChain, error) {
+	transaction, err := t.transactionRepo.GetByID(ctx, transactionID)
+	if err != nil {
+		return nil, fmt.Errorf("getting transaction %d: %w", transactionID, err)
+	}
+
+	if transaction == nil {
+		return nil, ErrTransactionNotFound
+	}
+
+	ancestors, err := t.transactionRepo.GetAncestors(ctx, transactionID)
+	if err != nil {
+		return nil, fmt.Errorf("getting ancestors of transaction %d: %w", transactionID, err)
+	}
+
+	chain := &AncestorChain{TransactionID: transactionID, RootID: transactionID, Ancestors: ancestors}
+	if len(ancestors) > 0 {
+		chain.RootID = ancestors[len(ancestors)-1].Id
+	}
+	return chain, nil
 }
 
 // GetTransitiveSum retrieves the sum of all transactions transitively linked by their parent_id to a given transaction ID.
-func (t *transactionService) GetTransitiveSum(transactionID uint) (float64, error) {
-	Transaction, err := t.transactionRepo.GetByID(transactionID)
+// Amounts are never added across currencies: when targetCurrency is empty the total is only reported if every
+// descendant shares one currency, otherwise each subtotal is converted into targetCurrency with the rates provider.
+func (t *transactionService) GetTransitiveSum(ctx context.Context, transactionID uint, targetCurrency string) (*TransitiveSum, error) {
+	if targetCurrency != "" && !currency.IsValid(targetCurrency) {
+		return nil, ErrInvalidCurrency
+	}
+
+	Transaction, err := t.transactionRepo.GetByID(ctx, transactionID)
 	if err != nil {
-		return 0, err
+		return nil, fmt.Errorf("getting transaction %d: %w", transactionID, err)
 	}
 
 	if Transaction == nil {
-		return 0, ErrTransactionNotFound
+		return nil, ErrTransactionNotFound
+	}
+
+	subtotals, err := t.transactionRepo.GetTransitiveSum(ctx, transactionID)
+	if err != nil {
+		return nil, fmt.Errorf("getting transitive sum of transaction %d: %w", transactionID, err)
+	}
+
+	result := &TransitiveSum{Subtotals: subtotals}
+	if targetCurrency == "" {
+		switch len(subtotals) {
+		case 0:
+			zero := decimal.Zero
+			result.Sum = &zero
+		case 1:
+			for code, subtotal := range subtotals {
+				subtotal := subtotal
+				result.Sum = &subtotal
+				result.Currency = code
+			}
+		}
+		return result, nil
 	}
-	return t.transactionRepo.GetTransitiveSum(transactionID)
+
+	total := decimal.Zero
+	for code, subtotal := range subtotals {
+		if code == targetCurrency {
+			total = total.Add(subtotal)
+			continue
+		}
+		if t.ratesProvider == nil {
+			return nil, ErrCurrencyConversionUnavailable
+		}
+		converted, err := t.ratesProvider.Convert(subtotal, code, targetCurrency)
+		if err != nil {
+			return nil, fmt.Errorf("converting %s subtotal to %s: %w", code, targetCurrency, err)
+		}
+		total = total.Add(converted)
+	}
+	result.Sum = &total
+	result.Currency = targetCurrency
+
+	return result, nil
 }
@@ -1,14 +1,25 @@
 package services_test
 
 import (
+	"context"
 	"errors"
 	"github.com/golang/mock/gomock"
+	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
 	"testing"
+	"time"
 
+	"transaction_system/app/lib/apperror"
+	"transaction_system/app/lib/audit"
+	"transaction_system/app/lib/cache"
+	"transaction_system/app/lib/config"
+	"transaction_system/app/lib/rates"
+	"transaction_system/app/lib/rules"
 	"transaction_system/app/models"
+	"transaction_system/app/repositories"
 	"transaction_system/app/repositories/mock_repositories"
 	"transaction_system/app/services"
+	"transaction_system/app/services/mock_services"
 )
 
 func TestCreateTransaction_Success(t *testing.T) {
@@ -24,15 +35,15 @@ func TestCreateTransaction_Success(t *testing.T) {
 	// Test data
 	transaction := models.Transaction{
 		Id:     1,
-		Amount: 100.0,
+		Amount: decimal.NewFromInt(100),
 		Type:   "purchase",
 	}
 
 	// Mock expectations
-	mockTransactionRepo.EXPECT().Create(gomock.Any()).Return(nil)
+	mockTransactionRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil)
 
 	// Test the service method
-	status, err := transactionService.CreateTransaction(transaction)
+	_, status, err := transactionService.CreateTransaction(context.Background(), transaction)
 
 	// Assert the result
 	assert.True(t, status)
@@ -54,16 +65,16 @@ func TestCreateTransaction_ParentTransactionNotFound(t *testing.T) {
 	// Test data
 	transaction := models.Transaction{
 		Id:       1,
-		Amount:   100.0,
+		Amount:   decimal.NewFromInt(100),
 		Type:     "purchase",
 		ParentID: &parentID,
 	}
 
 	// Mock expectations
-	mockTransactionRepo.EXPECT().GetByID(parentID).Return(nil, nil) // Set up expectation for GetByID
+	mockTransactionRepo.EXPECT().GetByID(gomock.Any(), parentID).Return(nil, nil) // Set up expectation for GetByID
 
 	// Test the service method
-	status, err := transactionService.CreateTransaction(transaction)
+	_, status, err := transactionService.CreateTransaction(context.Background(), transaction)
 
 	// Assert the result
 	assert.False(t, status)
@@ -84,18 +95,1503 @@ func TestCreateTransaction_TransactionAlreadyExist(t *testing.T) {
 	// Test data
 	transaction := models.Transaction{
 		Id:     1,
-		Amount: 100.0,
+		Amount: decimal.NewFromInt(100),
 		Type:   "purchase",
 	}
 
 	// Mock expectations
-	mockTransactionRepo.EXPECT().Create(gomock.Any()).Return(errors.New("transaction with the same ID already exists"))
+	mockTransactionRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(errors.New("transaction with the same ID already exists"))
 
 	// Test the service method
-	status, err := transactionService.CreateTransaction(transaction)
+	_, status, err := transactionService.CreateTransaction(context.Background(), transaction)
 
 	// Assert the result
 	assert.False(t, status)
 	assert.Error(t, err)
-	assert.EqualError(t, err, "transaction with the same ID already exists")
+	assert.EqualError(t, err, "creating transaction 1: transaction with the same ID already exists")
+}
+
+func TestGetTransitiveSum_Success(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Mocks
+	mockTransactionRepo := mock_repositories.NewMockTransactionRepositoryI(ctrl)
+
+	// Service
+	transactionService := services.MakeTransactionService(mockTransactionRepo)
+
+	transactionID := uint(1)
+	expectedSum, _ := decimal.NewFromString("0.3")
+
+	// Mock expectations
+	mockTransactionRepo.EXPECT().GetByID(gomock.Any(), transactionID).Return(&models.Transaction{Id: transactionID, Type: "purchase"}, nil)
+	mockTransactionRepo.EXPECT().GetTransitiveSum(gomock.Any(), transactionID).Return(map[string]decimal.Decimal{"USD": expectedSum}, nil)
+
+	// Test the service method
+	sum, err := transactionService.GetTransitiveSum(context.Background(), transactionID, "")
+
+	// Assert the result
+	assert.NoError(t, err)
+	assert.True(t, expectedSum.Equal(*sum.Sum))
+	assert.Equal(t, "USD", sum.Currency)
+}
+
+func TestGetTransitiveSum_MixedCurrencies(t *testing.T) {
+	t.Run("without a target currency", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		// Mocks
+		mockTransactionRepo := mock_repositories.NewMockTransactionRepositoryI(ctrl)
+
+		// Service
+		transactionService := services.MakeTransactionService(mockTransactionRepo)
+
+		subtotals := map[string]decimal.Decimal{"USD": decimal.NewFromInt(10), "EUR": decimal.NewFromInt(5)}
+
+		// Mock expectations
+		mockTransactionRepo.EXPECT().GetByID(gomock.Any(), uint(1)).Return(&models.Transaction{Id: 1, Type: "purchase"}, nil)
+		mockTransactionRepo.EXPECT().GetTransitiveSum(gomock.Any(), uint(1)).Return(subtotals, nil)
+
+		// Test the service method
+		sum, err := transactionService.GetTransitiveSum(context.Background(), 1, "")
+
+		// Assert only subtotals are reported
+		assert.NoError(t, err)
+		assert.Nil(t, sum.Sum)
+		assert.Equal(t, subtotals, sum.Subtotals)
+	})
+
+	t.Run("with a target currency and rates provider", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		// Mocks
+		mockTransactionRepo := mock_repositories.NewMockTransactionRepositoryI(ctrl)
+
+		// Service
+		provider := rates.NewStaticProvider("USD", map[string]decimal.Decimal{"EUR": decimal.RequireFromString("0.5")})
+		transactionService := services.MakeTransactionService(mockTransactionRepo, services.WithRatesProvider(provider))
+
+		subtotals := map[string]decimal.Decimal{"USD": decimal.NewFromInt(10), "EUR": decimal.NewFromInt(5)}
+
+		// Mock expectations
+		mockTransactionRepo.EXPECT().GetByID(gomock.Any(), uint(1)).Return(&models.Transaction{Id: 1, Type: "purchase"}, nil)
+		mockTransactionRepo.EXPECT().GetTransitiveSum(gomock.Any(), uint(1)).Return(subtotals, nil)
+
+		// Test the service method
+		sum, err := transactionService.GetTransitiveSum(context.Background(), 1, "USD")
+
+		// Assert EUR subtotal was converted before adding
+		assert.NoError(t, err)
+		assert.Equal(t, "20", sum.Sum.String())
+		assert.Equal(t, "USD", sum.Currency)
+	})
+
+	t.Run("with a target currency and no rates provider", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		// Mocks
+		mockTransactionRepo := mock_repositories.NewMockTransactionRepositoryI(ctrl)
+
+		// Service
+		transactionService := services.MakeTransactionService(mockTransactionRepo)
+
+		subtotals := map[string]decimal.Decimal{"USD": decimal.NewFromInt(10), "EUR": decimal.NewFromInt(5)}
+
+		// Mock expectations
+		mockTransactionRepo.EXPECT().GetByID(gomock.Any(), uint(1)).Return(&models.Transaction{Id: 1, Type: "purchase"}, nil)
+		mockTransactionRepo.EXPECT().GetTransitiveSum(gomock.Any(), uint(1)).Return(subtotals, nil)
+
+		// Test the service method
+		sum, err := transactionService.GetTransitiveSum(context.Background(), 1, "USD")
+
+		// Assert the result
+		assert.Nil(t, sum)
+		assert.Equal(t, services.ErrCurrencyConversionUnavailable, err)
+	})
+}
+
+func TestGetTransitiveSum_TransactionNotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Mocks
+	mockTransactionRepo := mock_repositories.NewMockTransactionRepositoryI(ctrl)
+
+	// Service
+	transactionService := services.MakeTransactionService(mockTransactionRepo)
+
+	// Mock expectations
+	mockTransactionRepo.EXPECT().GetByID(gomock.Any(), uint(1)).Return(nil, nil)
+
+	// Test the service method
+	sum, err := transactionService.GetTransitiveSum(context.Background(), 1, "")
+
+	// Assert the result
+	assert.Nil(t, sum)
+	assert.EqualError(t, err, "transaction does not exist for given transaction ID")
+}
+
+func TestCreateTransaction_DefaultCurrency(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Mocks
+	mockTransactionRepo := mock_repositories.NewMockTransactionRepositoryI(ctrl)
+
+	// Service
+	transactionService := services.MakeTransactionService(mockTransactionRepo)
+
+	// Test data without a currency
+	transaction := models.Transaction{
+		Id:     1,
+		Amount: decimal.NewFromInt(100),
+		Type:   "purchase",
+	}
+
+	// Mock expectations
+	mockTransactionRepo.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, created *models.Transaction) error {
+		assert.Equal(t, "USD", created.Currency)
+		return nil
+	})
+
+	// Test the service method
+	_, status, err := transactionService.CreateTransaction(context.Background(), transaction)
+
+	// Assert the result
+	assert.True(t, status)
+	assert.NoError(t, err)
+}
+
+func TestCreateTransaction_IdenticalReplay(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Mocks
+	mockTransactionRepo := mock_repositories.NewMockTransactionRepositoryI(ctrl)
+
+	// Service
+	transactionService := services.MakeTransactionService(mockTransactionRepo)
+
+	// Test data
+	transaction := models.Transaction{
+		Id:     1,
+		Amount: decimal.RequireFromString("100.50"),
+		Type:   "purchase",
+	}
+	storedTransaction := &models.Transaction{
+		Id:       1,
+		Amount:   decimal.RequireFromString("100.5"),
+		Type:     "purchase",
+		Currency: "USD",
+	}
+
+	// Mock expectations
+	mockTransactionRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(repositories.ErrTransactionAlreadyExist)
+	mockTransactionRepo.EXPECT().GetByID(gomock.Any(), uint(1)).Return(storedTransaction, nil)
+
+	// Test the service method
+	result, created, err := transactionService.CreateTransaction(context.Background(), transaction)
+
+	// Assert the stored transaction is returned without error
+	assert.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, storedTransaction, result)
+}
+
+func TestCreateTransaction_ConflictingReplay(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Mocks
+	mockTransactionRepo := mock_repositories.NewMockTransactionRepositoryI(ctrl)
+
+	// Service
+	transactionService := services.MakeTransactionService(mockTransactionRepo)
+
+	parentID := uint(2)
+
+	// Test data
+	transaction := models.Transaction{
+		Id:     1,
+		Amount: decimal.NewFromInt(200),
+		Type:   "purchase",
+	}
+	storedTransaction := &models.Transaction{
+		Id:       1,
+		Amount:   decimal.NewFromInt(100),
+		Type:     "purchase",
+		ParentID: &parentID,
+		Currency: "USD",
+	}
+
+	// Mock expectations
+	mockTransactionRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(repositories.ErrTransactionAlreadyExist)
+	mockTransactionRepo.EXPECT().GetByID(gomock.Any(), uint(1)).Return(storedTransaction, nil)
+
+	// Test the service method
+	result, created, err := transactionService.CreateTransaction(context.Background(), transaction)
+
+	// Assert a conflict listing the differing fields
+	assert.Nil(t, result)
+	assert.False(t, created)
+	assert.ErrorIs(t, err, services.ErrTransactionConflict)
+	var conflictErr *services.TransactionConflictError
+	assert.True(t, errors.As(err, &conflictErr))
+	assert.ElementsMatch(t, []string{"amount", "parent_id"}, mapKeys(conflictErr.Diff))
+}
+
+func mapKeys(diff map[string]services.FieldDiff) []string {
+	keys := make([]string, 0, len(diff))
+	for key := range diff {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func TestUpdateTransaction_Success(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Mocks
+	mockTransactionRepo := mock_repositories.NewMockTransactionRepositoryI(ctrl)
+
+	// Service
+	transactionService := services.MakeTransactionService(mockTransactionRepo)
+
+	parentID := uint(2)
+	newAmount := decimal.NewFromInt(50)
+	storedTransaction := &models.Transaction{Id: 1, Amount: decimal.NewFromInt(100), Type: "purchase", Currency: "USD"}
+
+	// Mock expectations: the transaction and its new parent are locked before being read
+	mockTransactionRepo.EXPECT().WithinTransaction(gomock.Any(), gomock.Any()).DoAndReturn(withinTransaction(mockTransactionRepo))
+	gomock.InOrder(
+		mockTransactionRepo.EXPECT().LockByID(gomock.Any(), uint(1)).Return(nil),
+		mockTransactionRepo.EXPECT().GetByID(gomock.Any(), uint(1)).Return(storedTransaction, nil),
+		mockTransactionRepo.EXPECT().LockByID(gomock.Any(), parentID).Return(nil),
+		mockTransactionRepo.EXPECT().GetByID(gomock.Any(), parentID).Return(&models.Transaction{Id: parentID, Type: "purchase"}, nil),
+	)
+	mockTransactionRepo.EXPECT().GetAncestors(gomock.Any(), parentID).Return([]models.Transaction{}, nil)
+	mockTransactionRepo.EXPECT().GetDescendants(gomock.Any(), uint(1), gomock.Any()).Return([]models.TransactionNode{{Transaction: *storedTransaction}}, nil)
+	mockTransactionRepo.EXPECT().Update(gomock.Any(), gomock.Any()).Return(nil)
+
+	// Test the service method
+	updated, err := transactionService.UpdateTransaction(context.Background(), 1, models.TransactionUpdate{
+		Amount:      &newAmount,
+		ParentID:    &parentID,
+		SetParentID: true,
+	})
+
+	// Assert only the supplied fields changed
+	assert.NoError(t, err)
+	assert.True(t, newAmount.Equal(updated.Amount))
+	assert.Equal(t, "purchase", updated.Type)
+	assert.Equal(t, &parentID, updated.ParentID)
+}
+
+func TestUpdateTransaction_OwnParent(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Mocks
+	mockTransactionRepo := mock_repositories.NewMockTransactionRepositoryI(ctrl)
+
+	// Service
+	transactionService := services.MakeTransactionService(mockTransactionRepo)
+
+	parentID := uint(1)
+
+	// Mock expectations
+	mockTransactionRepo.EXPECT().WithinTransaction(gomock.Any(), gomock.Any()).DoAndReturn(withinTransaction(mockTransactionRepo))
+	mockTransactionRepo.EXPECT().LockByID(gomock.Any(), uint(1)).Return(nil)
+	mockTransactionRepo.EXPECT().GetByID(gomock.Any(), uint(1)).Return(&models.Transaction{Id: 1, Type: "purchase"}, nil)
+
+	// Test the service method
+	updated, err := transactionService.UpdateTransaction(context.Background(), 1, models.TransactionUpdate{ParentID: &parentID, SetParentID: true})
+
+	// Assert the result
+	assert.Nil(t, updated)
+	assert.Equal(t, services.ErrInvalidParent, err)
+}
+
+func TestDeleteTransaction_Success(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Mocks
+	mockTransactionRepo := mock_repositories.NewMockTransactionRepositoryI(ctrl)
+
+	// Service
+	transactionService := services.MakeTransactionService(mockTransactionRepo)
+
+	// Mock expectations
+	mockTransactionRepo.EXPECT().GetByID(gomock.Any(), uint(1)).Return(&models.Transaction{Id: 1, Type: "purchase"}, nil)
+	mockTransactionRepo.EXPECT().Delete(gomock.Any(), uint(1), repositories.DeleteModeCascade).Return(nil)
+
+	// Test the service method
+	err := transactionService.DeleteTransaction(context.Background(), 1, repositories.DeleteModeCascade)
+
+	// Assert the result
+	assert.NoError(t, err)
+}
+
+func TestDeleteTransaction_TransactionNotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Mocks
+	mockTransactionRepo := mock_repositories.NewMockTransactionRepositoryI(ctrl)
+
+	// Service
+	transactionService := services.MakeTransactionService(mockTransactionRepo)
+
+	// Mock expectations
+	mockTransactionRepo.EXPECT().GetByID(gomock.Any(), uint(1)).Return(nil, nil)
+
+	// Test the service method
+	err := transactionService.DeleteTransaction(context.Background(), 1, repositories.DeleteModeReject)
+
+	// Assert the result
+	assert.Equal(t, services.ErrTransactionNotFound, err)
+}
+
+func TestGetTransaction_Success(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Mocks
+	mockTransactionRepo := mock_repositories.NewMockTransactionRepositoryI(ctrl)
+
+	// Service
+	transactionService := services.MakeTransactionService(mockTransactionRepo)
+
+	parentID := uint(1)
+	storedTransaction := &models.Transaction{Id: 2, Amount: decimal.NewFromInt(100), Type: "purchase", ParentID: &parentID}
+
+	// Mock expectations
+	mockTransactionRepo.EXPECT().GetByID(gomock.Any(), uint(2)).Return(storedTransaction, nil)
+	mockTransactionRepo.EXPECT().GetChildIDs(gomock.Any(), uint(2)).Return([]uint{3, 4}, nil)
+	mockTransactionRepo.EXPECT().GetDepth(gomock.Any(), uint(2)).Return(1, nil)
+
+	// Test the service method
+	details, err := transactionService.GetTransaction(context.Background(), 2, services.GetTransactionOptions{IncludeChildren: true, IncludeDepth: true})
+
+	// Assert the result
+	assert.NoError(t, err)
+	assert.Equal(t, storedTransaction, details.Transaction)
+	assert.Equal(t, []uint{3, 4}, *details.ChildIDs)
+	assert.Equal(t, 1, *details.Depth)
+}
+
+func TestGetTransaction_TransactionNotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Mocks
+	mockTransactionRepo := mock_repositories.NewMockTransactionRepositoryI(ctrl)
+
+	// Service
+	transactionService := services.MakeTransactionService(mockTransactionRepo)
+
+	// Mock expectations
+	mockTransactionRepo.EXPECT().GetByID(gomock.Any(), uint(2)).Return(nil, nil)
+
+	// Test the service method
+	details, err := transactionService.GetTransaction(context.Background(), 2, services.GetTransactionOptions{IncludeChildren: true})
+
+	// Assert the result
+	assert.Nil(t, details)
+	assert.Equal(t, services.ErrTransactionNotFound, err)
+}
+
+func TestGetTransactionIDsByType_Pagination(t *testing.T) {
+	t.Run("when more transactions remain", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		// Mocks
+		mockTransactionRepo := mock_repositories.NewMockTransactionRepositoryI(ctrl)
+
+		// Service
+		transactionService := services.MakeTransactionService(mockTransactionRepo)
+
+		// Mock expectations: one extra row is requested to detect the next page
+		mockTransactionRepo.EXPECT().GetByType(gomock.Any(), repositories.TypeQuery{Type: "purchase", Limit: 3}).Return([]models.Transaction{
+			{Id: 1}, {Id: 2}, {Id: 3},
+		}, nil)
+
+		// Test the service method
+		page, err := transactionService.GetTransactionIDsByType(context.Background(), repositories.TypeQuery{Type: "purchase", Limit: 2})
+
+		// Assert the result
+		assert.NoError(t, err)
+		assert.Equal(t, []uint{1, 2}, page.TransactionIDs)
+		assert.Equal(t, uint(2), *page.NextAfterID)
+	})
+
+	t.Run("on the last page", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		// Mocks
+		mockTransactionRepo := mock_repositories.NewMockTransactionRepositoryI(ctrl)
+
+		// Service
+		transactionService := services.MakeTransactionService(mockTransactionRepo)
+
+		// Mock expectations: the default limit applies when none is given
+		mockTransactionRepo.EXPECT().GetByType(gomock.Any(), repositories.TypeQuery{Type: "purchase", Limit: services.DefaultPageLimit + 1}).Return([]models.Transaction{
+			{Id: 1},
+		}, nil)
+
+		// Test the service method
+		page, err := transactionService.GetTransactionIDsByType(context.Background(), repositories.TypeQuery{Type: "purchase"})
+
+		// Assert the result
+		assert.NoError(t, err)
+		assert.Equal(t, []uint{1}, page.TransactionIDs)
+		assert.Nil(t, page.NextAfterID)
+	})
+}
+
+func TestGetTransactionTree_TypeFilter(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Mocks
+	mockTransactionRepo := mock_repositories.NewMockTransactionRepositoryI(ctrl)
+
+	// Service
+	transactionService := services.MakeTransactionService(mockTransactionRepo)
+
+	// Tree: 1 (purchase) -> 2 (fee) -> 3 (purchase), 1 -> 4 (purchase)
+	nodes := []models.TransactionNode{
+		{Transaction: models.Transaction{Id: 1, Type: "purchase"}, Depth: 0, Path: []uint{1}},
+		{Transaction: models.Transaction{Id: 2, Type: "fee"}, Depth: 1, Path: []uint{1, 2}},
+		{Transaction: models.Transaction{Id: 4, Type: "purchase"}, Depth: 1, Path: []uint{1, 4}},
+		{Transaction: models.Transaction{Id: 3, Type: "purchase"}, Depth: 2, Path: []uint{1, 2, 3}},
+	}
+
+	// Mock expectations
+	mockTransactionRepo.EXPECT().GetDescendants(gomock.Any(), uint(1), 5).Return(nodes, nil)
+
+	// Test the service method
+	tree, err := transactionService.GetTransactionTree(context.Background(), 1, services.TreeOptions{MaxDepth: 5, Type: "purchase"})
+
+	// Assert the fee node was filtered out
+	assert.NoError(t, err)
+	assert.Len(t, tree, 3)
+
+	// Assert the nested tree attaches 3 to its closest remaining ancestor
+	root := services.NestTransactionTree(tree)
+	assert.Equal(t, uint(1), root.Id)
+	assert.Len(t, root.Children, 2)
+	assert.Equal(t, uint(4), root.Children[0].Id)
+	assert.Equal(t, uint(3), root.Children[1].Id)
+	assert.Equal(t, 2, root.Children[1].Depth)
+}
+
+func TestGetTransactionTree_TransactionNotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Mocks
+	mockTransactionRepo := mock_repositories.NewMockTransactionRepositoryI(ctrl)
+
+	// Service
+	transactionService := services.MakeTransactionService(mockTransactionRepo)
+
+	// Mock expectations
+	mockTransactionRepo.EXPECT().GetDescendants(gomock.Any(), uint(1), gomock.Any()).Return([]models.TransactionNode{}, nil)
+
+	// Test the service method
+	tree, err := transactionService.GetTransactionTree(context.Background(), 1, services.TreeOptions{})
+
+	// Assert the result
+	assert.Nil(t, tree)
+	assert.Equal(t, services.ErrTransactionNotFound, err)
+}
+
+func TestGetAncestors_Success(t *testing.T) {
+	t.Run("for a child transaction", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		// Mocks
+		mockTransactionRepo := mock_repositories.NewMockTransactionRepositoryI(ctrl)
+
+		// Service
+		transactionService := services.MakeTransactionService(mockTransactionRepo)
+
+		parentID, rootID := uint(2), uint(1)
+		ancestors := []models.Transaction{
+			{Id: parentID, Type: "purchase", ParentID: &rootID},
+			{Id: rootID, Type: "purchase"},
+		}
+
+		// Mock expectations
+		mockTransactionRepo.EXPECT().GetByID(gomock.Any(), uint(3)).Return(&models.Transaction{Id: 3, Type: "fee", ParentID: &parentID}, nil)
+		mockTransactionRepo.EXPECT().GetAncestors(gomock.Any(), uint(3)).Return(ancestors, nil)
+
+		// Test the service method
+		chain, err := transactionService.GetAncestors(context.Background(), 3)
+
+		// Assert the result
+		assert.NoError(t, err)
+		assert.Equal(t, uint(3), chain.TransactionID)
+		assert.Equal(t, rootID, chain.RootID)
+		assert.Equal(t, ancestors, chain.Ancestors)
+	})
+
+	t.Run("for a root transaction", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		// Mocks
+		mockTransactionRepo := mock_repositories.NewMockTransactionRepositoryI(ctrl)
+
+		// Service
+		transactionService := services.MakeTransactionService(mockTransactionRepo)
+
+		// Mock expectations
+		mockTransactionRepo.EXPECT().GetByID(gomock.Any(), uint(1)).Return(&models.Transaction{Id: 1, Type: "purchase"}, nil)
+		mockTransactionRepo.EXPECT().GetAncestors(gomock.Any(), uint(1)).Return([]models.Transaction{}, nil)
+
+		// Test the service method
+		chain, err := transactionService.GetAncestors(context.Background(), 1)
+
+		// Assert the transaction is its own root
+		assert.NoError(t, err)
+		assert.Equal(t, uint(1), chain.RootID)
+		assert.Empty(t, chain.Ancestors)
+	})
+}
+
+func TestCreateTransaction_MaxDepthExceeded(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Mocks
+	mockTransactionRepo := mock_repositories.NewMockTransactionRepositoryI(ctrl)
+
+	// Service
+	transactionService := services.MakeTransactionService(mockTransactionRepo, services.WithMaxTreeDepth(3))
+
+	parentID := uint(2)
+
+	// Test data
+	transaction := models.Transaction{
+		Id:       1,
+		Amount:   decimal.NewFromInt(100),
+		Type:     "purchase",
+		ParentID: &parentID,
+	}
+
+	// Mock expectations: the parent already sits at the maximum depth
+	mockTransactionRepo.EXPECT().GetByID(gomock.Any(), parentID).Return(&models.Transaction{Id: parentID, Type: "purchase"}, nil)
+	mockTransactionRepo.EXPECT().GetDepth(gomock.Any(), parentID).Return(3, nil)
+
+	// Test the service method
+	_, status, err := transactionService.CreateTransaction(context.Background(), transaction)
+
+	// Assert the result
+	assert.False(t, status)
+	assert.Equal(t, repositories.ErrMaxDepthExceeded, err)
+}
+
+func TestUpdateTransaction_CycleDetected(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Mocks
+	mockTransactionRepo := mock_repositories.NewMockTransactionRepositoryI(ctrl)
+
+	// Service
+	transactionService := services.MakeTransactionService(mockTransactionRepo)
+
+	// Moving 1 under its own grandchild 3 (1 -> 2 -> 3) would create a cycle
+	newParentID, childID := uint(3), uint(2)
+	rootID := uint(1)
+
+	// Mock expectations
+	mockTransactionRepo.EXPECT().WithinTransaction(gomock.Any(), gomock.Any()).DoAndReturn(withinTransaction(mockTransactionRepo))
+	mockTransactionRepo.EXPECT().LockByID(gomock.Any(), rootID).Return(nil)
+	mockTransactionRepo.EXPECT().LockByID(gomock.Any(), newParentID).Return(nil)
+	mockTransactionRepo.EXPECT().GetByID(gomock.Any(), rootID).Return(&models.Transaction{Id: rootID, Type: "purchase"}, nil)
+	mockTransactionRepo.EXPECT().GetByID(gomock.Any(), newParentID).Return(&models.Transaction{Id: newParentID, Type: "purchase", ParentID: &childID}, nil)
+	mockTransactionRepo.EXPECT().GetAncestors(gomock.Any(), newParentID).Return([]models.Transaction{
+		{Id: childID, ParentID: &rootID},
+		{Id: rootID},
+	}, nil)
+
+	// Test the service method
+	updated, err := transactionService.UpdateTransaction(context.Background(), rootID, models.TransactionUpdate{ParentID: &newParentID, SetParentID: true})
+
+	// Assert the result
+	assert.Nil(t, updated)
+	assert.Equal(t, repositories.ErrCycleDetected, err)
+}
+
+// withinTransaction makes the repository mock run transactional work against itself.
+func withinTransaction(mockTransactionRepo *mock_repositories.MockTransactionRepositoryI) func(context.Context, func(repositories.TransactionRepositoryI) error) error {
+	return func(ctx context.Context, fn func(repositories.TransactionRepositoryI) error) error {
+		return fn(mockTransactionRepo)
+	}
+}
+
+func TestCreateTransactions_ParentInBatch(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Mocks
+	mockTransactionRepo := mock_repositories.NewMockTransactionRepositoryI(ctrl)
+
+	// Service
+	transactionService := services.MakeTransactionService(mockTransactionRepo)
+
+	parentID := uint(1)
+
+	// Test data: the child references a parent created earlier in the same batch
+	transactions := []models.Transaction{
+		{Id: 1, Amount: decimal.NewFromInt(100), Type: "purchase"},
+		{Id: 2, Amount: decimal.NewFromInt(50), Type: "purchase", ParentID: &parentID},
+	}
+
+	// Mock expectations: no parent lookup is needed, and each transaction is created behind a savepoint
+	mockTransactionRepo.EXPECT().WithinTransaction(gomock.Any(), gomock.Any()).DoAndReturn(withinTransaction(mockTransactionRepo)).Times(3)
+	mockTransactionRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil).Times(2)
+
+	// Test the service method
+	result, err := transactionService.CreateTransactions(context.Background(), transactions, services.BatchModeAtomic)
+
+	// Assert the result
+	assert.NoError(t, err)
+	assert.True(t, result.Committed)
+	assert.Equal(t, services.BatchItemCreated, result.Results[0].Status)
+	assert.Equal(t, services.BatchItemCreated, result.Results[1].Status)
+}
+
+func TestCreateTransactions_StoredParentDepths(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Mocks
+	mockTransactionRepo := mock_repositories.NewMockTransactionRepositoryI(ctrl)
+
+	// Service
+	transactionService := services.MakeTransactionService(mockTransactionRepo, services.WithMaxTreeDepth(3))
+
+	rootID, deepID := uint(5), uint(6)
+
+	// Test data: both parents are already stored, the second one at the maximum depth
+	transactions := []models.Transaction{
+		{Id: 1, Amount: decimal.NewFromInt(100), Type: "purchase", ParentID: &rootID},
+		{Id: 2, Amount: decimal.NewFromInt(50), Type: "purchase", ParentID: &deepID},
+	}
+
+	// Mock expectations: the depths of both parents are read with a single call
+	mockTransactionRepo.EXPECT().GetByIDs(gomock.Any(), []uint{rootID, deepID}).Return([]models.Transaction{
+		{Id: rootID, Amount: decimal.NewFromInt(10), Type: "purchase"},
+		{Id: deepID, Amount: decimal.NewFromInt(10), Type: "purchase"},
+	}, nil)
+	mockTransactionRepo.EXPECT().GetDepths(gomock.Any(), []uint{rootID, deepID}).Return(map[uint]int{rootID: 0, deepID: 3}, nil)
+	mockTransactionRepo.EXPECT().WithinTransaction(gomock.Any(), gomock.Any()).DoAndReturn(withinTransaction(mockTransactionRepo)).Times(2)
+	mockTransactionRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil)
+
+	// Test the service method
+	result, err := transactionService.CreateTransactions(context.Background(), transactions, services.BatchModeBestEffort)
+
+	// Assert the child of the deep parent is rejected
+	assert.NoError(t, err)
+	assert.True(t, result.Committed)
+	assert.Equal(t, services.BatchItemCreated, result.Results[0].Status)
+	assert.Equal(t, services.BatchItemFailed, result.Results[1].Status)
+	assert.Equal(t, repositories.ErrMaxDepthExceeded, result.Results[1].Err)
+}
+
+func TestCreateTransactions_AtomicRollsBack(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Mocks
+	mockTransactionRepo := mock_repositories.NewMockTransactionRepositoryI(ctrl)
+
+	// Service
+	transactionService := services.MakeTransactionService(mockTransactionRepo)
+
+	missingParentID := uint(99)
+
+	// Test data: the second transaction references a parent that does not exist
+	transactions := []models.Transaction{
+		{Id: 1, Amount: decimal.NewFromInt(100), Type: "purchase"},
+		{Id: 2, Amount: decimal.NewFromInt(50), Type: "purchase", ParentID: &missingParentID},
+		{Id: 3, Amount: decimal.NewFromInt(25), Type: "purchase"},
+	}
+
+	// Mock expectations
+	mockTransactionRepo.EXPECT().GetByIDs(gomock.Any(), []uint{missingParentID}).Return(nil, nil)
+	mockTransactionRepo.EXPECT().WithinTransaction(gomock.Any(), gomock.Any()).DoAndReturn(withinTransaction(mockTransactionRepo)).Times(2)
+	mockTransactionRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil)
+
+	// Test the service method
+	result, err := transactionService.CreateTransactions(context.Background(), transactions, services.BatchModeAtomic)
+
+	// Assert the result
+	assert.NoError(t, err)
+	assert.False(t, result.Committed)
+	assert.Equal(t, services.BatchItemRolledBack, result.Results[0].Status)
+	assert.Equal(t, services.BatchItemFailed, result.Results[1].Status)
+	assert.Equal(t, services.ErrParentTransactionNotFound, result.Results[1].Err)
+	assert.Equal(t, services.BatchItemSkipped, result.Results[2].Status)
+}
+
+func TestCreateTransactions_BestEffort(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Mocks
+	mockTransactionRepo := mock_repositories.NewMockTransactionRepositoryI(ctrl)
+
+	// Service
+	transactionService := services.MakeTransactionService(mockTransactionRepo)
+
+	// Test data: the second transaction was already stored with a different amount
+	transactions := []models.Transaction{
+		{Id: 1, Amount: decimal.NewFromInt(100), Type: "purchase"},
+		{Id: 2, Amount: decimal.NewFromInt(50), Type: "purchase"},
+		{Id: 3, Amount: decimal.NewFromInt(25), Type: "purchase"},
+	}
+
+	// Mock expectations
+	mockTransactionRepo.EXPECT().WithinTransaction(gomock.Any(), gomock.Any()).DoAndReturn(withinTransaction(mockTransactionRepo)).Times(4)
+	mockTransactionRepo.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, transaction *models.Transaction) error {
+		if transaction.Id == 2 {
+			return repositories.ErrTransactionAlreadyExist
+		}
+		return nil
+	}).Times(3)
+	mockTransactionRepo.EXPECT().GetByID(gomock.Any(), uint(2)).Return(&models.Transaction{Id: 2, Amount: decimal.NewFromInt(60), Type: "purchase", Currency: "USD"}, nil)
+
+	// Test the service method
+	result, err := transactionService.CreateTransactions(context.Background(), transactions, services.BatchModeBestEffort)
+
+	// Assert the result
+	assert.NoError(t, err)
+	assert.True(t, result.Committed)
+	assert.Equal(t, services.BatchItemCreated, result.Results[0].Status)
+	assert.Equal(t, services.BatchItemFailed, result.Results[1].Status)
+	assert.ErrorIs(t, result.Results[1].Err, services.ErrTransactionConflict)
+	assert.Equal(t, services.BatchItemCreated, result.Results[2].Status)
+}
+
+func TestImportTransactions_ParentsFirst(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Mocks
+	mockTransactionRepo := mock_repositories.NewMockTransactionRepositoryI(ctrl)
+
+	// Service
+	transactionService := services.MakeTransactionService(mockTransactionRepo)
+
+	parentID := uint(1)
+
+	// Test data: the child is listed before its parent
+	transactions := []models.Transaction{
+		{Id: 2, Amount: decimal.NewFromInt(50), Type: "purchase", ParentID: &parentID},
+		{Id: 1, Amount: decimal.NewFromInt(100), Type: "purchase"},
+	}
+
+	// Mock expectations: the parent is created first
+	var createdIDs []uint
+	mockTransactionRepo.EXPECT().WithinTransaction(gomock.Any(), gomock.Any()).DoAndReturn(withinTransaction(mockTransactionRepo)).Times(3)
+	mockTransactionRepo.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, transaction *models.Transaction) error {
+		createdIDs = append(createdIDs, transaction.Id)
+		return nil
+	}).Times(2)
+
+	// Test the service method
+	result, err := transactionService.ImportTransactions(context.Background(), transactions, false)
+
+	// Assert the result is reported in input order
+	assert.NoError(t, err)
+	assert.True(t, result.Committed)
+	assert.Equal(t, []uint{1, 2}, createdIDs)
+	assert.Equal(t, 0, result.Results[0].Index)
+	assert.Equal(t, uint(2), result.Results[0].ID)
+	assert.Equal(t, services.BatchItemCreated, result.Results[0].Status)
+}
+
+func TestImportTransactions_DryRun(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Mocks
+	mockTransactionRepo := mock_repositories.NewMockTransactionRepositoryI(ctrl)
+
+	// Service
+	transactionService := services.MakeTransactionService(mockTransactionRepo)
+
+	// Test data: the second transaction has an invalid currency
+	transactions := []models.Transaction{
+		{Id: 1, Amount: decimal.NewFromInt(100), Type: "purchase"},
+		{Id: 2, Amount: decimal.NewFromInt(50), Type: "purchase", Currency: "XXY"},
+		{Id: 3, Amount: decimal.NewFromInt(25), Type: "purchase"},
+	}
+
+	// Mock expectations: the outer transaction is rolled back whatever its outcome
+	var outerErr error
+	mockTransactionRepo.EXPECT().WithinTransaction(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, fn func(repositories.TransactionRepositoryI) error) error {
+		outerErr = fn(mockTransactionRepo)
+		return outerErr
+	})
+	mockTransactionRepo.EXPECT().WithinTransaction(gomock.Any(), gomock.Any()).DoAndReturn(withinTransaction(mockTransactionRepo)).Times(2)
+	mockTransactionRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil).Times(2)
+
+	// Test the service method
+	result, err := transactionService.ImportTransactions(context.Background(), transactions, true)
+
+	// Assert every transaction was validated and nothing was committed
+	assert.NoError(t, err)
+	assert.Error(t, outerErr)
+	assert.True(t, result.DryRun)
+	assert.False(t, result.Committed)
+	assert.Equal(t, services.BatchItemCreated, result.Results[0].Status)
+	assert.Equal(t, services.ErrInvalidCurrency, result.Results[1].Err)
+	assert.Equal(t, services.BatchItemCreated, result.Results[2].Status)
+}
+
+func TestImportTransactions_TooLarge(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Mocks
+	mockTransactionRepo := mock_repositories.NewMockTransactionRepositoryI(ctrl)
+
+	// Service
+	transactionService := services.MakeTransactionService(mockTransactionRepo)
+
+	// Test data: one transaction more than a batch may hold
+	transactions := make([]models.Transaction, services.MaxBatchSize+1)
+	for i := range transactions {
+		transactions[i] = models.Transaction{Id: uint(i + 1), Amount: decimal.NewFromInt(1), Type: "purchase"}
+	}
+
+	// Test the service method
+	result, err := transactionService.ImportTransactions(context.Background(), transactions, false)
+
+	// Assert the import is rejected before anything is written
+	assert.Nil(t, result)
+	assert.Equal(t, services.ErrBatchTooLarge, err)
+}
+
+func TestTransactionService_MemoryRepository(t *testing.T) {
+	ctx := context.Background()
+
+	// Service backed by the in-memory repository instead of mocks
+	transactionService := services.MakeTransactionService(repositories.MakeMemoryTransactionRepository(10))
+
+	parentID, childID := uint(1), uint(2)
+	result, err := transactionService.CreateTransactions(ctx, []models.Transaction{
+		{Id: 1, Amount: decimal.NewFromInt(100), Type: "car"},
+		{Id: 2, Amount: decimal.RequireFromString("20.5"), Type: "shopping", ParentID: &parentID},
+		{Id: 3, Amount: decimal.RequireFromString("4.5"), Type: "shopping", ParentID: &childID},
+	}, services.BatchModeAtomic)
+	assert.NoError(t, err)
+	assert.True(t, result.Committed)
+
+	// Assert a replay of a stored transaction is detected
+	_, created, err := transactionService.CreateTransaction(ctx, models.Transaction{Id: 2, Amount: decimal.RequireFromString("20.5"), Type: "shopping", ParentID: &parentID})
+	assert.NoError(t, err)
+	assert.False(t, created)
+
+	// Assert sums and ancestors are computed from the stored tree
+	sum, err := transactionService.GetTransitiveSum(ctx, 1, "")
+	assert.NoError(t, err)
+	assert.True(t, decimal.NewFromInt(25).Equal(*sum.Sum))
+
+	chain, err := transactionService.GetAncestors(ctx, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(1), chain.RootID)
+	assert.Len(t, chain.Ancestors, 2)
+
+	page, err := transactionService.GetTransactionIDsByType(ctx, repositories.TypeQuery{Type: "shopping"})
+	assert.NoError(t, err)
+	assert.Equal(t, []uint{2, 3}, page.TransactionIDs)
+
+	// Assert a cascading delete removes the whole subtree
+	assert.NoError(t, transactionService.DeleteTransaction(ctx, 2, repositories.DeleteModeCascade))
+	_, err = transactionService.GetTransaction(ctx, 3, services.GetTransactionOptions{})
+	assert.Equal(t, services.ErrTransactionNotFound, err)
+}
+
+func TestCachedTransactionService_InvalidatesAncestorSums(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	ctx := context.Background()
+
+	// Mocks
+	mockTransactionService := mock_services.NewMockTransactionServiceI(ctrl)
+
+	// Service
+	transactionService := services.MakeCachedTransactionService(mockTransactionService, cache.NewMemoryStore(cache.DefaultMaxEntries), time.Minute)
+
+	// Test data
+	sum := func(amount int64) *services.TransitiveSum {
+		total := decimal.NewFromInt(amount)
+		return &services.TransitiveSum{Sum: &total, Currency: "USD", Subtotals: map[string]decimal.Decimal{"USD": total}}
+	}
+	parentID := uint(2)
+	child := models.Transaction{Id: 3, Amount: decimal.NewFromInt(2), Type: "car", ParentID: &parentID, Currency: "USD"}
+
+	// Mock expectations
+	gomock.InOrder(
+		mockTransactionService.EXPECT().GetTransitiveSum(gomock.Any(), uint(1), "").Return(sum(10), nil),
+		mockTransactionService.EXPECT().GetTransitiveSum(gomock.Any(), uint(1), "").Return(sum(12), nil),
+	)
+	mockTransactionService.EXPECT().GetTransitiveSum(gomock.Any(), uint(5), "").Return(sum(7), nil).Times(1)
+	mockTransactionService.EXPECT().CreateTransaction(gomock.Any(), child).Return(&child, true, nil)
+	mockTransactionService.EXPECT().GetAncestors(gomock.Any(), uint(3)).Return(&services.AncestorChain{
+		TransactionID: 3,
+		RootID:        1,
+		Ancestors:     []models.Transaction{{Id: 2}, {Id: 1}},
+	}, nil)
+
+	// Assert repeated reads are served from the cache
+	for i := 0; i < 2; i++ {
+		result, err := transactionService.GetTransitiveSum(ctx, 1, "")
+		assert.NoError(t, err)
+		assert.True(t, decimal.NewFromInt(10).Equal(*result.Sum))
+		_, err = transactionService.GetTransitiveSum(ctx, 5, "")
+		assert.NoError(t, err)
+	}
+
+	_, created, err := transactionService.CreateTransaction(ctx, child)
+	assert.NoError(t, err)
+	assert.True(t, created)
+
+	// Assert the sum of an ancestor is recomputed while other sums stay cached
+	result, err := transactionService.GetTransitiveSum(ctx, 1, "")
+	assert.NoError(t, err)
+	assert.True(t, decimal.NewFromInt(12).Equal(*result.Sum))
+	result, err = transactionService.GetTransitiveSum(ctx, 5, "")
+	assert.NoError(t, err)
+	assert.True(t, decimal.NewFromInt(7).Equal(*result.Sum))
+}
+
+func TestCachedTransactionService_InvalidatesTypeLookups(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	ctx := context.Background()
+
+	// Mocks
+	mockTransactionService := mock_services.NewMockTransactionServiceI(ctrl)
+
+	// Service
+	transactionService := services.MakeCachedTransactionService(mockTransactionService, cache.NewMemoryStore(cache.DefaultMaxEntries), time.Minute)
+
+	// Test data
+	cars := repositories.TypeQuery{Type: "car"}
+	shopping := repositories.TypeQuery{Type: "shopping"}
+	transaction := models.Transaction{Id: 4, Amount: decimal.NewFromInt(1), Type: "car", Currency: "USD"}
+
+	// Mock expectations
+	gomock.InOrder(
+		mockTransactionService.EXPECT().GetTransactionIDsByType(gomock.Any(), cars).Return(&services.TransactionIDPage{TransactionIDs: []uint{1}}, nil),
+		mockTransactionService.EXPECT().GetTransactionIDsByType(gomock.Any(), cars).Return(&services.TransactionIDPage{TransactionIDs: []uint{1, 4}}, nil),
+	)
+	mockTransactionService.EXPECT().GetTransactionIDsByType(gomock.Any(), shopping).Return(&services.TransactionIDPage{TransactionIDs: []uint{2}}, nil).Times(1)
+	mockTransactionService.EXPECT().CreateTransaction(gomock.Any(), transaction).Return(&transaction, true, nil)
+	mockTransactionService.EXPECT().GetAncestors(gomock.Any(), uint(4)).Return(&services.AncestorChain{TransactionID: 4, RootID: 4}, nil)
+	mockTransactionService.EXPECT().DeleteTransaction(gomock.Any(), uint(2), repositories.DeleteModeReject).Return(nil)
+	mockTransactionService.EXPECT().GetTransactionIDsByType(gomock.Any(), shopping).Return(&services.TransactionIDPage{TransactionIDs: []uint{}}, nil)
+
+	for i := 0; i < 2; i++ {
+		_, err := transactionService.GetTransactionIDsByType(ctx, cars)
+		assert.NoError(t, err)
+		_, err = transactionService.GetTransactionIDsByType(ctx, shopping)
+		assert.NoError(t, err)
+	}
+
+	// Assert creating a transaction only invalidates the lookups of its type
+	_, _, err := transactionService.CreateTransaction(ctx, transaction)
+	assert.NoError(t, err)
+	page, err := transactionService.GetTransactionIDsByType(ctx, cars)
+	assert.NoError(t, err)
+	assert.Equal(t, []uint{1, 4}, page.TransactionIDs)
+	page, err = transactionService.GetTransactionIDsByType(ctx, shopping)
+	assert.NoError(t, err)
+	assert.Equal(t, []uint{2}, page.TransactionIDs)
+
+	// Assert a delete invalidates every lookup
+	assert.NoError(t, transactionService.DeleteTransaction(ctx, 2, repositories.DeleteModeReject))
+	page, err = transactionService.GetTransactionIDsByType(ctx, shopping)
+	assert.NoError(t, err)
+	assert.Empty(t, page.TransactionIDs)
+}
+
+func TestCachedTransactionService_MemoryRepository(t *testing.T) {
+	ctx := context.Background()
+
+	// Cached service backed by the in-memory repository, checking cached results follow every write
+	transactionService := services.MakeCachedTransactionService(
+		services.MakeTransactionService(repositories.MakeMemoryTransactionRepository(10)),
+		cache.NewMemoryStore(cache.DefaultMaxEntries),
+		time.Minute,
+	)
+	assertSum := func(transactionID uint, expected string) {
+		t.Helper()
+		sum, err := transactionService.GetTransitiveSum(ctx, transactionID, "")
+		assert.NoError(t, err)
+		assert.Equal(t, expected, sum.Sum.String())
+	}
+
+	rootID, childID := uint(1), uint(2)
+	for _, transaction := range []models.Transaction{
+		{Id: 1, Amount: decimal.NewFromInt(100), Type: "car"},
+		{Id: 2, Amount: decimal.NewFromInt(20), Type: "car", ParentID: &rootID},
+	} {
+		_, _, err := transactionService.CreateTransaction(ctx, transaction)
+		assert.NoError(t, err)
+	}
+	assertSum(1, "20")
+	assertSum(2, "0")
+
+	// Assert a grandchild reaches the sums of every ancestor
+	_, _, err := transactionService.CreateTransaction(ctx, models.Transaction{Id: 3, Amount: decimal.NewFromInt(5), Type: "car", ParentID: &childID})
+	assert.NoError(t, err)
+	assertSum(1, "25")
+	assertSum(2, "5")
+
+	// Assert updates and deletes are reflected
+	newAmount := decimal.NewFromInt(8)
+	_, err = transactionService.UpdateTransaction(ctx, 3, models.TransactionUpdate{Amount: &newAmount})
+	assert.NoError(t, err)
+	assertSum(1, "28")
+	assert.NoError(t, transactionService.DeleteTransaction(ctx, 2, repositories.DeleteModeCascade))
+	assertSum(1, "0")
+}
+
+func TestTransactionTypeService_AddTransactionType(t *testing.T) {
+	ctx := context.Background()
+
+	// Service backed by the in-memory type registry
+	typeService := services.MakeTransactionTypeService(repositories.MakeMemoryTransactionTypeRepository(), config.TypeRegistryStrict)
+
+	// Assert types are registered under their canonical form
+	transactionType, created, err := typeService.AddTransactionType(ctx, "  Online   Purchase ")
+	assert.NoError(t, err)
+	assert.True(t, created)
+	assert.Equal(t, "online purchase", transactionType.Name)
+
+	// Assert adding another spelling of a registered type is not an error
+	transactionType, created, err = typeService.AddTransactionType(ctx, "ONLINE PURCHASE")
+	assert.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, "online purchase", transactionType.Name)
+
+	transactionTypes, err := typeService.ListTransactionTypes(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []models.TransactionType{{Name: "online purchase"}}, transactionTypes)
+}
+
+func TestCreateTransaction_TypeRegistry(t *testing.T) {
+	ctx := context.Background()
+
+	for _, tc := range []struct {
+		mode         string
+		storedType   string
+		unknownError error
+	}{
+		{config.TypeRegistryOff, "Purchase", nil},
+		{config.TypeRegistryNormalize, "purchase", nil},
+		{config.TypeRegistryStrict, "purchase", services.ErrUnknownTransactionType},
+	} {
+		t.Run(tc.mode, func(t *testing.T) {
+			// Service backed by in-memory repositories, with "purchase" registered
+			typeService := services.MakeTransactionTypeService(repositories.MakeMemoryTransactionTypeRepository(), tc.mode)
+			_, _, err := typeService.AddTransactionType(ctx, "purchase")
+			assert.NoError(t, err)
+			transactionService := services.MakeTransactionService(repositories.MakeMemoryTransactionRepository(10), services.WithTransactionTypeService(typeService))
+
+			// Assert the type is stored normalized unless the registry is off
+			created, _, err := transactionService.CreateTransaction(ctx, models.Transaction{Id: 1, Amount: decimal.NewFromInt(10), Type: "Purchase"})
+			assert.NoError(t, err)
+			assert.Equal(t, tc.storedType, created.Type)
+
+			// Assert lookups are normalized the same way
+			page, err := transactionService.GetTransactionIDsByType(ctx, repositories.TypeQuery{Type: " PURCHASE"})
+			assert.NoError(t, err)
+			assert.Equal(t, tc.mode != config.TypeRegistryOff, len(page.TransactionIDs) == 1)
+
+			// Assert unregistered types are only rejected in strict mode, by creates, batches and updates
+			_, _, err = transactionService.CreateTransaction(ctx, models.Transaction{Id: 2, Amount: decimal.NewFromInt(10), Type: "purchace"})
+			assert.Equal(t, tc.unknownError == nil, err == nil)
+			if tc.unknownError != nil {
+				assert.ErrorIs(t, err, tc.unknownError)
+
+				result, err := transactionService.CreateTransactions(ctx, []models.Transaction{{Id: 3, Amount: decimal.NewFromInt(1), Type: "refund"}}, services.BatchModeBestEffort)
+				assert.NoError(t, err)
+				assert.ErrorIs(t, result.Results[0].Err, tc.unknownError)
+
+				unknownType := "refund"
+				_, err = transactionService.UpdateTransaction(ctx, 1, models.TransactionUpdate{Type: &unknownType})
+				assert.ErrorIs(t, err, tc.unknownError)
+			}
+		})
+	}
+}
+
+func TestCreateTransaction_Rules(t *testing.T) {
+	ctx := context.Background()
+
+	// Service backed by the in-memory repository, where a purchase has at most one refund
+	maxChildren := 1
+	ruleSet := &rules.RuleSet{Types: map[string]rules.TypeRules{
+		"purchase": {RequireRoot: true, MaxChildren: &maxChildren},
+		"refund":   {ParentTypes: []string{"purchase"}, RequireParent: true, AmountSign: rules.AmountSignNegative},
+	}}
+	transactionService := services.MakeTransactionService(repositories.MakeMemoryTransactionRepository(10), services.WithRules(ruleSet))
+
+	purchaseID := uint(1)
+	refund := models.Transaction{Id: 2, Amount: decimal.NewFromInt(-10), Type: "refund", ParentID: &purchaseID}
+	_, _, err := transactionService.CreateTransaction(ctx, models.Transaction{Id: 1, Amount: decimal.NewFromInt(100), Type: "purchase"})
+	assert.NoError(t, err)
+	_, created, err := transactionService.CreateTransaction(ctx, refund)
+	assert.NoError(t, err)
+	assert.True(t, created)
+
+	// Assert a replay is not counted against the children of its parent
+	_, created, err = transactionService.CreateTransaction(ctx, refund)
+	assert.NoError(t, err)
+	assert.False(t, created)
+
+	// Assert a second refund breaks the rules of its parent, and a root refund those of its own type
+	_, _, err = transactionService.CreateTransaction(ctx, models.Transaction{Id: 3, Amount: decimal.NewFromInt(-5), Type: "refund", ParentID: &purchaseID})
+	assert.ErrorIs(t, err, rules.ErrRuleViolation)
+
+	result, err := transactionService.CreateTransactions(ctx, []models.Transaction{
+		{Id: 4, Amount: decimal.NewFromInt(50), Type: "purchase"},
+		{Id: 5, Amount: decimal.NewFromInt(-5), Type: "refund"},
+	}, services.BatchModeBestEffort)
+	assert.NoError(t, err)
+	assert.NoError(t, result.Results[0].Err)
+	assert.ErrorIs(t, result.Results[1].Err, rules.ErrRuleViolation)
+}
+
+// slowChildrenRepository widens the window between counting the children of a transaction and writing.
+type slowChildrenRepository struct {
+	repositories.TransactionRepositoryI
+}
+
+func (r slowChildrenRepository) GetChildIDs(ctx context.Context, transactionID uint) ([]uint, error) {
+	childIDs, err := r.TransactionRepositoryI.GetChildIDs(ctx, transactionID)
+	time.Sleep(time.Millisecond)
+	return childIDs, err
+}
+
+func (r slowChildrenRepository) WithinTransaction(ctx context.Context, fn func(transactionRepo repositories.TransactionRepositoryI) error) error {
+	return r.TransactionRepositoryI.WithinTransaction(ctx, func(transactionRepo repositories.TransactionRepositoryI) error {
+		return fn(slowChildrenRepository{transactionRepo})
+	})
+}
+
+func TestCreateTransaction_RulesConcurrentChildren(t *testing.T) {
+	ctx := context.Background()
+
+	// Service backed by a slow in-memory repository, where a purchase has at most two children
+	maxChildren := 2
+	ruleSet := &rules.RuleSet{Types: map[string]rules.TypeRules{"purchase": {MaxChildren: &maxChildren}}}
+	transactionRepo := slowChildrenRepository{repositories.MakeMemoryTransactionRepository(10)}
+	transactionService := services.MakeTransactionService(transactionRepo, services.WithRules(ruleSet))
+	_, _, err := transactionService.CreateTransaction(ctx, models.Transaction{Id: 1, Amount: decimal.NewFromInt(100), Type: "purchase"})
+	assert.NoError(t, err)
+
+	// Create many children of the purchase at once
+	parentID := uint(1)
+	errs := make(chan error, 20)
+	for i := 0; i < cap(errs); i++ {
+		go func(id uint) {
+			_, _, err := transactionService.CreateTransaction(ctx, models.Transaction{Id: id, Amount: decimal.NewFromInt(1), Type: "fee", ParentID: &parentID})
+			errs <- err
+		}(uint(i + 2))
+	}
+
+	// Assert only as many children as the limit were created
+	created := 0
+	for i := 0; i < cap(errs); i++ {
+		if err := <-errs; err == nil {
+			created++
+		} else {
+			assert.ErrorIs(t, err, rules.ErrRuleViolation)
+		}
+	}
+	assert.Equal(t, maxChildren, created)
+}
+
+// slowAncestorsRepository widens the window between checking a move for cycles and writing it.
+type slowAncestorsRepository struct {
+	repositories.TransactionRepositoryI
+}
+
+func (r slowAncestorsRepository) GetAncestors(ctx context.Context, transactionID uint) ([]models.Transaction, error) {
+	ancestors, err := r.TransactionRepositoryI.GetAncestors(ctx, transactionID)
+	time.Sleep(time.Millisecond)
+	return ancestors, err
+}
+
+func (r slowAncestorsRepository) WithinTransaction(ctx context.Context, fn func(transactionRepo repositories.TransactionRepositoryI) error) error {
+	return r.TransactionRepositoryI.WithinTransaction(ctx, func(transactionRepo repositories.TransactionRepositoryI) error {
+		return fn(slowAncestorsRepository{transactionRepo})
+	})
+}
+
+func TestUpdateTransaction_ConcurrentMoves(t *testing.T) {
+	ctx := context.Background()
+
+	// Service backed by a slow in-memory repository holding two roots
+	transactionRepo := slowAncestorsRepository{repositories.MakeMemoryTransactionRepository(10)}
+	transactionService := services.MakeTransactionService(transactionRepo)
+	for _, id := range []uint{1, 2} {
+		_, _, err := transactionService.CreateTransaction(ctx, models.Transaction{Id: id, Amount: decimal.NewFromInt(100), Type: "purchase"})
+		assert.NoError(t, err)
+	}
+
+	// Move each root under the other at once
+	errs := make(chan error, 2)
+	for _, move := range [][2]uint{{1, 2}, {2, 1}} {
+		go func(transactionID, parentID uint) {
+			_, err := transactionService.UpdateTransaction(ctx, transactionID, models.TransactionUpdate{ParentID: &parentID, SetParentID: true})
+			errs <- err
+		}(move[0], move[1])
+	}
+
+	// Assert only one move went through, the other seeing the cycle it would now create
+	moved := 0
+	for i := 0; i < cap(errs); i++ {
+		if err := <-errs; err == nil {
+			moved++
+		} else {
+			assert.Equal(t, repositories.ErrCycleDetected, err)
+		}
+	}
+	assert.Equal(t, 1, moved)
+}
+
+func TestUpdateTransaction_Rules(t *testing.T) {
+	ctx := context.Background()
+
+	// Service backed by the in-memory repository, where a purchase has at most one child
+	maxChildren := 1
+	ruleSet := &rules.RuleSet{Types: map[string]rules.TypeRules{
+		"purchase": {RequireRoot: true, MaxChildren: &maxChildren},
+		"refund":   {ParentTypes: []string{"purchase"}, RequireParent: true, AmountSign: rules.AmountSignNegative},
+		"fee":      {RequireParent: true},
+	}}
+	transactionService := services.MakeTransactionService(repositories.MakeMemoryTransactionRepository(10), services.WithRules(ruleSet))
+
+	id := func(id uint) *uint { return &id }
+	for _, transaction := range []models.Transaction{
+		{Id: 1, Amount: decimal.NewFromInt(100), Type: "purchase"},
+		{Id: 2, Amount: decimal.NewFromInt(100), Type: "purchase"},
+		{Id: 3, Amount: decimal.NewFromInt(100), Type: "purchase"},
+		{Id: 4, Amount: decimal.NewFromInt(-10), Type: "refund", ParentID: id(1)},
+		{Id: 5, Amount: decimal.NewFromInt(2), Type: "fee", ParentID: id(2)},
+	} {
+		_, _, err := transactionService.CreateTransaction(ctx, transaction)
+		assert.NoError(t, err)
+	}
+
+	positive, negative := decimal.NewFromInt(5), decimal.NewFromInt(-20)
+	purchase := "purchase"
+	for _, tc := range []struct {
+		name          string
+		transactionID uint
+		update        models.TransactionUpdate
+		violation     string
+	}{
+		{"detaching a fee from its parent", 5, models.TransactionUpdate{SetParentID: true}, "require_parent"},
+		{"making a refund positive", 4, models.TransactionUpdate{Amount: &positive}, "amount_sign"},
+		{"moving a refund under a full parent", 4, models.TransactionUpdate{ParentID: id(2), SetParentID: true}, "max_children"},
+		{"giving a child a root type", 4, models.TransactionUpdate{Type: &purchase}, "require_root"},
+		{"moving a purchase under another", 3, models.TransactionUpdate{ParentID: id(1), SetParentID: true}, "require_root"},
+		{"changing the amount of a refund in place", 4, models.TransactionUpdate{Amount: &negative}, ""},
+		{"moving a refund under a purchase with room", 4, models.TransactionUpdate{ParentID: id(3), SetParentID: true}, ""},
+	} {
+		_, err := transactionService.UpdateTransaction(ctx, tc.transactionID, tc.update)
+
+		// Assert updates are held to the rules of creation, the transaction not counting against its own parent
+		if tc.violation == "" {
+			assert.NoError(t, err, tc.name)
+			continue
+		}
+		assert.ErrorIs(t, err, rules.ErrRuleViolation, tc.name)
+		var appErr *apperror.Error
+		if assert.True(t, errors.As(err, &appErr), tc.name) {
+			violations := appErr.Details["violations"].([]rules.Violation)
+			assert.Equal(t, tc.violation, violations[0].Rule, tc.name)
+		}
+	}
+
+	// Assert rejected updates left the transactions unchanged
+	details, err := transactionService.GetTransaction(ctx, 5, services.GetTransactionOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, uint(2), *details.ParentID)
+}
+
+func TestUpdateTransaction_TypeChangeRules(t *testing.T) {
+	ctx := context.Background()
+
+	// Service backed by the in-memory repository, where an order has at most one child
+	maxChildren := 1
+	ruleSet := &rules.RuleSet{Types: map[string]rules.TypeRules{
+		"order":  {MaxChildren: &maxChildren},
+		"bundle": {},
+		"item":   {ParentTypes: []string{"order", "bundle"}},
+		"fee":    {ParentTypes: []string{"bundle"}},
+	}}
+	transactionService := services.MakeTransactionService(repositories.MakeMemoryTransactionRepository(10), services.WithRules(ruleSet))
+
+	id := func(id uint) *uint { return &id }
+	for _, transaction := range []models.Transaction{
+		{Id: 1, Amount: decimal.NewFromInt(100), Type: "bundle"},
+		{Id: 2, Amount: decimal.NewFromInt(1), Type: "fee", ParentID: id(1)},
+		{Id: 10, Amount: decimal.NewFromInt(100), Type: "bundle"},
+		{Id: 11, Amount: decimal.NewFromInt(100), Type: "item", ParentID: id(10)},
+		{Id: 12, Amount: decimal.NewFromInt(100), Type: "item", ParentID: id(10)},
+		{Id: 20, Amount: decimal.NewFromInt(100), Type: "bundle"},
+		{Id: 21, Amount: decimal.NewFromInt(100), Type: "item", ParentID: id(20)},
+	} {
+		_, _, err := transactionService.CreateTransaction(ctx, transaction)
+		assert.NoError(t, err)
+	}
+
+	order := "order"
+	for _, tc := range []struct {
+		name          string
+		transactionID uint
+		violation     string
+		childID       uint
+	}{
+		{"turning the parent of a fee into an order", 1, "parent_types", 2},
+		{"turning the parent of two items into an order", 10, "max_children", 12},
+	} {
+		_, err := transactionService.UpdateTransaction(ctx, tc.transactionID, models.TransactionUpdate{Type: &order})
+
+		// Assert the children are held to the rules under the new type of their parent
+		assert.ErrorIs(t, err, rules.ErrRuleViolation, tc.name)
+		var appErr *apperror.Error
+		if assert.True(t, errors.As(err, &appErr), tc.name) {
+			violations := appErr.Details["violations"].([]rules.Violation)
+			assert.Equal(t, tc.violation, violations[0].Rule, tc.name)
+			assert.Equal(t, tc.childID, appErr.Details["transaction_id"], tc.name)
+		}
+
+		// Assert the rejected update left the type unchanged
+		details, err := transactionService.GetTransaction(ctx, tc.transactionID, services.GetTransactionOptions{})
+		assert.NoError(t, err, tc.name)
+		assert.Equal(t, "bundle", details.Type, tc.name)
+	}
+
+	// Assert a type change its children keep to is applied
+	updated, err := transactionService.UpdateTransaction(ctx, 20, models.TransactionUpdate{Type: &order})
+	assert.NoError(t, err)
+	assert.Equal(t, "order", updated.Type)
+}
+
+func TestDeleteTransaction_ReparentRules(t *testing.T) {
+	ctx := context.Background()
+
+	// Service backed by the in-memory repository, where an order has at most two children
+	maxChildren := 2
+	ruleSet := &rules.RuleSet{Types: map[string]rules.TypeRules{
+		"order":  {RequireRoot: true, MaxChildren: &maxChildren},
+		"bundle": {ParentTypes: []string{"order"}, RequireParent: true},
+		"item":   {ParentTypes: []string{"order", "bundle"}},
+		"fee":    {ParentTypes: []string{"bundle"}},
+	}}
+	transactionService := services.MakeTransactionService(repositories.MakeMemoryTransactionRepository(10), services.WithRules(ruleSet))
+
+	id := func(id uint) *uint { return &id }
+	for _, transaction := range []models.Transaction{
+		{Id: 1, Amount: decimal.NewFromInt(100), Type: "order"},
+		{Id: 2, Amount: decimal.NewFromInt(100), Type: "bundle", ParentID: id(1)},
+		{Id: 3, Amount: decimal.NewFromInt(1), Type: "fee", ParentID: id(2)},
+		{Id: 10, Amount: decimal.NewFromInt(100), Type: "order"},
+		{Id: 11, Amount: decimal.NewFromInt(100), Type: "bundle", ParentID: id(10)},
+		{Id: 12, Amount: decimal.NewFromInt(100), Type: "item", ParentID: id(10)},
+		{Id: 13, Amount: decimal.NewFromInt(100), Type: "item", ParentID: id(11)},
+		{Id: 14, Amount: decimal.NewFromInt(100), Type: "item", ParentID: id(11)},
+		{Id: 20, Amount: decimal.NewFromInt(100), Type: "order"},
+		{Id: 21, Amount: decimal.NewFromInt(100), Type: "bundle", ParentID: id(20)},
+		{Id: 22, Amount: decimal.NewFromInt(100), Type: "item", ParentID: id(21)},
+	} {
+		_, _, err := transactionService.CreateTransaction(ctx, transaction)
+		assert.NoError(t, err)
+	}
+
+	for _, tc := range []struct {
+		name          string
+		transactionID uint
+		violation     string
+		childID       uint
+	}{
+		{"moving a fee under an order", 2, "parent_types", 3},
+		{"making a bundle a root", 1, "require_parent", 2},
+		{"moving two items under an order with one child", 11, "max_children", 14},
+	} {
+		err := transactionService.DeleteTransaction(ctx, tc.transactionID, repositories.DeleteModeReparent)
+
+		// Assert the moved children are held to the rules under their new parent
+		assert.ErrorIs(t, err, rules.ErrRuleViolation, tc.name)
+		var appErr *apperror.Error
+		if assert.True(t, errors.As(err, &appErr), tc.name) {
+			violations := appErr.Details["violations"].([]rules.Violation)
+			assert.Equal(t, tc.violation, violations[0].Rule, tc.name)
+			assert.Equal(t, tc.childID, appErr.Details["transaction_id"], tc.name)
+		}
+
+		// Assert the rejected delete left the transaction in place
+		_, err = transactionService.GetTransaction(ctx, tc.transactionID, services.GetTransactionOptions{})
+		assert.NoError(t, err, tc.name)
+	}
+
+	// Assert children that keep to the rules are moved
+	assert.NoError(t, transactionService.DeleteTransaction(ctx, 21, repositories.DeleteModeReparent))
+	details, err := transactionService.GetTransaction(ctx, 22, services.GetTransactionOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, uint(20), *details.ParentID)
+}
+
+func TestCreateTransaction_AuditFields(t *testing.T) {
+	ctx := audit.WithActor(context.Background(), "alice")
+
+	// Service backed by the in-memory repository instead of mocks
+	transactionService := services.MakeTransactionService(repositories.MakeMemoryTransactionRepository(10))
+
+	occurredAt := time.Date(2024, 3, 1, 13, 30, 0, 123456789, time.FixedZone("CET", 3600))
+	stored, created, err := transactionService.CreateTransaction(ctx, models.Transaction{Id: 1, Amount: decimal.NewFromInt(100), Type: "car", OccurredAt: &occurredAt})
+
+	// Assert the business time is stored in UTC at microsecond precision and the creation is recorded
+	assert.NoError(t, err)
+	assert.True(t, created)
+	assert.Equal(t, time.Date(2024, 3, 1, 12, 30, 0, 123456000, time.UTC), *stored.OccurredAt)
+	assert.Equal(t, "alice", *stored.CreatedBy)
+	assert.NotNil(t, stored.CreatedAt)
+
+	// Assert a replay of the same instant is identical, whatever its zone and precision
+	sameInstant := occurredAt.UTC()
+	_, created, err = transactionService.CreateTransaction(context.Background(), models.Transaction{Id: 1, Amount: decimal.NewFromInt(100), Type: "car", OccurredAt: &sameInstant})
+	assert.NoError(t, err)
+	assert.False(t, created)
+
+	// Assert a replay with another business time conflicts
+	later := occurredAt.Add(time.Hour)
+	_, _, err = transactionService.CreateTransaction(context.Background(), models.Transaction{Id: 1, Amount: decimal.NewFromInt(100), Type: "car", OccurredAt: &later})
+	var conflictErr *services.TransactionConflictError
+	assert.True(t, errors.As(err, &conflictErr))
+	assert.Equal(t, []string{"occurred_at"}, mapKeys(conflictErr.Diff))
 }
@@ -0,0 +1,143 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"transaction_system/app/lib/apperror"
+	"transaction_system/app/lib/config"
+	"transaction_system/app/models"
+	"transaction_system/app/repositories"
+)
+
+//go:generate mockgen -source=./transaction_type_service.go -destination=mock_services/mock_transaction_type_service.go -package=mock_services
+
+var ErrUnknownTransactionType = apperror.New("unknown_transaction_type", http.StatusUnprocessableEntity, "transaction type is not registered")
+
+var seedTransactionTypesOnce sync.Once
+
+type TransactionTypeServiceI interface {
+	ListTransactionTypes(ctx context.Context) ([]models.TransactionType, error)
+	AddTransactionType(ctx context.Context, name string) (*models.TransactionType, bool, error)
+	NormalizeTransactionType(name string) string
+	CheckTransactionType(ctx context.Context, name string) error
+}
+
+// typesFile is the on-disk format of a transaction types file, e.g.
+//
+//	{"types": ["purchase", "refund", "transfer"]}
+type typesFile struct {
+	Types []string `json:"types"`
+}
+
+type transactionTypeService struct {
+	transactionTypeRepo repositories.TransactionTypeRepositoryI
+	// mode is one of the config.TypeRegistry constants.
+	mode string
+}
+
+// NewTransactionTypeService returns the type registry service, adding the types of the configured types
+// file to the registry the first time it is called.
+func NewTransactionTypeService() TransactionTypeServiceI {
+	service := MakeTransactionTypeService(repositories.NewTransactionTypeRepository(), config.Get().TypeRegistry)
+	seedTransactionTypesOnce.Do(func() {
+		typesFile := config.Get().TypesFile
+		if typesFile == "" {
+			return
+		}
+		if err := seedTransactionTypes(context.Background(), service, typesFile); err != nil {
+			log.Fatalf("Error loading types file: %v", err)
+		}
+	})
+	return service
+}
+
+func MakeTransactionTypeService(transactionTypeRepo repositories.TransactionTypeRepositoryI, mode string) TransactionTypeServiceI {
+	return &transactionTypeService{
+		transactionTypeRepo: transactionTypeRepo,
+		mode:                mode,
+	}
+}
+
+// seedTransactionTypes adds every type listed in the types file at path to the registry of service.
+func seedTransactionTypes(ctx context.Context, service TransactionTypeServiceI, path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var file typesFile
+	if err := json.Unmarshal(content, &file); err != nil {
+		return fmt.Errorf("error decoding types file %s: %w", path, err)
+	}
+	for _, name := range file.Types {
+		if CanonicalTransactionType(name) == "" {
+			return fmt.Errorf("types file %s has a blank type", path)
+		}
+		if _, _, err := service.AddTransactionType(ctx, name); err != nil {
+			return fmt.Errorf("adding type %q of types file %s: %w", name, path, err)
+		}
+	}
+	return nil
+}
+
+// CanonicalTransactionType returns the canonical form of a transaction type: lower case, with surrounding
+// whitespace removed and inner whitespace collapsed to single spaces.
+func CanonicalTransactionType(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+// ListTransactionTypes returns every registered transaction type ordered by name.
+func (t *transactionTypeService) ListTransactionTypes(ctx context.Context) ([]models.TransactionType, error) {
+	transactionTypes, err := t.transactionTypeRepo.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing transaction types: %w", err)
+	}
+	return transactionTypes, nil
+}
+
+// AddTransactionType registers a transaction type under its canonical form and reports whether it was
+// added. Adding a type that is already registered is not an error: the registered type is returned with
+// created set to false.
+func (t *transactionTypeService) AddTransactionType(ctx context.Context, name string) (*models.TransactionType, bool, error) {
+	transactionType := models.TransactionType{Name: CanonicalTransactionType(name)}
+	err := t.transactionTypeRepo.Create(ctx, &transactionType)
+	if errors.Is(err, repositories.ErrTransactionTypeAlreadyExist) {
+		return &transactionType, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("adding transaction type %q: %w", transactionType.Name, err)
+	}
+	return &transactionType, true, nil
+}
+
+// NormalizeTransactionType returns the form a transaction type is stored and looked up under, which is its
+// canonical form unless the registry is off.
+func (t *transactionTypeService) NormalizeTransactionType(name string) string {
+	if t.mode != config.TypeRegistryNormalize && t.mode != config.TypeRegistryStrict {
+		return name
+	}
+	return CanonicalTransactionType(name)
+}
+
+// CheckTransactionType returns ErrUnknownTransactionType in strict mode when a normalized transaction type
+// is not registered.
+func (t *transactionTypeService) CheckTransactionType(ctx context.Context, name string) error {
+	if t.mode != config.TypeRegistryStrict {
+		return nil
+	}
+	exists, err := t.transactionTypeRepo.Exists(ctx, name)
+	if err != nil {
+		return fmt.Errorf("checking transaction type %q: %w", name, err)
+	}
+	if !exists {
+		return ErrUnknownTransactionType.WithDetails(map[string]interface{}{"transaction_type": name})
+	}
+	return nil
+}
@@ -3,19 +3,25 @@ package cmd
 import (
 	"log"
 	"os"
+	"transaction_system/app/lib/config"
 	"transaction_system/app/lib/db"
 
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
 )
 
-func init() {
+// SetupDBConnection loads the configuration from development.env, then connects to the database it names
+// unless the in-memory repository is configured. Commands call it before reading the configuration.
+func SetupDBConnection() {
 	if err := godotenv.Load("development.env"); err != nil {
 		log.Fatal("Error loading .env file")
 	}
-}
 
-func SetupDBConnection() {
+	if config.Get().RepositoryMode == config.RepositoryModeMemory {
+		log.Println("Using the in-memory repository, skipping the database connection")
+		return
+	}
+
 	dbURL := os.Getenv("DATABASE_URL")
 
 	log.Println("Connecting to the database")
@@ -0,0 +1,47 @@
+// Command repairsums recomputes the materialized descendant sums from the transactions table. Run it when
+// enabling MATERIALIZED_SUMS on a database that was written to while it was disabled, or whenever the sums
+// are suspected to have drifted. With -closure it first rebuilds the transaction_closure table, which is
+// needed before switching REPOSITORY_MODE to closure on a database written to in adjacency mode.
+//
+// Both tables are rebuilt down to MAX_TREE_DEPTH levels, whereas the migrations that created them stop at
+// 1000, so run it after raising MAX_TREE_DEPTH on a database holding deeper trees.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+
+	"transaction_system/app/lib/config"
+	"transaction_system/app/lib/db"
+	"transaction_system/app/repositories"
+	"transaction_system/cmd"
+)
+
+func main() {
+	closure := flag.Bool("closure", false, "also rebuild the transaction_closure table")
+	flag.Parse()
+
+	cmd.SetupDBConnection()
+	defer db.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	log.Printf("Rebuilding down to %d levels, as set by MAX_TREE_DEPTH", config.Get().MaxTreeDepth)
+	if *closure {
+		rows, err := repositories.RebuildClosure(ctx, db.Get(), config.Get().MaxTreeDepth)
+		if err != nil {
+			log.Fatal("Error rebuilding transaction closure: ", err)
+		}
+		log.Printf("Rebuilt %d closure rows", rows)
+	}
+
+	rows, err := repositories.NewTransactionRepository().RebuildDescendantSums(ctx)
+	if err != nil {
+		log.Fatal("Error rebuilding descendant sums: ", err)
+	}
+	log.Printf("Rebuilt %d descendant sums", rows)
+}
@@ -0,0 +1,117 @@
+// Command replay sends a JSONL capture of HTTP requests to the transaction service, compares every response
+// with the recorded one and prints a summary, exiting with status 1 when any response differs.
+//
+//	replay [-target http://localhost:8080] [-rate 50] [-ignore transaction.id,...] [-v] capture.jsonl
+//
+// Without -target the requests are served in process by the application routes, connected to the database
+// configured in development.env. See package app/lib/replay for the capture format.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"time"
+
+	"transaction_system/app/lib/db"
+	"transaction_system/app/lib/replay"
+	"transaction_system/app/routes"
+	"transaction_system/cmd"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+func main() {
+	os.Exit(run())
+}
+
+// run replays the capture named on the command line and returns the exit status, once the deferred cleanup
+// has run.
+func run() int {
+	target := flag.String("target", "", "base URL of a running instance (default: serve requests in process)")
+	rate := flag.Float64("rate", 0, "maximum requests per second, 0 for no limit")
+	ignore := flag.String("ignore", "", "comma-separated response body fields to leave out of the comparison, e.g. transaction.created_at,transaction.updated_at")
+	timeout := flag.Duration("timeout", 30*time.Second, "timeout of each request sent to -target")
+	verbose := flag.Bool("v", false, "report every request, not only mismatches and failures")
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: replay [-target url] [-rate n] [-ignore fields] [-v] capture.jsonl")
+		return 2
+	}
+
+	file, err := os.Open(flag.Arg(0))
+	if err != nil {
+		log.Print(err)
+		return 1
+	}
+	entries, skipped, err := replay.Load(file)
+	file.Close()
+	if err != nil {
+		log.Print(err)
+		return 1
+	}
+	if skipped > 0 {
+		log.Printf("Skipping %d lines that are not requests", skipped)
+	}
+
+	replayer := &replay.Replayer{Rate: *rate}
+	if *ignore != "" {
+		replayer.Ignore = strings.Split(*ignore, ",")
+	}
+	if *target != "" {
+		replayer.Target = replay.URLTarget{BaseURL: *target, Client: &http.Client{Timeout: *timeout}}
+	} else {
+		// Only requests served in process need the configuration and the database
+		cmd.SetupDBConnection()
+		defer db.Close()
+
+		router := httprouter.New()
+		routes.InitRoutes(router)
+		replayer.Target = replay.HandlerTarget{Handler: router}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	summary := replayer.Run(ctx, entries, func(result replay.Result) {
+		if result.Outcome == replay.Matched && !*verbose {
+			return
+		}
+		prefix := fmt.Sprintf("line %d: %s %s", result.Entry.Line, result.Entry.Method, result.Entry.Path)
+		switch result.Outcome {
+		case replay.Failed:
+			fmt.Printf("%s: failed: %v\n", prefix, result.Err)
+		case replay.Mismatched:
+			fmt.Printf("%s: mismatched\n", prefix)
+			for _, diff := range result.Diffs {
+				fmt.Printf("    %s\n", diff)
+			}
+		default:
+			fmt.Printf("%s: %s (%d) in %s\n", prefix, result.Outcome, result.Status, result.Latency)
+		}
+	})
+
+	printSummary(summary, len(entries))
+	if summary.Outcomes[replay.Mismatched] > 0 || summary.Outcomes[replay.Failed] > 0 {
+		return 1
+	}
+	return 0
+}
+
+func printSummary(summary replay.Summary, total int) {
+	outcomes := make([]string, 0, len(summary.Outcomes))
+	for outcome, count := range summary.Outcomes {
+		outcomes = append(outcomes, fmt.Sprintf("%d %s", count, outcome))
+	}
+	sort.Strings(outcomes)
+
+	fmt.Printf("\nReplayed %d of %d requests in %s: %s\n", summary.Total, total, summary.Duration.Round(time.Millisecond), strings.Join(outcomes, ", "))
+	fmt.Printf("Latency: mean %s, max %s\n", summary.MeanLatency().Round(time.Microsecond), summary.MaxLatency.Round(time.Microsecond))
+}
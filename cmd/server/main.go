@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -35,9 +36,15 @@ func main() {
 
 	// Set up HTTP server
 	port := 8080
+	// Requests derive their context from baseCtx, so canceling it aborts in-flight queries
+	baseCtx, cancelBase := context.WithCancel(ctx)
+	defer cancelBase()
 	server := &http.Server{
 		Addr:    fmt.Sprintf(":%d", port),
 		Handler: router,
+		BaseContext: func(net.Listener) context.Context {
+			return baseCtx
+		},
 	}
 
 	// Start the server in a goroutine
@@ -57,8 +64,10 @@ func main() {
 	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
-	// Attempt to shut down the server gracefully
-	if err := server.Shutdown(ctx); err != nil {
+	// Attempt to shut down the server gracefully, canceling requests still running after the timeout
+	err := server.Shutdown(ctx)
+	cancelBase()
+	if err != nil {
 		log.Fatal("Server shutdown:", err)
 	}
 	log.Println("Server exiting")
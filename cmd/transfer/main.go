@@ -0,0 +1,189 @@
+// Command transfer imports transactions into, or exports them from, the transactions table as CSV or
+// newline-delimited JSON.
+//
+//	transfer import [-format csv|ndjson] [-dry-run] [file]
+//	transfer export [-format csv|ndjson] [-o file]
+//
+// Files default to standard input and output. Imported rows are inserted parents first, in a single
+// database transaction; with -dry-run every row is validated and the transaction is rolled back.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+
+	"transaction_system/app/lib/db"
+	"transaction_system/app/lib/transfer"
+	"transaction_system/app/models"
+	"transaction_system/app/services"
+	"transaction_system/cmd"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	var err error
+	switch os.Args[1] {
+	case "import":
+		err = runImport(ctx, os.Args[2:])
+	case "export":
+		err = runExport(ctx, os.Args[2:])
+	default:
+		usage()
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: transfer import [-format csv|ndjson] [-dry-run] [file]")
+	fmt.Fprintln(os.Stderr, "       transfer export [-format csv|ndjson] [-o file]")
+	os.Exit(2)
+}
+
+func runImport(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	formatName := flags.String("format", "", "input format, csv or ndjson (default: from the file extension, else ndjson)")
+	dryRun := flags.Bool("dry-run", false, "validate every row, then roll the import back")
+	flags.Parse(args)
+
+	input := io.Reader(os.Stdin)
+	path := flags.Arg(0)
+	if path != "" && path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		input = file
+	}
+	format, err := formatFor(*formatName, path)
+	if err != nil {
+		return err
+	}
+
+	records, err := transfer.Decode(format, input)
+	if err != nil {
+		return err
+	}
+	transactions := make([]models.Transaction, len(records))
+	for i, record := range records {
+		transactions[i] = record.Transaction
+	}
+
+	cmd.SetupDBConnection()
+	defer db.Close()
+
+	result, err := services.NewTransactionService().ImportTransactions(ctx, transactions, *dryRun)
+	if err != nil {
+		return err
+	}
+
+	// Report every row that did not go through, then a summary per status
+	counts := make(map[services.BatchItemStatus]int)
+	for _, itemResult := range result.Results {
+		counts[itemResult.Status]++
+		if itemResult.Err != nil {
+			log.Printf("line %d: transaction %d: %v", records[itemResult.Index].Line, itemResult.ID, itemResult.Err)
+		}
+	}
+	var summary []string
+	for _, status := range []services.BatchItemStatus{services.BatchItemCreated, services.BatchItemUnchanged, services.BatchItemFailed, services.BatchItemRolledBack, services.BatchItemSkipped} {
+		if counts[status] > 0 {
+			summary = append(summary, fmt.Sprintf("%d %s", counts[status], status))
+		}
+	}
+
+	switch {
+	case result.DryRun:
+		log.Printf("Dry run of %d transactions: %s", len(transactions), strings.Join(summary, ", "))
+		if counts[services.BatchItemFailed] > 0 {
+			return errors.New("dry run found invalid transactions")
+		}
+	case result.Committed:
+		log.Printf("Imported %d transactions: %s", len(transactions), strings.Join(summary, ", "))
+	default:
+		return fmt.Errorf("import rolled back: %s", strings.Join(summary, ", "))
+	}
+	return nil
+}
+
+func runExport(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	formatName := flags.String("format", "", "output format, csv or ndjson (default: from the file extension, else ndjson)")
+	path := flags.String("o", "", "output file (default: standard output)")
+	flags.Parse(args)
+
+	format, err := formatFor(*formatName, *path)
+	if err != nil {
+		return err
+	}
+
+	output := io.Writer(os.Stdout)
+	if *path != "" && *path != "-" {
+		file, err := os.Create(*path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		output = file
+	}
+	encoder, err := transfer.NewEncoder(format, output)
+	if err != nil {
+		return err
+	}
+
+	cmd.SetupDBConnection()
+	defer db.Close()
+
+	transactionService := services.NewTransactionService()
+	exported := 0
+	var afterID *uint
+	for {
+		transactions, err := transactionService.ListTransactions(ctx, afterID, services.MaxPageLimit)
+		if err != nil {
+			return err
+		}
+		if len(transactions) == 0 {
+			break
+		}
+		for _, transaction := range transactions {
+			if err := encoder.Encode(transaction); err != nil {
+				return err
+			}
+		}
+		exported += len(transactions)
+		afterID = &transactions[len(transactions)-1].Id
+	}
+	if err := encoder.Flush(); err != nil {
+		return err
+	}
+
+	log.Printf("Exported %d transactions", exported)
+	return nil
+}
+
+// formatFor returns the format named by name, falling back to the extension of path and then to NDJSON.
+func formatFor(name, path string) (transfer.Format, error) {
+	if name == "" {
+		name = strings.TrimPrefix(filepath.Ext(path), ".")
+		if _, err := transfer.ParseFormat(name); err != nil {
+			name = string(transfer.NDJSON)
+		}
+	}
+	return transfer.ParseFormat(name)
+}
@@ -0,0 +1 @@
+ALTER TABLE transactions ALTER COLUMN amount TYPE DOUBLE PRECISION USING amount::DOUBLE PRECISION;
@@ -0,0 +1 @@
+ALTER TABLE transactions ALTER COLUMN amount TYPE NUMERIC USING amount::NUMERIC;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_transaction_parent_id_currency;
+
+ALTER TABLE transactions DROP COLUMN IF EXISTS currency;
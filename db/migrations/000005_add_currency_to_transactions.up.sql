@@ -0,0 +1,3 @@
+ALTER TABLE transactions ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'USD';
+
+CREATE INDEX idx_transaction_parent_id_currency ON transactions (parent_id, currency);
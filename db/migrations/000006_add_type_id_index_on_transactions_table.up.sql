@@ -0,0 +1 @@
+CREATE INDEX idx_transaction_type_id ON transactions (type, id);
@@ -0,0 +1,52 @@
+CREATE TABLE IF NOT EXISTS transaction_descendant_sums(
+    transaction_id BIGINT NOT NULL,
+    currency CHAR(3) NOT NULL,
+    amount NUMERIC NOT NULL,
+    transaction_count BIGINT NOT NULL,
+    PRIMARY KEY (transaction_id, currency),
+    FOREIGN KEY (transaction_id) REFERENCES transactions(id) ON DELETE CASCADE
+);
+
+-- Refuse to backfill parent_id links that form a cycle, found by walking every transaction up its ancestors
+-- until one repeats
+DO $$
+BEGIN
+    IF EXISTS (
+        WITH RECURSIVE Walk AS (
+            SELECT id, parent_id, '/' || CAST(id AS TEXT) || '/' AS path, 0 AS is_cycle
+            FROM transactions
+
+            UNION ALL
+
+            SELECT t.id, t.parent_id, Walk.path || CAST(t.id AS TEXT) || '/',
+                CASE WHEN Walk.path LIKE '%/' || CAST(t.id AS TEXT) || '/%' THEN 1 ELSE 0 END
+            FROM Walk
+            JOIN transactions t ON t.id = Walk.parent_id
+            WHERE Walk.is_cycle = 0
+        )
+        SELECT 1 FROM Walk WHERE is_cycle = 1
+    ) THEN
+        RAISE EXCEPTION 'transaction hierarchy contains a cycle';
+    END IF;
+END
+$$;
+
+-- Backfill trees down to 1000 levels, the default MAX_TREE_DEPTH. Run cmd/repairsums after migrating
+-- a database whose trees are deeper, with MAX_TREE_DEPTH raised to cover them.
+INSERT INTO transaction_descendant_sums (transaction_id, currency, amount, transaction_count)
+WITH RECURSIVE Closure AS (
+    SELECT parent_id AS ancestor_id, id AS descendant_id, 1 AS depth
+    FROM transactions
+    WHERE parent_id IS NOT NULL
+
+    UNION ALL
+
+    SELECT t.parent_id, Closure.descendant_id, Closure.depth + 1
+    FROM Closure
+    JOIN transactions t ON t.id = Closure.ancestor_id
+    WHERE t.parent_id IS NOT NULL AND Closure.depth < 1000
+)
+SELECT Closure.ancestor_id, d.currency, SUM(d.amount), COUNT(*)
+FROM Closure
+JOIN transactions d ON d.id = Closure.descendant_id
+GROUP BY Closure.ancestor_id, d.currency;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_transaction_closure_descendant_id_depth;
+
+DROP TABLE IF EXISTS transaction_closure;
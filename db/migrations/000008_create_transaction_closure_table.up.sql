@@ -0,0 +1,52 @@
+CREATE TABLE IF NOT EXISTS transaction_closure(
+    ancestor_id BIGINT NOT NULL,
+    descendant_id BIGINT NOT NULL,
+    depth INT NOT NULL,
+    PRIMARY KEY (ancestor_id, descendant_id),
+    FOREIGN KEY (ancestor_id) REFERENCES transactions(id) ON DELETE CASCADE,
+    FOREIGN KEY (descendant_id) REFERENCES transactions(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_transaction_closure_descendant_id_depth ON transaction_closure (descendant_id, depth);
+
+-- Refuse to backfill parent_id links that form a cycle, found by walking every transaction up its ancestors
+-- until one repeats
+DO $$
+BEGIN
+    IF EXISTS (
+        WITH RECURSIVE Walk AS (
+            SELECT id, parent_id, '/' || CAST(id AS TEXT) || '/' AS path, 0 AS is_cycle
+            FROM transactions
+
+            UNION ALL
+
+            SELECT t.id, t.parent_id, Walk.path || CAST(t.id AS TEXT) || '/',
+                CASE WHEN Walk.path LIKE '%/' || CAST(t.id AS TEXT) || '/%' THEN 1 ELSE 0 END
+            FROM Walk
+            JOIN transactions t ON t.id = Walk.parent_id
+            WHERE Walk.is_cycle = 0
+        )
+        SELECT 1 FROM Walk WHERE is_cycle = 1
+    ) THEN
+        RAISE EXCEPTION 'transaction hierarchy contains a cycle';
+    END IF;
+END
+$$;
+
+-- Backfill trees down to 1000 levels, the default MAX_TREE_DEPTH. Run cmd/repairsums -closure after migrating
+-- a database whose trees are deeper, with MAX_TREE_DEPTH raised to cover them.
+INSERT INTO transaction_closure (ancestor_id, descendant_id, depth)
+WITH RECURSIVE Closure AS (
+    SELECT id AS ancestor_id, id AS descendant_id, 0 AS depth
+    FROM transactions
+
+    UNION ALL
+
+    SELECT t.parent_id, Closure.descendant_id, Closure.depth + 1
+    FROM Closure
+    JOIN transactions t ON t.id = Closure.ancestor_id
+    WHERE t.parent_id IS NOT NULL AND Closure.depth < 1000
+)
+SELECT ancestor_id, descendant_id, MIN(depth)
+FROM Closure
+GROUP BY ancestor_id, descendant_id;
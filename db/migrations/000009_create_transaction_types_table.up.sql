@@ -0,0 +1,3 @@
+CREATE TABLE IF NOT EXISTS transaction_types(
+    name VARCHAR(50) PRIMARY KEY
+);
@@ -0,0 +1,6 @@
+-- Rows that predate the audit fields are stamped with the time of the migration
+ALTER TABLE transactions
+    ADD COLUMN occurred_at TIMESTAMPTZ,
+    ADD COLUMN created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    ADD COLUMN updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    ADD COLUMN created_by VARCHAR(255);